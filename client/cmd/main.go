@@ -17,6 +17,8 @@ import (
 	"github.com/cloudwego/eino/flow/agent/react"
 	"github.com/cloudwego/eino/schema"
 	"github.com/joho/godotenv"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 
 	// 本地包导入
 	"mcp-docker/client/pkg/mcp"
@@ -47,6 +49,8 @@ type Application struct {
 	lastCommand    string
 	pendingRetry   bool
 	lastUpdateTime time.Time
+	// shutdownTracing 导出尚未发送的span，未启用链路追踪时什么也不做
+	shutdownTracing func(context.Context) error
 }
 
 // NewApplication 创建新的应用程序实例
@@ -72,6 +76,15 @@ func (app *Application) Initialize() error {
 		return fmt.Errorf("加载环境变量失败: %w", err)
 	}
 
+	// 设置了OTLP接收地址时启用链路追踪，重试初始化时不重复创建
+	if app.shutdownTracing == nil {
+		shutdown, err := mcp.SetupTracing(app.ctx)
+		if err != nil {
+			return fmt.Errorf("启用链路追踪失败: %w", err)
+		}
+		app.shutdownTracing = shutdown
+	}
+
 	// 获取服务器URL
 	serverURL := os.Getenv("MCP_SERVER_URL")
 	if serverURL == "" {
@@ -106,6 +119,11 @@ func (app *Application) Initialize() error {
 	fmt.Println("MCP连接已建立，等待连接稳定...")
 	time.Sleep(5 * time.Second)
 
+	// 检查服务器到Docker守护进程和Kubernetes集群的连接，不可用的后端会提示给用户
+	if _, err := app.clientManager.CheckReadiness(initCtx); err != nil && Debug {
+		fmt.Printf("[健康] 就绪检查失败: %v\n", err)
+	}
+
 	// 初始化系统提示
 	app.dialog = append(app.dialog, &schema.Message{
 		Role: schema.System,
@@ -125,8 +143,13 @@ func (app *Application) Initialize() error {
    - 检查危险操作（删除、清理等）
    - 确认命令格式符合Windows要求，不要使用|或者grep等unix才有的命令
    - 危险命令必须按此格式确认："【安全提示】即将执行：xxx，是否继续？(Y/N)"
+   - 删除、清理类工具首次调用时服务器只会返回【待确认】预览和 confirm_token，不会执行操作
+   - 必须把预览内容展示给用户并等待用户回复 Y，之后才能使用相同参数并携带 confirm_token 再次调用
+   - 用户没有明确确认时，绝对不要携带 confirm_token 调用工具
+   - 用户只想了解操作会改动什么时，使用 dry_run=true 调用工具，预演结果不会修改任何资源
 
 2. Docker命令构造规则：
+   - 服务器管理多台Docker主机时，用户提到某台主机时先用 list_docker_hosts 确认名称，再通过 host 参数调用工具
    - 命令以docker开头
    - 使用反斜杠路径（如docker run -v C:\app:/app）
    - 禁止使用管道符、重定向等复杂操作
@@ -134,6 +157,7 @@ func (app *Application) Initialize() error {
 
 3. Kubernetes命令规则：
    - 不要手动构造kubectl命令，使用提供的MCP工具
+   - 用户提到某个集群（如 staging、prod）时，先用 list_kube_contexts 确认对应的context，再通过 context 参数调用工具
    - 操作前先检查相关资源是否存在
    - 命名空间敏感操作需要先确认命名空间
    - 删除资源操作需要二次确认
@@ -151,6 +175,7 @@ func (app *Application) Initialize() error {
 示例对话：
 用户：删除所有停止的容器
 你：【安全提示】即将执行：docker system prune -a，这将删除所有未使用的容器、镜像和网络，是否继续？(Y/N)
+（调用 system_prune 后服务器返回【待确认】预览和 confirm_token，向用户展示预览；用户回复 Y 后再携带 confirm_token 调用一次）
 
 用户：查看所有的Kubernetes命名空间
 你：我将获取所有Kubernetes命名空间列表。
@@ -341,7 +366,9 @@ func (app *Application) processCommand(message string) bool {
 	})
 
 	// 创建独立上下文进行命令执行，避免共享app.ctx导致的上下文取消问题
-	cmdCtx := context.Background()
+	// 每条命令对应一条链路，Agent调用的工具和服务端访问Docker、Kubernetes的请求都挂在这个span下
+	cmdCtx, span := otel.Tracer("mcp-docker/client").Start(context.Background(), "agent.command")
+	defer span.End()
 
 	// 设置超时上下文，增加时间为90秒，给复杂命令更多时间
 	generateCtx, generateCancel := context.WithTimeout(cmdCtx, time.Duration(90)*time.Second)
@@ -357,10 +384,15 @@ func (app *Application) processCommand(message string) bool {
 	var out *schema.Message
 	var generateErr error
 
-	// 显示等待提示
+	// 显示等待提示，工具调用的进度会实时显示在下一行
 	fmt.Print("AI: 正在处理您的请求")
-	waitIndicator := time.NewTicker(1 * time.Second)
-	defer waitIndicator.Stop()
+	var progressEvents <-chan mcp.ProgressEvent
+	if app.clientManager != nil {
+		progressEvents = app.clientManager.Progress()
+	}
+	showingProgress := false
+	timeout := time.NewTimer(time.Duration(100) * time.Second)
+	defer timeout.Stop()
 
 	// 创建工作协程
 	go func() {
@@ -383,6 +415,7 @@ func (app *Application) processCommand(message string) bool {
 			}
 
 			if generateErr != nil {
+				span.SetStatus(codes.Error, generateErr.Error())
 				// 检查是否是连接或超时问题
 				if isConnectionError(generateErr) {
 					fmt.Printf("\n[系统] 检测到连接问题: %v\n尝试重新连接MCP服务器...\n", generateErr)
@@ -413,11 +446,15 @@ func (app *Application) processCommand(message string) bool {
 				Content: output,
 			})
 
-		case <-waitIndicator.C:
-			// 显示等待动画
-			fmt.Print(".")
+		case event := <-progressEvents:
+			// 第一条进度另起一行，之后在同一行刷新
+			if !showingProgress {
+				fmt.Println()
+				showingProgress = true
+			}
+			fmt.Printf("\r\033[K[进度] %s", event)
 
-		case <-time.After(time.Duration(100) * time.Second):
+		case <-timeout.C:
 			// 超时
 			waiting = false
 			fmt.Println() // 换行，结束进度指示
@@ -502,6 +539,11 @@ func (app *Application) Shutdown() {
 	if app.clientManager != nil {
 		app.clientManager.Close()
 	}
+	if app.shutdownTracing != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		app.shutdownTracing(shutdownCtx)
+		cancel()
+	}
 	app.cancel()
 }
 
@@ -599,4 +641,5 @@ func main() {
 
 	// 启动应用
 	app.Start()
+	app.Shutdown()
 }
@@ -0,0 +1,45 @@
+package mcp
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+var injectTokenOnce sync.Once
+
+// injectAPIToken 让发往MCP服务器的请求都携带 Authorization: Bearer 请求头
+//
+// mcp-go 的SSE客户端建立事件流时不会附加 WithHeaders 设置的请求头，与 injectTraceContext 一样
+// 只能包装默认的Transport。令牌只发送给MCP服务器，不写入URL，避免出现在代理和访问日志中。
+func injectAPIToken(serverURL, token string) {
+	u, err := url.Parse(serverURL)
+	if err != nil || token == "" {
+		return
+	}
+	injectTokenOnce.Do(func() {
+		http.DefaultTransport = &tokenTransport{
+			base:  http.DefaultTransport,
+			host:  u.Host,
+			token: token,
+		}
+	})
+}
+
+// tokenTransport 向MCP服务器的请求添加API令牌
+type tokenTransport struct {
+	base  http.RoundTripper
+	host  string
+	token string
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host || req.Header.Get("Authorization") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	// RoundTripper 不能修改调用方的请求，复制后再添加请求头
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(req)
+}
@@ -49,6 +49,8 @@ type ClientManager struct {
 	lastConnectionError  error
 	connectionFailedLock sync.RWMutex
 	sessionExpiryTimer   *time.Timer
+	readiness            *ReadinessReport // 最近一次就绪检查的结果
+	progress             *progressTracker // 工具调用的进度通知
 }
 
 // ClientOption 是客户端配置选项函数
@@ -92,6 +94,7 @@ func NewClientManager(serverURL, apiToken string, options ...ClientOption) *Clie
 		maxRetries:     5,
 		retryInterval:  2 * time.Second,
 		connectTimeout: 5 * time.Second,
+		progress:       newProgressTracker(),
 	}
 
 	// 应用选项
@@ -99,6 +102,10 @@ func NewClientManager(serverURL, apiToken string, options ...ClientOption) *Clie
 		option(cm)
 	}
 
+	// 工具调用请求携带当前的链路上下文，服务端的span会成为客户端span的子span
+	injectTraceContext(serverURL)
+	injectAPIToken(serverURL, apiToken)
+
 	return cm
 }
 
@@ -212,6 +219,11 @@ func (m *ClientManager) connect(ctx context.Context) error {
 	// 重置会话ID
 	m.sessionID = ""
 
+	// API令牌由 injectAPIToken 包装的Transport以Authorization请求头发送，包括建立事件流的请求
+	if m.apiToken != "" && Debug {
+		fmt.Println("[连接] API令牌已配置")
+	}
+
 	// 创建新客户端
 	var err error
 	m.client, err = client.NewSSEMCPClient(m.serverURL)
@@ -221,13 +233,7 @@ func (m *ClientManager) connect(ctx context.Context) error {
 		}
 		return fmt.Errorf("创建MCP客户端失败: %w", err)
 	}
-
-	// 配置客户端
-	// 注意: 由于SSEMCPClient没有SetToken方法，将apiToken通过其他方式处理
-	// 如果需要设置token，可能需要在NewSSEMCPClient前后处理
-	if m.apiToken != "" && Debug {
-		fmt.Println("[连接] API令牌已配置")
-	}
+	m.client.OnNotification(m.progress.handleNotification)
 
 	// 使用完全独立的上下文进行连接，避免外部上下文取消导致SSE流关闭
 	connectCtx := context.Background()
@@ -516,6 +522,11 @@ func (m *ClientManager) HealthCheck() bool {
 	}
 	m.connectionFailedLock.RUnlock()
 
+	// 检查服务器的后端状态，后端不可用时会话本身仍然正常，只提示用户
+	if _, err := m.CheckReadiness(context.Background()); err != nil && Debug {
+		fmt.Printf("[健康] 就绪检查失败: %v\n", err)
+	}
+
 	// 获取连接锁
 	m.connectLock.Lock()
 	defer m.connectLock.Unlock()
@@ -0,0 +1,131 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// readinessPath 服务器就绪检查端点的路径
+const readinessPath = "/readyz"
+
+// readinessTimeout 单次就绪检查的超时时间，服务器会并发检查所有后端
+const readinessTimeout = 10 * time.Second
+
+// ReadinessReport 服务器就绪检查返回的各后端状态
+type ReadinessReport struct {
+	Status   string          `json:"status"`
+	Backends []BackendStatus `json:"backends"`
+}
+
+// BackendStatus 服务器连接的一个Docker主机或Kubernetes集群的状态
+type BackendStatus struct {
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	OK        bool   `json:"ok"`
+	Version   string `json:"version,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// String 返回 类型/名称 形式的后端名称
+func (b BackendStatus) String() string {
+	if b.Name == "" {
+		return b.Type
+	}
+	return b.Type + "/" + b.Name
+}
+
+// Ready 所有后端都可用时返回 true
+func (r *ReadinessReport) Ready() bool {
+	return r.Status == "ok"
+}
+
+// Unavailable 返回不可用的后端
+func (r *ReadinessReport) Unavailable() []BackendStatus {
+	var backends []BackendStatus
+	for _, backend := range r.Backends {
+		if !backend.OK {
+			backends = append(backends, backend)
+		}
+	}
+	return backends
+}
+
+// CheckReadiness 请求服务器的就绪检查端点，获取服务器到Docker守护进程和Kubernetes API服务器的连接状态
+//
+// MCP会话正常只说明客户端与服务器之间的连接可用，服务器丢失Docker套接字或集群连接时
+// 只能通过就绪检查得知。就绪检查端点不需要鉴权。
+func (m *ClientManager) CheckReadiness(ctx context.Context) (*ReadinessReport, error) {
+	u, err := url.Parse(m.serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("解析服务器URL失败: %w", err)
+	}
+	u.Path = readinessPath
+	u.RawQuery = ""
+	u.User = nil
+
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建就绪检查请求失败: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求就绪检查失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// 后端不可用时服务器返回 503，响应体同样是检查结果
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil, fmt.Errorf("就绪检查返回状态码 %d，服务器可能不支持就绪检查", resp.StatusCode)
+	}
+	var report ReadinessReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, fmt.Errorf("解析就绪检查结果失败: %w", err)
+	}
+
+	m.updateReadiness(&report)
+	return &report, nil
+}
+
+// LastReadiness 返回最近一次就绪检查的结果，尚未成功检查过时返回 nil
+func (m *ClientManager) LastReadiness() *ReadinessReport {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.readiness
+}
+
+// updateReadiness 保存就绪检查结果，不可用的后端发生变化时提示用户
+func (m *ClientManager) updateReadiness(report *ReadinessReport) {
+	m.mutex.Lock()
+	previous := m.readiness
+	m.readiness = report
+	m.mutex.Unlock()
+
+	current := describeUnavailable(report)
+	if previous != nil && describeUnavailable(previous) == current {
+		return
+	}
+	switch {
+	case current != "":
+		fmt.Printf("[健康] 服务器的以下后端不可用，相关工具调用会失败:\n%s", current)
+	case previous != nil:
+		fmt.Println("[健康] 服务器的所有后端已恢复可用")
+	}
+}
+
+// describeUnavailable 列出不可用的后端及原因，全部可用时返回空字符串
+func describeUnavailable(report *ReadinessReport) string {
+	var builder strings.Builder
+	for _, backend := range report.Unavailable() {
+		builder.WriteString(fmt.Sprintf("  - %s: %s\n", backend, backend.Error))
+	}
+	return builder.String()
+}
@@ -0,0 +1,155 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// 服务器发送的通知
+const (
+	methodProgress   = "notifications/progress"
+	methodLogMessage = "notifications/message"
+)
+
+// progressBuffer 进度事件通道的容量，界面来不及显示时丢弃多余的事件
+const progressBuffer = 32
+
+// ProgressEvent 服务器为一次工具调用发送的进度
+type ProgressEvent struct {
+	// Tool 发起调用的工具名称
+	Tool     string
+	Progress float64
+	// Total 总量，未知时为0
+	Total   float64
+	Message string
+	// CurrentBytes、TotalBytes 拉取镜像时已下载和已知的总字节数，其他工具为0
+	CurrentBytes int64
+	TotalBytes   int64
+}
+
+// String 返回适合在一行中显示的进度
+func (e ProgressEvent) String() string {
+	text := e.Message
+	if text == "" {
+		text = e.Tool
+		if e.Total > 0 {
+			text += fmt.Sprintf(" %.0f%%", e.Progress/e.Total*100)
+		}
+	}
+	if e.TotalBytes > 0 {
+		text += fmt.Sprintf(" (%s/%s)", formatBytes(e.CurrentBytes), formatBytes(e.TotalBytes))
+	}
+	return text
+}
+
+// progressTracker 为工具调用分配 progressToken，并把收到的进度通知转换为 ProgressEvent
+type progressTracker struct {
+	next   atomic.Uint64
+	mu     sync.Mutex
+	tools  map[string]string
+	events chan ProgressEvent
+}
+
+func newProgressTracker() *progressTracker {
+	return &progressTracker{
+		tools:  make(map[string]string),
+		events: make(chan ProgressEvent, progressBuffer),
+	}
+}
+
+// begin 为一次工具调用分配 progressToken
+func (t *progressTracker) begin(tool string) string {
+	token := fmt.Sprintf("mcp-docker-%d", t.next.Add(1))
+	t.mu.Lock()
+	t.tools[token] = tool
+	t.mu.Unlock()
+	return token
+}
+
+// end 调用结束后丢弃 progressToken，之后到达的通知会被忽略
+func (t *progressTracker) end(token string) {
+	t.mu.Lock()
+	delete(t.tools, token)
+	t.mu.Unlock()
+}
+
+// handleNotification 处理服务器发送的通知：进度通知转为事件，警告及以上级别的日志直接打印
+func (t *progressTracker) handleNotification(notification mcp.JSONRPCNotification) {
+	fields := notification.Params.AdditionalFields
+	switch notification.Method {
+	case methodProgress:
+		token, _ := fields["progressToken"].(string)
+		t.mu.Lock()
+		tool, ok := t.tools[token]
+		t.mu.Unlock()
+		if !ok {
+			return
+		}
+
+		event := ProgressEvent{Tool: tool}
+		event.Progress, _ = fields["progress"].(float64)
+		event.Total, _ = fields["total"].(float64)
+		event.Message, _ = fields["message"].(string)
+		if current, ok := fields["current_bytes"].(float64); ok {
+			event.CurrentBytes = int64(current)
+		}
+		if total, ok := fields["total_bytes"].(float64); ok {
+			event.TotalBytes = int64(total)
+		}
+		select {
+		case t.events <- event:
+		default:
+		}
+	case methodLogMessage:
+		level, _ := fields["level"].(string)
+		switch level {
+		case "warning", "error", "critical", "alert", "emergency":
+			fmt.Printf("\n[服务器] %v\n", fields["data"])
+		default:
+			if Debug {
+				fmt.Printf("[服务器] %v\n", fields["data"])
+			}
+		}
+	}
+}
+
+// progressClient 为每次工具调用附加 progressToken，服务器据此发送进度通知
+type progressClient struct {
+	client.MCPClient
+	tracker *progressTracker
+}
+
+// CallTool 调用工具，调用期间收到的进度通知会发送到 ClientManager.Progress
+func (c *progressClient) CallTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	token := c.tracker.begin(request.Params.Name)
+	defer c.tracker.end(token)
+
+	request.Params.Meta = &struct {
+		ProgressToken mcp.ProgressToken `json:"progressToken,omitempty"`
+	}{ProgressToken: token}
+	return c.MCPClient.CallTool(ctx, request)
+}
+
+// Progress 返回工具调用的进度事件，界面可以在等待Agent时实时显示
+func (m *ClientManager) Progress() <-chan ProgressEvent {
+	return m.progress.events
+}
+
+// formatBytes 以易读的单位显示字节数
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
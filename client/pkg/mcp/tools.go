@@ -89,7 +89,7 @@ func GetMCPTools(ctx context.Context, clientManager *ClientManager, options ...b
 		// 使用超长超时时间的mcpp配置
 		startTime := time.Now()
 		tools, getErr = mcpp.GetTools(toolCtx, &mcpp.Config{
-			Cli: cli,
+			Cli: &progressClient{MCPClient: cli, tracker: clientManager.progress},
 		})
 		duration := time.Since(startTime)
 
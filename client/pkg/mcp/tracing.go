@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// defaultServiceName 未设置 OTEL_SERVICE_NAME 时客户端使用的服务名称
+const defaultServiceName = "mcp-docker-client"
+
+// SetupTracing 根据OpenTelemetry的标准环境变量启用链路追踪
+//
+// 只有设置了 OTEL_EXPORTER_OTLP_ENDPOINT 或 OTEL_EXPORTER_OTLP_TRACES_ENDPOINT 时才会启用，
+// 返回的函数用于在退出前导出尚未发送的span；未启用时返回的函数什么也不做。
+func SetupTracing(ctx context.Context) (func(context.Context) error, error) {
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("创建OTLP导出器失败: %w", err)
+	}
+
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("创建链路追踪资源失败: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	return provider.Shutdown, nil
+}
+
+var injectOnce sync.Once
+
+// injectTraceContext 让发往MCP服务器的请求携带当前的链路上下文
+//
+// mcp-go 的SSE客户端固定使用 http.DefaultTransport，且没有提供替换HTTP客户端的选项，
+// 因此只能包装默认的Transport；包装后的Transport只向MCP服务器的请求注入请求头，
+// 不会把链路信息发送给OpenAI等其他服务。
+func injectTraceContext(serverURL string) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return
+	}
+	injectOnce.Do(func() {
+		http.DefaultTransport = &traceTransport{
+			base: http.DefaultTransport,
+			host: u.Host,
+		}
+	})
+}
+
+// traceTransport 向MCP服务器的请求注入 traceparent 等请求头
+type traceTransport struct {
+	base http.RoundTripper
+	host string
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host {
+		return t.base.RoundTrip(req)
+	}
+
+	carrier := propagation.HeaderCarrier{}
+	otel.GetTextMapPropagator().Inject(req.Context(), carrier)
+	if len(carrier) == 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	// RoundTripper 不能修改调用方的请求，复制后再添加请求头
+	req = req.Clone(req.Context())
+	for key, values := range carrier {
+		req.Header[key] = values
+	}
+	return t.base.RoundTrip(req)
+}
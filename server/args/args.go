@@ -0,0 +1,363 @@
+// Package args 按工具声明的输入模式检查参数、填充默认值，并把参数解码为各工具的参数结构体
+//
+// 工具的 InputSchema 是参数的唯一定义：必填参数、类型、枚举值、数值范围、长度和名称格式
+// 都在声明工具时通过 mcp.Required、mcp.Enum、mcp.Min、mcp.Pattern 等选项给出，
+// Middleware 在调用处理函数之前据此检查参数，处理函数再通过 Decode 取得类型明确的参数。
+package args
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp-docker/server/output"
+	"mcp-docker/server/registry"
+	"mcp-docker/server/toolerror"
+)
+
+// ErrorInvalidArgument 参数校验失败时结构化结果中的错误类型
+const ErrorInvalidArgument = toolerror.CodeInvalidArgument
+
+// Problem 一个参数的问题
+type Problem struct {
+	Argument string `json:"argument"`
+	Message  string `json:"message"`
+}
+
+// Error 参数校验失败，包含全部有问题的参数
+type Error struct {
+	Tool     string
+	Problems []Problem
+}
+
+// Error 实现 error
+func (e *Error) Error() string {
+	messages := make([]string, 0, len(e.Problems))
+	for _, problem := range e.Problems {
+		messages = append(messages, problem.Message)
+	}
+	return fmt.Sprintf("工具 %s 的参数不正确: %s", e.Tool, strings.Join(messages, "；"))
+}
+
+// Invalid 参数校验失败时返回的结构化结果
+type Invalid struct {
+	Error    string    `json:"error"`
+	Tool     string    `json:"tool"`
+	Problems []Problem `json:"problems"`
+	Message  string    `json:"message"`
+	Hint     string    `json:"hint"`
+}
+
+// patterns 缓存编译过的 pattern
+var patterns sync.Map
+
+// Middleware 以工具中间件的形式检查参数并填充默认值，参数不正确时返回错误结果，不执行工具
+//
+// 处理函数 panic 时同样返回错误结果并记录堆栈，不会影响其他调用；处理函数返回的 Go error 转换为带错误码的错误结果。
+// recover 只是最后的保护，调用方能构造的输入（包括游标等不透明的字符串）必须在解析时检查，不能依赖它兜底。
+// 检查在调用时读取 spec.Tool，外层中间件为工具增加的参数（例如 output_format）同样会被检查。
+func Middleware(spec *registry.Spec, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				slog.Error("工具处理函数发生panic", "tool", spec.Tool.Name, "panic", r, "stack", string(debug.Stack()))
+				result, err = toolerror.Coded(request, toolerror.CodeInternal, fmt.Sprintf("工具 %s 执行时发生内部错误: %v", spec.Tool.Name, r))
+			}
+		}()
+
+		arguments, err := Validate(spec.Tool, request.Params.Arguments)
+		if err != nil {
+			return ErrorResult(request, err)
+		}
+		request.Params.Arguments = arguments
+		result, err = next(ctx, request)
+		if err != nil {
+			return toolerror.Result(request, fmt.Sprintf("工具 %s 执行失败", spec.Tool.Name), err)
+		}
+		return result, nil
+	}
+}
+
+// Validate 按工具的输入模式检查参数，返回填充了默认值的参数副本
+//
+// 未声明的参数原样保留；值为 null 的参数视为未提供。
+func Validate(tool mcp.Tool, arguments map[string]interface{}) (map[string]interface{}, error) {
+	validated := make(map[string]interface{}, len(arguments))
+	for name, value := range arguments {
+		if value != nil {
+			validated[name] = value
+		}
+	}
+
+	required := make(map[string]bool, len(tool.InputSchema.Required))
+	for _, name := range tool.InputSchema.Required {
+		required[name] = true
+	}
+
+	names := make([]string, 0, len(tool.InputSchema.Properties))
+	for name := range tool.InputSchema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []Problem
+	for _, name := range names {
+		schema, _ := tool.InputSchema.Properties[name].(map[string]interface{})
+		value, ok := validated[name]
+		if !ok {
+			if def, ok := schema["default"]; ok {
+				validated[name] = def
+			} else if required[name] {
+				problems = append(problems, Problem{Argument: name, Message: fmt.Sprintf("缺少必填参数 %s", name)})
+			}
+			continue
+		}
+
+		normalized, problem := check(name, schema, value, required[name])
+		if problem != "" {
+			problems = append(problems, Problem{Argument: name, Message: problem})
+			continue
+		}
+		validated[name] = normalized
+	}
+
+	if len(problems) > 0 {
+		return nil, &Error{Tool: tool.Name, Problems: problems}
+	}
+	return validated, nil
+}
+
+// Decode 把已经检查过的参数解码到参数结构体，结构体字段通过 json 标签对应参数名
+func Decode(request mcp.CallToolRequest, dst interface{}) error {
+	data, err := json.Marshal(request.Params.Arguments)
+	if err != nil {
+		return fmt.Errorf("序列化参数失败: %v", err)
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return &Error{Tool: request.Params.Name, Problems: []Problem{{Message: fmt.Sprintf("解析参数失败: %v", err)}}}
+	}
+	return nil
+}
+
+// ErrorResult 把参数错误转换为工具的错误结果，text 格式返回说明文字和处理建议，json/yaml 格式返回 Invalid
+func ErrorResult(request mcp.CallToolRequest, err error) (*mcp.CallToolResult, error) {
+	invalid := Invalid{
+		Error:   ErrorInvalidArgument,
+		Tool:    request.Params.Name,
+		Message: err.Error(),
+		Hint:    toolerror.Hint(ErrorInvalidArgument),
+	}
+	if argsErr, ok := err.(*Error); ok {
+		invalid.Problems = argsErr.Problems
+	}
+
+	// 输出格式参数本身不正确时只能返回文本
+	if _, formatErr := output.FormatOf(request); formatErr != nil {
+		request.Params.Arguments = map[string]interface{}{output.FormatArg: output.FormatText}
+	}
+	text := toolerror.Text(invalid.Message, invalid.Hint)
+	result, resultErr := output.Result(request, text, invalid)
+	if resultErr != nil || result == nil {
+		result = mcp.NewToolResultText(text)
+	}
+	return toolerror.Mark(result, ErrorInvalidArgument), nil
+}
+
+// check 检查单个参数，返回规范化后的值；参数不正确时返回问题描述
+func check(name string, schema map[string]interface{}, value interface{}, required bool) (interface{}, string) {
+	switch schema["type"] {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Sprintf("参数 %s 应为字符串，实际为%s", name, describe(value))
+		}
+		if s == "" {
+			// 可选参数传入空字符串等同于未提供
+			if required {
+				return nil, fmt.Sprintf("参数 %s 不能为空", name)
+			}
+			return s, ""
+		}
+		if enum := stringList(schema["enum"]); len(enum) > 0 && !contains(enum, s) {
+			return nil, fmt.Sprintf("参数 %s 的取值 %q 无效，可选值: %s", name, s, strings.Join(enum, "、"))
+		}
+		length := len([]rune(s))
+		if min, ok := number(schema["minLength"]); ok && float64(length) < min {
+			return nil, fmt.Sprintf("参数 %s 的长度不能少于 %g 个字符", name, min)
+		}
+		if max, ok := number(schema["maxLength"]); ok && float64(length) > max {
+			return nil, fmt.Sprintf("参数 %s 的长度不能超过 %g 个字符", name, max)
+		}
+		if pattern, _ := schema["pattern"].(string); pattern != "" {
+			re, err := compile(pattern)
+			if err != nil {
+				return nil, fmt.Sprintf("参数 %s 的格式定义不正确: %v", name, err)
+			}
+			if !re.MatchString(s) {
+				return nil, fmt.Sprintf("参数 %s 的值 %q 格式不正确，应匹配 %s", name, s, pattern)
+			}
+		}
+		return s, ""
+
+	case "number", "integer":
+		n, ok := number(value)
+		if !ok {
+			return nil, fmt.Sprintf("参数 %s 应为数字，实际为%s", name, describe(value))
+		}
+		step, hasStep := number(schema["multipleOf"])
+		if (schema["type"] == "integer" || (hasStep && step == 1)) && n != math.Trunc(n) {
+			return nil, fmt.Sprintf("参数 %s 应为整数，实际为 %g", name, n)
+		}
+		if hasStep && step > 0 && math.Mod(n, step) != 0 {
+			return nil, fmt.Sprintf("参数 %s 应为 %g 的整数倍", name, step)
+		}
+		if min, ok := number(schema["minimum"]); ok && n < min {
+			return nil, fmt.Sprintf("参数 %s 不能小于 %g，实际为 %g", name, min, n)
+		}
+		if max, ok := number(schema["maximum"]); ok && n > max {
+			return nil, fmt.Sprintf("参数 %s 不能大于 %g，实际为 %g", name, max, n)
+		}
+		return n, ""
+
+	case "boolean":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Sprintf("参数 %s 应为布尔值 true 或 false，实际为%s", name, describe(value))
+		}
+		return b, ""
+
+	case "array":
+		items, ok := toList(value)
+		if !ok {
+			return nil, fmt.Sprintf("参数 %s 应为数组，实际为%s", name, describe(value))
+		}
+		if min, ok := number(schema["minItems"]); ok && float64(len(items)) < min {
+			return nil, fmt.Sprintf("参数 %s 至少需要 %g 项", name, min)
+		}
+		if max, ok := number(schema["maxItems"]); ok && float64(len(items)) > max {
+			return nil, fmt.Sprintf("参数 %s 最多只能有 %g 项", name, max)
+		}
+		itemSchema, _ := schema["items"].(map[string]interface{})
+		if itemSchema == nil {
+			return items, ""
+		}
+		normalized := make([]interface{}, 0, len(items))
+		for i, item := range items {
+			value, problem := check(fmt.Sprintf("%s[%d]", name, i), itemSchema, item, true)
+			if problem != "" {
+				return nil, problem
+			}
+			normalized = append(normalized, value)
+		}
+		return normalized, ""
+
+	case "object":
+		if _, ok := value.(map[string]interface{}); !ok {
+			return nil, fmt.Sprintf("参数 %s 应为对象，实际为%s", name, describe(value))
+		}
+		return value, ""
+	}
+	return value, ""
+}
+
+// compile 编译并缓存 pattern
+func compile(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(pattern, re)
+	return re, nil
+}
+
+// number 把JSON解码得到的数字或调用方直接传入的整数转换为 float64
+func number(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toList 把数组参数转换为 []interface{}
+func toList(value interface{}) ([]interface{}, bool) {
+	switch list := value.(type) {
+	case []interface{}:
+		return list, true
+	case []string:
+		items := make([]interface{}, 0, len(list))
+		for _, item := range list {
+			items = append(items, item)
+		}
+		return items, true
+	}
+	return nil, false
+}
+
+// stringList 读取 mcp.Enum 声明的枚举值
+func stringList(value interface{}) []string {
+	switch list := value.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		values := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// describe 描述参数实际的类型，用于错误信息
+func describe(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("字符串 %q", v)
+	case bool:
+		return fmt.Sprintf("布尔值 %t", v)
+	case []interface{}, []string:
+		return "数组"
+	case map[string]interface{}:
+		return "对象"
+	}
+	if n, ok := number(value); ok {
+		return fmt.Sprintf("数字 %g", n)
+	}
+	return fmt.Sprintf("%T", value)
+}
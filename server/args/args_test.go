@@ -0,0 +1,264 @@
+package args
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-docker/server/registry"
+	"mcp-docker/server/toolerror"
+)
+
+// testTool 覆盖各类约束的工具声明
+var testTool = mcp.NewTool("test_tool",
+	mcp.WithString("name",
+		mcp.Required(),
+		mcp.Pattern(`^[a-z][a-z0-9-]*$`),
+	),
+	mcp.WithString("mode",
+		mcp.Enum("fast", "slow"),
+		mcp.DefaultString("fast"),
+	),
+	mcp.WithNumber("replicas",
+		mcp.Min(0),
+		mcp.Max(10),
+		mcp.MultipleOf(1),
+	),
+	mcp.WithBoolean("force"),
+	mcp.WithArray("ports",
+		mcp.Items(map[string]interface{}{"type": "string", "pattern": `^[0-9]+$`}),
+	),
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments map[string]interface{}
+		// problem 为空表示应通过校验，否则为第一个问题的参数名
+		problem string
+		// message 问题描述中应包含的内容
+		message string
+		check   func(t *testing.T, validated map[string]interface{})
+	}{
+		{
+			name:      "合法参数并填充默认值",
+			arguments: map[string]interface{}{"name": "web", "replicas": float64(3), "force": true},
+			check: func(t *testing.T, validated map[string]interface{}) {
+				if validated["mode"] != "fast" {
+					t.Errorf("未提供的 mode 应填充默认值 fast，实际为 %v", validated["mode"])
+				}
+				if validated["replicas"] != float64(3) {
+					t.Errorf("replicas 应为 3，实际为 %v", validated["replicas"])
+				}
+			},
+		},
+		{
+			name:      "缺少必填参数",
+			arguments: map[string]interface{}{},
+			problem:   "name",
+			message:   "缺少必填参数",
+		},
+		{
+			name:      "必填参数为空字符串",
+			arguments: map[string]interface{}{"name": ""},
+			problem:   "name",
+			message:   "不能为空",
+		},
+		{
+			name:      "null视为未提供",
+			arguments: map[string]interface{}{"name": nil},
+			problem:   "name",
+			message:   "缺少必填参数",
+		},
+		{
+			name:      "null的可选参数使用默认值",
+			arguments: map[string]interface{}{"name": "web", "mode": nil},
+			check: func(t *testing.T, validated map[string]interface{}) {
+				if validated["mode"] != "fast" {
+					t.Errorf("值为 null 的 mode 应填充默认值 fast，实际为 %v", validated["mode"])
+				}
+			},
+		},
+		{
+			name:      "字符串类型不正确",
+			arguments: map[string]interface{}{"name": float64(1)},
+			problem:   "name",
+			message:   "应为字符串",
+		},
+		{
+			name:      "数字类型不正确",
+			arguments: map[string]interface{}{"name": "web", "replicas": "3"},
+			problem:   "replicas",
+			message:   "应为数字",
+		},
+		{
+			name:      "布尔类型不正确",
+			arguments: map[string]interface{}{"name": "web", "force": "true"},
+			problem:   "force",
+			message:   "应为布尔值",
+		},
+		{
+			name:      "数组类型不正确",
+			arguments: map[string]interface{}{"name": "web", "ports": "80"},
+			problem:   "ports",
+			message:   "应为数组",
+		},
+		{
+			name:      "枚举值无效",
+			arguments: map[string]interface{}{"name": "web", "mode": "medium"},
+			problem:   "mode",
+			message:   "可选值: fast、slow",
+		},
+		{
+			name:      "小于最小值",
+			arguments: map[string]interface{}{"name": "web", "replicas": float64(-1)},
+			problem:   "replicas",
+			message:   "不能小于 0",
+		},
+		{
+			name:      "大于最大值",
+			arguments: map[string]interface{}{"name": "web", "replicas": float64(11)},
+			problem:   "replicas",
+			message:   "不能大于 10",
+		},
+		{
+			name:      "非整数",
+			arguments: map[string]interface{}{"name": "web", "replicas": 1.5},
+			problem:   "replicas",
+			message:   "应为整数",
+		},
+		{
+			name:      "不匹配格式",
+			arguments: map[string]interface{}{"name": "Web_1"},
+			problem:   "name",
+			message:   "格式不正确",
+		},
+		{
+			name:      "数组元素不匹配格式",
+			arguments: map[string]interface{}{"name": "web", "ports": []interface{}{"80", "http"}},
+			problem:   "ports",
+			message:   "ports[1]",
+		},
+		{
+			name:      "未声明的参数原样保留",
+			arguments: map[string]interface{}{"name": "web", "extra": map[string]interface{}{"a": "b"}},
+			check: func(t *testing.T, validated map[string]interface{}) {
+				extra, ok := validated["extra"].(map[string]interface{})
+				if !ok || extra["a"] != "b" {
+					t.Errorf("未声明的参数 extra 应原样保留，实际为 %v", validated["extra"])
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validated, err := Validate(testTool, tt.arguments)
+			if tt.problem == "" {
+				if err != nil {
+					t.Fatalf("应通过校验: %v", err)
+				}
+				if tt.check != nil {
+					tt.check(t, validated)
+				}
+				return
+			}
+
+			var argsErr *Error
+			if !errors.As(err, &argsErr) {
+				t.Fatalf("应返回 *Error，实际为 %v", err)
+			}
+			if len(argsErr.Problems) == 0 || argsErr.Problems[0].Argument != tt.problem {
+				t.Fatalf("应报告参数 %s 的问题，实际: %+v", tt.problem, argsErr.Problems)
+			}
+			if !strings.Contains(argsErr.Problems[0].Message, tt.message) {
+				t.Errorf("问题描述应包含 %q，实际为 %q", tt.message, argsErr.Problems[0].Message)
+			}
+		})
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	_, err := Validate(testTool, map[string]interface{}{"mode": "medium", "replicas": float64(20)})
+	var argsErr *Error
+	if !errors.As(err, &argsErr) {
+		t.Fatalf("应返回 *Error，实际为 %v", err)
+	}
+	if len(argsErr.Problems) != 3 {
+		t.Errorf("应同时报告 mode、name、replicas 三个问题，实际: %+v", argsErr.Problems)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments map[string]interface{}
+		handler   func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error)
+		code      string
+		called    bool
+	}{
+		{
+			name:      "参数正确时调用处理函数",
+			arguments: map[string]interface{}{"name": "web"},
+			called:    true,
+		},
+		{
+			name:      "参数不正确时不调用处理函数",
+			arguments: map[string]interface{}{"name": "web", "mode": "medium"},
+			code:      toolerror.CodeInvalidArgument,
+		},
+		{
+			name:      "处理函数返回的Go错误转换为错误结果",
+			arguments: map[string]interface{}{"name": "web"},
+			handler: func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				return nil, toolerror.New(toolerror.CodeNotFound, "容器 web 不存在")
+			},
+			code:   toolerror.CodeNotFound,
+			called: true,
+		},
+		{
+			name:      "处理函数panic时返回内部错误",
+			arguments: map[string]interface{}{"name": "web"},
+			handler: func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				panic("boom")
+			},
+			code:   toolerror.CodeInternal,
+			called: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := tt.handler
+			if handler == nil {
+				handler = func(_ context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+					if request.Params.Arguments["mode"] != "fast" {
+						t.Errorf("处理函数应收到填充了默认值的参数，实际: %v", request.Params.Arguments)
+					}
+					return mcp.NewToolResultText("ok"), nil
+				}
+			}
+			spec := &registry.Spec{Tool: testTool}
+			wrapped := Middleware(spec, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				called = true
+				return handler(ctx, request)
+			})
+
+			var request mcp.CallToolRequest
+			request.Params.Name = testTool.Name
+			request.Params.Arguments = tt.arguments
+			result, err := wrapped(context.Background(), request)
+			if err != nil {
+				t.Fatalf("不应返回Go错误: %v", err)
+			}
+			if called != tt.called {
+				t.Errorf("处理函数是否被调用应为 %t，实际为 %t", tt.called, called)
+			}
+			if code := toolerror.CodeOfResult(result); code != tt.code {
+				t.Errorf("错误码应为 %q，实际为 %q", tt.code, code)
+			}
+		})
+	}
+}
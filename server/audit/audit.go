@@ -0,0 +1,357 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp-docker/server/auth"
+	"mcp-docker/server/registry"
+	"mcp-docker/server/toolerror"
+)
+
+// 调用结果
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+// 默认配置
+const (
+	DefaultPath       = "logs/audit.log"
+	DefaultMaxSize    = 100 * 1024 * 1024
+	DefaultMaxBackups = 5
+)
+
+// maxErrorLength 审计日志中错误信息的最大长度
+const maxErrorLength = 1024
+
+// Entry 一次工具调用的审计记录，每条记录占审计日志中的一行
+type Entry struct {
+	Time       time.Time              `json:"time"`
+	Session    string                 `json:"session,omitempty"`
+	Caller     string                 `json:"caller,omitempty"`
+	Tool       string                 `json:"tool"`
+	Arguments  map[string]interface{} `json:"arguments,omitempty"`
+	DurationMs int64                  `json:"duration_ms"`
+	Outcome    string                 `json:"outcome"`
+	Error      string                 `json:"error,omitempty"`
+	ErrorCode  string                 `json:"error_code,omitempty"`
+}
+
+// Logger 将审计记录以JSON行的形式写入文件，文件超过大小上限时自动轮转
+//
+// 轮转后的文件依次命名为 audit.log.1、audit.log.2 ...，数字越大越旧，
+// 超过保留数量的旧文件会被删除。
+type Logger struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// Open 打开或创建审计日志文件，maxSize<=0 或 maxBackups<0 时使用默认值
+func Open(path string, maxSize int64, maxBackups int) (*Logger, error) {
+	if path == "" {
+		path = DefaultPath
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if maxBackups < 0 {
+		maxBackups = DefaultMaxBackups
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("创建审计日志目录失败: %v", err)
+	}
+
+	l := &Logger{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	if err := l.openFile(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Path 返回当前审计日志文件路径
+func (l *Logger) Path() string {
+	return l.path
+}
+
+// Write 写入一条审计记录
+func (l *Logger) Write(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("序列化审计记录失败: %v", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return fmt.Errorf("审计日志已关闭")
+	}
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("写入审计日志失败: %v", err)
+	}
+	return nil
+}
+
+// Close 关闭审计日志文件
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Middleware 以工具中间件的形式记录审计日志
+func (l *Logger) Middleware(spec *registry.Spec, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return l.Wrap(next)
+}
+
+// Wrap 为工具处理函数记录审计日志，应作为最外层包装，这样被拒绝的调用也会被记录
+func (l *Logger) Wrap(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := next(ctx, request)
+
+		entry := Entry{
+			Time:       start,
+			Session:    sessionID(ctx),
+			Tool:       request.Params.Name,
+			Arguments:  Redact(request.Params.Arguments),
+			DurationMs: time.Since(start).Milliseconds(),
+			Outcome:    OutcomeSuccess,
+		}
+		if id, ok := auth.IdentityFromContext(ctx); ok {
+			entry.Caller = id.Name
+		}
+		switch {
+		case err != nil:
+			entry.Outcome = OutcomeError
+			entry.Error = truncate(err.Error())
+		case result != nil && result.IsError:
+			entry.Outcome = OutcomeError
+			entry.Error = truncate(resultText(result))
+			entry.ErrorCode = toolerror.CodeOfResult(result)
+		}
+
+		if writeErr := l.Write(entry); writeErr != nil {
+			slog.Error("记录审计日志失败", "error", writeErr)
+		}
+		return result, err
+	}
+}
+
+// openFile 以追加方式打开当前日志文件
+func (l *Logger) openFile() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("打开审计日志失败: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("读取审计日志信息失败: %v", err)
+	}
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+// rotate 轮转日志文件，调用方需持有锁
+func (l *Logger) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("关闭审计日志失败: %v", err)
+	}
+	l.file = nil
+
+	if l.maxBackups == 0 {
+		if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("删除审计日志失败: %v", err)
+		}
+		return l.openFile()
+	}
+
+	os.Remove(backupName(l.path, l.maxBackups))
+	for i := l.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(backupName(l.path, i), backupName(l.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("轮转审计日志失败: %v", err)
+		}
+	}
+	if err := os.Rename(l.path, backupName(l.path, 1)); err != nil {
+		return fmt.Errorf("轮转审计日志失败: %v", err)
+	}
+	return l.openFile()
+}
+
+// snapshotFile 查询开始时打开的日志文件，size 为当时的文件大小，之后写入的内容不会被读取
+type snapshotFile struct {
+	file *os.File
+	size int64
+}
+
+// snapshot 按从新到旧的顺序打开当前日志文件和全部轮转文件
+//
+// 只在打开文件时持有锁，查询期间发生的轮转只会重命名文件，不影响已经打开的文件。
+func (l *Logger) snapshot() ([]snapshotFile, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	names := make([]string, 0, l.maxBackups+1)
+	names = append(names, l.path)
+	for i := 1; i <= l.maxBackups; i++ {
+		names = append(names, backupName(l.path, i))
+	}
+
+	files := make([]snapshotFile, 0, len(names))
+	for _, name := range names {
+		file, err := os.Open(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err == nil {
+			var info os.FileInfo
+			if info, err = file.Stat(); err == nil {
+				files = append(files, snapshotFile{file: file, size: info.Size()})
+				continue
+			}
+			file.Close()
+		}
+		for _, f := range files {
+			f.file.Close()
+		}
+		return nil, fmt.Errorf("读取审计日志失败: %v", err)
+	}
+	return files, nil
+}
+
+// scan 按写入顺序从新到旧读取审计记录，fn 返回 false 时停止读取
+func (l *Logger) scan(fn func(Entry) bool) error {
+	files, err := l.snapshot()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, f := range files {
+			f.file.Close()
+		}
+	}()
+
+	for _, f := range files {
+		stopped := false
+		err := readLinesReverse(f.file, f.size, func(line []byte) bool {
+			var entry Entry
+			// 跳过损坏的行，不影响其它记录的查询
+			if json.Unmarshal(line, &entry) != nil {
+				return true
+			}
+			stopped = !fn(entry)
+			return !stopped
+		})
+		if err != nil {
+			return fmt.Errorf("读取审计日志失败: %v", err)
+		}
+		if stopped {
+			return nil
+		}
+	}
+	return nil
+}
+
+// readLinesReverse 从文件的 size 处向前逐行读取，fn 返回 false 时停止读取
+func readLinesReverse(r io.ReaderAt, size int64, fn func(line []byte) bool) error {
+	const chunkSize = 64 * 1024
+
+	// rest 为上一块开头尚不完整的行
+	var rest []byte
+	for offset := size; offset > 0; {
+		n := min(int64(chunkSize), offset)
+		offset -= n
+		buf := make([]byte, int(n)+len(rest))
+		if _, err := r.ReadAt(buf[:n], offset); err != nil && err != io.EOF {
+			return err
+		}
+		copy(buf[n:], rest)
+
+		for {
+			i := bytes.LastIndexByte(buf, '\n')
+			if i < 0 {
+				break
+			}
+			if line := buf[i+1:]; len(line) > 0 && !fn(line) {
+				return nil
+			}
+			buf = buf[:i]
+		}
+		rest = buf
+	}
+	if len(rest) > 0 {
+		fn(rest)
+	}
+	return nil
+}
+
+// backupName 返回第n个轮转文件的名称
+func backupName(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// sessionID 返回当前MCP会话的ID
+func sessionID(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
+
+// resultText 拼接工具结果中的文本内容
+func resultText(result *mcp.CallToolResult) string {
+	var text strings.Builder
+	for _, content := range result.Content {
+		if textContent, ok := content.(mcp.TextContent); ok {
+			text.WriteString(textContent.Text)
+		}
+	}
+	return text.String()
+}
+
+// truncate 截断过长的错误信息
+func truncate(message string) string {
+	if len(message) <= maxErrorLength {
+		return message
+	}
+	return strings.ToValidUTF8(message[:maxErrorLength], "") + "..."
+}
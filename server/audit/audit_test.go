@@ -0,0 +1,327 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-docker/server/auth"
+	"mcp-docker/server/toolerror"
+)
+
+// openTestLogger 在临时目录中打开审计日志
+func openTestLogger(t *testing.T, maxSize int64, maxBackups int) *Logger {
+	t.Helper()
+
+	l, err := Open(filepath.Join(t.TempDir(), "audit.log"), maxSize, maxBackups)
+	if err != nil {
+		t.Fatalf("打开审计日志失败: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+func TestRotate(t *testing.T) {
+	const maxSize = 512
+	l := openTestLogger(t, maxSize, 2)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 50; i++ {
+		if err := l.Write(Entry{Time: start.Add(time.Duration(i) * time.Second), Tool: fmt.Sprintf("tool_%02d", i), Outcome: OutcomeSuccess}); err != nil {
+			t.Fatalf("写入审计记录失败: %v", err)
+		}
+	}
+
+	for _, name := range []string{l.Path(), backupName(l.Path(), 1), backupName(l.Path(), 2)} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("文件 %s 应存在: %v", filepath.Base(name), err)
+		}
+		if info.Size() > maxSize {
+			t.Errorf("文件 %s 的大小 %d 超过了上限 %d", filepath.Base(name), info.Size(), maxSize)
+		}
+	}
+	if _, err := os.Stat(backupName(l.Path(), 3)); !os.IsNotExist(err) {
+		t.Errorf("超过 max_backups 的轮转文件应被删除，实际: %v", err)
+	}
+
+	// 查询跨越当前文件和全部轮转文件，按从新到旧的顺序返回
+	entries, err := l.Query(Filter{Limit: maxQueryLimit})
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if len(entries) == 0 || entries[0].Tool != "tool_49" {
+		t.Fatalf("第一条记录应为最新的 tool_49，实际: %+v", entries)
+	}
+	for i := 1; i < len(entries); i++ {
+		if !entries[i].Time.Before(entries[i-1].Time) {
+			t.Fatalf("记录应按时间从新到旧排列，第 %d 条为 %s，前一条为 %s", i, entries[i].Tool, entries[i-1].Tool)
+		}
+	}
+	if len(entries) >= 50 {
+		t.Errorf("被删除的轮转文件中的记录不应被查询到，实际返回了 %d 条", len(entries))
+	}
+}
+
+func TestRotateWithoutBackups(t *testing.T) {
+	l := openTestLogger(t, 1, 0)
+	for i := 0; i < 3; i++ {
+		if err := l.Write(Entry{Time: time.Now(), Tool: fmt.Sprintf("tool_%d", i)}); err != nil {
+			t.Fatalf("写入审计记录失败: %v", err)
+		}
+	}
+	if _, err := os.Stat(backupName(l.Path(), 1)); !os.IsNotExist(err) {
+		t.Errorf("max_backups=0 时不应保留轮转文件，实际: %v", err)
+	}
+	entries, err := l.Query(Filter{})
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Tool != "tool_2" {
+		t.Errorf("应只保留最新的一条记录，实际: %+v", entries)
+	}
+}
+
+func TestReadLinesReverse(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines int
+		// trailing 文件是否以换行结尾
+		trailing bool
+	}{
+		{name: "空文件", lines: 0, trailing: true},
+		{name: "单块", lines: 10, trailing: true},
+		{name: "跨越多个块", lines: 3000, trailing: true},
+		{name: "最后一行没有换行", lines: 3000, trailing: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				data bytes.Buffer
+				want []string
+			)
+			for i := 0; i < tt.lines; i++ {
+				// 行长不整除块大小，保证有行跨越块的边界
+				line := fmt.Sprintf("line-%04d-%s", i, strings.Repeat("x", 37+i%50))
+				want = append(want, line)
+				data.WriteString(line)
+				if i < tt.lines-1 || tt.trailing {
+					data.WriteString("\n")
+				}
+			}
+
+			var got []string
+			err := readLinesReverse(bytes.NewReader(data.Bytes()), int64(data.Len()), func(line []byte) bool {
+				got = append(got, string(line))
+				return true
+			})
+			if err != nil {
+				t.Fatalf("读取失败: %v", err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("应读取 %d 行，实际为 %d 行", len(want), len(got))
+			}
+			for i := range got {
+				if got[i] != want[len(want)-1-i] {
+					t.Fatalf("倒数第 %d 行应为 %q，实际为 %q", i+1, want[len(want)-1-i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadLinesReverseStops(t *testing.T) {
+	data := []byte("a\nb\nc\n")
+	var got []string
+	err := readLinesReverse(bytes.NewReader(data), int64(len(data)), func(line []byte) bool {
+		got = append(got, string(line))
+		return len(got) < 2
+	})
+	if err != nil {
+		t.Fatalf("读取失败: %v", err)
+	}
+	if strings.Join(got, ",") != "c,b" {
+		t.Errorf("返回 false 后应停止读取，实际读取了 %v", got)
+	}
+}
+
+func TestQueryFilter(t *testing.T) {
+	l := openTestLogger(t, 0, 0)
+	now := time.Now()
+	entries := []Entry{
+		{Time: now.Add(-3 * time.Hour), Caller: "alice", Tool: "delete_pod", Outcome: OutcomeSuccess},
+		{Time: now.Add(-2 * time.Hour), Caller: "bob", Tool: "list_pods", Outcome: OutcomeSuccess},
+		{Time: now.Add(-time.Hour), Caller: "alice", Tool: "delete_namespace", Outcome: OutcomeError},
+		{Time: now, Caller: "bob", Tool: "delete_pod", Outcome: OutcomeSuccess},
+	}
+	for _, entry := range entries {
+		if err := l.Write(entry); err != nil {
+			t.Fatalf("写入审计记录失败: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{name: "通配符", filter: Filter{Tool: "delete_*"}, want: []string{"delete_pod", "delete_namespace", "delete_pod"}},
+		{name: "调用方", filter: Filter{Caller: "alice"}, want: []string{"delete_namespace", "delete_pod"}},
+		{name: "结果", filter: Filter{Outcome: OutcomeError}, want: []string{"delete_namespace"}},
+		{name: "时间范围", filter: Filter{Since: now.Add(-150 * time.Minute), Until: now.Add(-30 * time.Minute)}, want: []string{"delete_namespace", "list_pods"}},
+		{name: "数量", filter: Filter{Limit: 1}, want: []string{"delete_pod"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.Query(tt.filter)
+			if err != nil {
+				t.Fatalf("查询失败: %v", err)
+			}
+			var tools []string
+			for _, entry := range got {
+				tools = append(tools, entry.Tool)
+			}
+			if strings.Join(tools, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("应返回 %v，实际为 %v", tt.want, tools)
+			}
+		})
+	}
+}
+
+func TestQuerySinceStopsScanning(t *testing.T) {
+	// 每个文件只保存一条记录：.3 最旧，当前文件最新
+	l := openTestLogger(t, 1, 3)
+	now := time.Now()
+	writes := []Entry{
+		// 时间晚于 since，但写在更早的记录之前；正常情况下不会出现，用来确认查询没有继续读取 .3
+		{Time: now, Tool: "unreachable"},
+		{Time: now.Add(-2 * time.Hour), Tool: "old_1"},
+		{Time: now.Add(-2 * time.Hour), Tool: "old_2"},
+		{Time: now, Tool: "recent"},
+	}
+	for _, entry := range writes {
+		if err := l.Write(entry); err != nil {
+			t.Fatalf("写入审计记录失败: %v", err)
+		}
+	}
+
+	got, err := l.Query(Filter{Since: now.Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if len(got) != 1 || got[0].Tool != "recent" {
+		t.Errorf("读到 since 之前的记录后应停止查询，只返回 recent，实际: %+v", got)
+	}
+}
+
+func TestQuerySinceKeepsLongCalls(t *testing.T) {
+	l := openTestLogger(t, 0, 0)
+	now := time.Now()
+	// 长时间运行的调用开始得早、写入得晚，不应让查询提前停止
+	writes := []Entry{
+		{Time: now.Add(-10 * time.Minute), Tool: "short", DurationMs: 10},
+		{Time: now.Add(-2 * time.Hour), Tool: "long", DurationMs: (2 * time.Hour).Milliseconds()},
+	}
+	for _, entry := range writes {
+		if err := l.Write(entry); err != nil {
+			t.Fatalf("写入审计记录失败: %v", err)
+		}
+	}
+
+	got, err := l.Query(Filter{Since: now.Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if len(got) != 1 || got[0].Tool != "short" {
+		t.Errorf("应返回 since 之后开始的 short，实际: %+v", got)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	redactedArgs := Redact(map[string]interface{}{
+		"container_id": "web",
+		"password":     "p@ss",
+		"API_Token":    "abc",
+		"env":          []interface{}{"DB_PASSWORD=hunter2", "MODE=prod", "NO_VALUE"},
+		"auth": map[string]interface{}{
+			"user": "alice",
+		},
+		"options": map[string]interface{}{
+			"secret_key": "s3cr3t",
+			"replicas":   float64(3),
+		},
+	})
+
+	if redactedArgs["container_id"] != "web" {
+		t.Errorf("普通参数不应脱敏，实际为 %v", redactedArgs["container_id"])
+	}
+	for _, name := range []string{"password", "API_Token", "auth"} {
+		if redactedArgs[name] != redacted {
+			t.Errorf("参数 %s 应脱敏，实际为 %v", name, redactedArgs[name])
+		}
+	}
+	env := redactedArgs["env"].([]interface{})
+	if env[0] != "DB_PASSWORD="+redacted || env[1] != "MODE=prod" || env[2] != "NO_VALUE" {
+		t.Errorf("环境变量应只隐藏敏感变量的值，实际为 %v", env)
+	}
+	options := redactedArgs["options"].(map[string]interface{})
+	if options["secret_key"] != redacted || options["replicas"] != float64(3) {
+		t.Errorf("嵌套的参数应按名称脱敏，实际为 %v", options)
+	}
+	if Redact(nil) != nil {
+		t.Error("没有参数时应返回 nil")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	l := openTestLogger(t, 0, 0)
+	ctx := auth.WithIdentity(context.Background(), auth.Identity{Name: "alice"})
+
+	ok := l.Wrap(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	failed := l.Wrap(func(_ context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return toolerror.Coded(request, toolerror.CodeNotFound, "容器 web 不存在")
+	})
+
+	var request mcp.CallToolRequest
+	request.Params.Name = "create_container"
+	request.Params.Arguments = map[string]interface{}{"image": "postgres", "env": []interface{}{"POSTGRES_PASSWORD=hunter2"}}
+	if _, err := ok(ctx, request); err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+	request.Params.Name = "remove_container"
+	request.Params.Arguments = map[string]interface{}{"container_id": "web"}
+	if _, err := failed(ctx, request); err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+
+	data, err := os.ReadFile(l.Path())
+	if err != nil {
+		t.Fatalf("读取审计日志失败: %v", err)
+	}
+	if strings.Contains(string(data), "hunter2") {
+		t.Errorf("审计日志中不应出现敏感值: %s", data)
+	}
+
+	entries, err := l.Query(Filter{})
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("应记录 2 次调用，实际: %+v", entries)
+	}
+	if e := entries[0]; e.Tool != "remove_container" || e.Outcome != OutcomeError || e.ErrorCode != toolerror.CodeNotFound || e.Caller != "alice" {
+		t.Errorf("失败的调用记录不正确: %+v", e)
+	}
+	if e := entries[1]; e.Tool != "create_container" || e.Outcome != OutcomeSuccess || e.Error != "" {
+		t.Errorf("成功的调用记录不正确: %+v", e)
+	}
+}
@@ -0,0 +1,225 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-docker/server/args"
+	"mcp-docker/server/output"
+	"mcp-docker/server/registry"
+	"mcp-docker/server/toolerror"
+)
+
+// 查询默认和最多返回的记录数
+const (
+	defaultQueryLimit = 50
+	maxQueryLimit     = 1000
+)
+
+// writeDelay 调用结束到写入审计记录之间允许的最大延迟，用于判断何时可以停止查询
+const writeDelay = time.Second
+
+// Filter 审计记录的查询条件，零值表示不限制
+type Filter struct {
+	// Tool 工具名称，支持 delete_* 这样的通配符
+	Tool    string
+	Caller  string
+	Outcome string
+	Since   time.Time
+	Until   time.Time
+	Limit   int
+}
+
+// Match 判断记录是否满足查询条件
+func (f Filter) Match(entry Entry) bool {
+	if f.Tool != "" {
+		if matched, _ := path.Match(f.Tool, entry.Tool); !matched {
+			return false
+		}
+	}
+	if f.Caller != "" && f.Caller != entry.Caller {
+		return false
+	}
+	if f.Outcome != "" && f.Outcome != entry.Outcome {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && entry.Time.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// before 判断记录以及日志中更早写入的记录是否都早于 Since
+//
+// 记录的 Time 是调用开始的时间，记录在调用结束后才写入，因此日志中的 Time 并不严格递增；
+// 这里按调用结束的时间判断，并留出 writeDelay 的余量。
+func (f Filter) before(entry Entry) bool {
+	if f.Since.IsZero() {
+		return false
+	}
+	end := entry.Time.Add(time.Duration(entry.DurationMs) * time.Millisecond)
+	return end.Add(writeDelay).Before(f.Since)
+}
+
+// Query 查询审计记录，按时间从新到旧返回
+//
+// 查询不会阻塞审计记录的写入，查询开始之后写入的记录不会被返回。
+func (l *Logger) Query(filter Filter) ([]Entry, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultQueryLimit
+	}
+
+	// 边读边过滤，找到足够的记录或者已经读到 since 之前的记录后不再读取更早的文件
+	var matched []Entry
+	err := l.scan(func(entry Entry) bool {
+		if filter.before(entry) {
+			return false
+		}
+		if filter.Match(entry) {
+			matched = append(matched, entry)
+		}
+		return len(matched) < filter.Limit
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matched, nil
+}
+
+// Tools 返回审计日志相关的工具
+func (l *Logger) Tools() []registry.Spec {
+	return []registry.Spec{
+		{
+			Tool: mcp.NewTool("audit_query",
+				mcp.WithDescription("查询最近的工具调用审计记录"),
+				mcp.WithString("tool",
+					mcp.Description("按工具名称过滤，支持 delete_* 这样的通配符"),
+				),
+				mcp.WithString("caller",
+					mcp.Description("按调用方密钥名称过滤"),
+				),
+				mcp.WithString("outcome",
+					mcp.Description("按调用结果过滤"),
+					mcp.Enum(OutcomeSuccess, OutcomeError),
+				),
+				mcp.WithString("since",
+					mcp.Description("起始时间，RFC3339格式或 1h、30m 这样的相对时间"),
+				),
+				mcp.WithString("until",
+					mcp.Description("结束时间，RFC3339格式或 1h、30m 这样的相对时间"),
+				),
+				mcp.WithNumber("limit",
+					mcp.Description("最多返回的记录数"),
+					mcp.DefaultNumber(defaultQueryLimit),
+					mcp.Min(1),
+					mcp.Max(maxQueryLimit),
+					mcp.MultipleOf(1),
+				),
+			),
+			Handler: l.QueryTool,
+			Group:   registry.GroupAudit,
+		},
+	}
+}
+
+// queryArgs audit_query 的参数
+type queryArgs struct {
+	Tool    string `json:"tool"`
+	Caller  string `json:"caller"`
+	Outcome string `json:"outcome"`
+	Since   string `json:"since"`
+	Until   string `json:"until"`
+	Limit   int    `json:"limit"`
+}
+
+// QueryTool 查询审计日志的工具函数
+func (l *Logger) QueryTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params queryArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	filter := Filter{
+		Tool:    params.Tool,
+		Caller:  params.Caller,
+		Outcome: params.Outcome,
+		Limit:   params.Limit,
+	}
+
+	slog.Info("ai 正在调用mcp server的tool", "tool", "audit_query", "filter_tool", filter.Tool, "caller", filter.Caller, "outcome", filter.Outcome)
+
+	now := time.Now()
+	var problems []args.Problem
+	if params.Since != "" {
+		t, err := parseTime(params.Since, now)
+		if err != nil {
+			problems = append(problems, args.Problem{Argument: "since", Message: fmt.Sprintf("参数 since 无效: %v", err)})
+		}
+		filter.Since = t
+	}
+	if params.Until != "" {
+		t, err := parseTime(params.Until, now)
+		if err != nil {
+			problems = append(problems, args.Problem{Argument: "until", Message: fmt.Sprintf("参数 until 无效: %v", err)})
+		}
+		filter.Until = t
+	}
+	if len(problems) > 0 {
+		return args.ErrorResult(request, &args.Error{Tool: request.Params.Name, Problems: problems})
+	}
+
+	entries, err := l.Query(filter)
+	if err != nil {
+		return toolerror.Result(request, "查询审计日志失败", err)
+	}
+	if entries == nil {
+		entries = []Entry{}
+	}
+	if len(entries) == 0 {
+		return output.Result(request, "没有符合条件的审计记录", entries)
+	}
+
+	// 格式化输出
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("共找到 %d 条审计记录（按时间从新到旧）:\n", len(entries)))
+	result.WriteString("TIME\tSESSION\tCALLER\tTOOL\tDURATION\tOUTCOME\tARGUMENTS\tERROR\n")
+	for _, entry := range entries {
+		args := ""
+		if len(entry.Arguments) > 0 {
+			data, _ := json.Marshal(entry.Arguments)
+			args = string(data)
+		}
+		result.WriteString(fmt.Sprintf("%s\t%s\t%s\t%s\t%dms\t%s\t%s\t%s\n",
+			entry.Time.Format("2006-01-02 15:04:05"),
+			entry.Session,
+			entry.Caller,
+			entry.Tool,
+			entry.DurationMs,
+			entry.Outcome,
+			args,
+			strings.ReplaceAll(entry.Error, "\n", " ")))
+	}
+
+	return output.Result(request, result.String(), entries)
+}
+
+// parseTime 解析RFC3339时间或 "1h"、"30m" 这样的相对时间
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("应为RFC3339格式的时间或 1h、30m 这样的相对时间")
+	}
+	return t, nil
+}
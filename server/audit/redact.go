@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"strings"
+)
+
+// redacted 敏感值的替代文本
+const redacted = "***"
+
+// sensitiveWords 参数名或环境变量名中包含这些词时，对应的值会被脱敏
+var sensitiveWords = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"apikey",
+	"api_key",
+	"credential",
+	"private_key",
+	"auth",
+}
+
+// Redact 复制工具参数并对敏感值脱敏
+//
+// 除了按参数名脱敏外，形如 "KEY=VALUE" 的字符串（例如 create_container 的 env）
+// 会按 KEY 判断并只隐藏 VALUE 部分。
+func Redact(args map[string]interface{}) map[string]interface{} {
+	if len(args) == 0 {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(args))
+	for name, value := range args {
+		if isSensitive(name) {
+			copied[name] = redacted
+			continue
+		}
+		copied[name] = redactValue(value)
+	}
+	return copied
+}
+
+// redactValue 递归处理嵌套的参数值
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return Redact(v)
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = redactValue(item)
+		}
+		return copied
+	case string:
+		if name, _, found := strings.Cut(v, "="); found && isSensitive(name) {
+			return name + "=" + redacted
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// isSensitive 判断名称是否表示敏感信息
+func isSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range sensitiveWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,15 +1,177 @@
 package auth
 
 import (
+	"bufio"
 	"context"
-
-	"github.com/mark3labs/mcp-go/mcp"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
 )
 
-func Auth(ctx context.Context, request mcp.CallToolRequest) (context.Context, mcp.CallToolRequest, bool) {
-	apiKey := request.Params.Arguments["api_key"].(string)
-	if apiKey == "" || apiKey != "123456" {
-		return ctx, request, false
+// Identity 表示一个通过鉴权的调用方
+type Identity struct {
+	// Name 密钥名称，用于日志和审计，不包含密钥本身
+	Name string
+}
+
+// KeyStore 保存服务端接受的API密钥
+type KeyStore struct {
+	tokens []string
+	ids    []Identity
+}
+
+// NewKeyStore 创建一个空的密钥集合
+func NewKeyStore() *KeyStore {
+	return &KeyStore{}
+}
+
+// Add 添加一个密钥，name为空时根据密钥指纹生成名称
+func (s *KeyStore) Add(name, token string) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = fingerprint(token)
+	}
+	s.tokens = append(s.tokens, token)
+	s.ids = append(s.ids, Identity{Name: name})
+}
+
+// Len 返回已配置的密钥数量
+func (s *KeyStore) Len() int {
+	return len(s.tokens)
+}
+
+// Lookup 校验密钥并返回对应的调用方身份
+func (s *KeyStore) Lookup(token string) (Identity, bool) {
+	var (
+		found Identity
+		ok    bool
+	)
+	// 遍历全部密钥并使用常量时间比较，避免通过耗时推测密钥
+	for i, candidate := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+			found, ok = s.ids[i], true
+		}
+	}
+	return found, ok
+}
+
+// LoadKeys 加载API密钥
+//
+// keys 中每项可以写成 "名称:密钥" 或仅写密钥；keysFile 指向一个每行一个密钥的文件，
+// 格式相同，以 # 开头的行为注释。名称以字母开头，只能包含字母、数字、_、. 和 -；
+// 密钥本身包含冒号且冒号前恰好像一个名称时，需要显式写出名称，例如 "ci:abc:def"。
+func LoadKeys(keys []string, keysFile string) (*KeyStore, error) {
+	store := NewKeyStore()
+
+	for _, item := range keys {
+		store.Add(splitKey(item))
 	}
-	return ctx, request, true
+
+	if keysFile != "" {
+		file, err := os.Open(keysFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取密钥文件失败: %v", err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			store.Add(splitKey(line))
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("读取密钥文件失败: %v", err)
+		}
+	}
+
+	return store, nil
+}
+
+// Middleware 校验请求头中的Bearer令牌，未通过鉴权的SSE和消息请求一律拒绝
+func Middleware(store *KeyStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			unauthorized(w, "缺少Bearer令牌")
+			return
+		}
+
+		id, ok := store.Lookup(token)
+		if !ok {
+			unauthorized(w, "API密钥不正确")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), id)))
+	})
+}
+
+type identityKey struct{}
+
+// WithIdentity 将调用方身份写入上下文
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFromContext 从上下文中获取调用方身份
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
+
+// bearerToken 解析 Authorization: Bearer <token> 请求头
+//
+// 部分第三方SSE客户端在建立事件流时无法附加自定义请求头，只能发送Basic认证，此时忽略用户名，
+// 将密码部分视为令牌，README 的排障指南中有说明。
+func bearerToken(r *http.Request) (string, bool) {
+	if _, password, ok := r.BasicAuth(); ok && password != "" {
+		return password, true
+	}
+
+	header := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func unauthorized(w http.ResponseWriter, reason string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="mcp"`)
+	http.Error(w, "未授权: "+reason, http.StatusUnauthorized)
+}
+
+// keyNamePattern 密钥名称的格式，以字母开头，最长64个字符
+var keyNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]{0,63}$`)
+
+// splitKey 将 "名称:密钥" 拆分为名称和密钥
+//
+// 只有第一个冒号之前是合法的密钥名称时才拆分，其余情况整串视为未命名的密钥，
+// 这样包含冒号的密钥（例如 "3f9a:xyz"）不会被误拆。
+func splitKey(item string) (string, string) {
+	item = strings.TrimSpace(item)
+	name, token, found := strings.Cut(item, ":")
+	if !found || !keyNamePattern.MatchString(name) || token == "" {
+		return "", item
+	}
+	return name, token
+}
+
+// fingerprint 生成密钥指纹，用于未命名密钥的展示名称
+func fingerprint(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "key-" + hex.EncodeToString(sum[:4])
 }
@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadKeys(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "keys")
+	content := "# 注释行\n\nci:ci-secret\n  bare-secret-2  \n"
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatalf("写入密钥文件失败: %v", err)
+	}
+
+	store, err := LoadKeys([]string{"alice:alice-secret", "bare-secret", " "}, file)
+	if err != nil {
+		t.Fatalf("加载密钥失败: %v", err)
+	}
+	if store.Len() != 4 {
+		t.Fatalf("应加载 4 个密钥，实际为 %d", store.Len())
+	}
+
+	tests := []struct {
+		token string
+		name  string
+	}{
+		{token: "alice-secret", name: "alice"},
+		{token: "ci-secret", name: "ci"},
+		{token: "bare-secret", name: fingerprint("bare-secret")},
+		{token: "bare-secret-2", name: fingerprint("bare-secret-2")},
+	}
+	for _, tt := range tests {
+		id, ok := store.Lookup(tt.token)
+		if !ok {
+			t.Errorf("密钥 %q 应通过校验", tt.token)
+			continue
+		}
+		if id.Name != tt.name {
+			t.Errorf("密钥 %q 的名称应为 %q，实际为 %q", tt.token, tt.name, id.Name)
+		}
+	}
+
+	// "名称:密钥" 形式只有密钥部分可以通过校验
+	if _, ok := store.Lookup("alice:alice-secret"); ok {
+		t.Error("带名称的整串不应通过校验")
+	}
+}
+
+func TestSplitKey(t *testing.T) {
+	tests := []struct {
+		item  string
+		name  string
+		token string
+	}{
+		{item: "alice:alice-secret", name: "alice", token: "alice-secret"},
+		{item: " ci-bot.v2:secret ", name: "ci-bot.v2", token: "secret"},
+		{item: "bare-secret", name: "", token: "bare-secret"},
+		// 冒号前不是合法名称时整串都是密钥
+		{item: "3f9a:b7c1", name: "", token: "3f9a:b7c1"},
+		{item: "a/b:c", name: "", token: "a/b:c"},
+		{item: ":secret", name: "", token: ":secret"},
+		{item: "alice:", name: "", token: "alice:"},
+		// 显式写出名称时密钥中可以包含冒号
+		{item: "ci:abc:def", name: "ci", token: "abc:def"},
+	}
+	for _, tt := range tests {
+		name, token := splitKey(tt.item)
+		if name != tt.name || token != tt.token {
+			t.Errorf("splitKey(%q) 应为 (%q, %q)，实际为 (%q, %q)", tt.item, tt.name, tt.token, name, token)
+		}
+	}
+}
+
+func TestLoadKeysMissingFile(t *testing.T) {
+	if _, err := LoadKeys(nil, filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("密钥文件不存在时应返回错误")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	store := NewKeyStore()
+	store.Add("alice", "alice-secret")
+
+	handler := Middleware(store, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := IdentityFromContext(r.Context())
+		if !ok {
+			t.Error("通过鉴权的请求上下文中应有调用方身份")
+		}
+		w.Write([]byte(id.Name))
+	}))
+
+	tests := []struct {
+		name   string
+		setup  func(r *http.Request)
+		status int
+	}{
+		{
+			name:   "缺少令牌",
+			setup:  func(r *http.Request) {},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "令牌不正确",
+			setup:  func(r *http.Request) { r.Header.Set("Authorization", "Bearer wrong") },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "不是Bearer方案",
+			setup:  func(r *http.Request) { r.Header.Set("Authorization", "Token alice-secret") },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "Bearer令牌正确",
+			setup:  func(r *http.Request) { r.Header.Set("Authorization", "Bearer alice-secret") },
+			status: http.StatusOK,
+		},
+		{
+			name:   "Basic认证的密码作为令牌",
+			setup:  func(r *http.Request) { r.SetBasicAuth("mcp", "alice-secret") },
+			status: http.StatusOK,
+		},
+		{
+			name:   "Basic认证的密码不正确",
+			setup:  func(r *http.Request) { r.SetBasicAuth("alice", "wrong") },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "Basic认证的密码为空",
+			setup:  func(r *http.Request) { r.SetBasicAuth("alice-secret", "") },
+			status: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/sse", nil)
+			tt.setup(r)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("状态码应为 %d，实际为 %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if tt.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 响应应带有 WWW-Authenticate 请求头")
+			}
+			if tt.status == http.StatusOK && w.Body.String() != "alice" {
+				t.Errorf("调用方身份应为 alice，实际为 %q", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"gopkg.in/yaml.v3"
+
+	"mcp-docker/server/registry"
+	"mcp-docker/server/toolerror"
+)
+
+// 内置角色
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+// Policy 描述API密钥到角色、角色到工具的映射关系
+type Policy struct {
+	// Roles 角色名称到允许调用的工具列表，支持 list_* 这样的通配符
+	Roles map[string][]string `yaml:"roles"`
+	// Keys 密钥名称到角色的映射，密钥名称即 API_KEY 中 "名称:密钥" 的名称部分
+	Keys map[string]string `yaml:"keys"`
+	// DefaultRole 未在 Keys 中列出的密钥使用的角色，为空表示拒绝访问
+	DefaultRole string `yaml:"default_role"`
+}
+
+// viewerTools 只读角色可以调用的工具
+var viewerTools = []string{
+	"list_*",
+	"describe_*",
+	"inspect_*",
+	"*_logs",
+	"*_status",
+	"system_info",
+	"current_kube_context",
+}
+
+// DefaultPolicy 返回内置的默认策略，未配置策略文件时所有密钥都拥有管理员权限
+func DefaultPolicy() *Policy {
+	operatorTools := append([]string{
+		"start_container",
+		"stop_container",
+		"restart_container",
+		"create_container",
+		"pull_image",
+		"scale_deployment",
+		"restart_deployment",
+		"create_namespace",
+	}, viewerTools...)
+
+	return &Policy{
+		Roles: map[string][]string{
+			RoleViewer:   viewerTools,
+			RoleOperator: operatorTools,
+			RoleAdmin:    {"*"},
+		},
+		Keys:        map[string]string{},
+		DefaultRole: RoleAdmin,
+	}
+}
+
+// LoadPolicy 从YAML文件加载权限策略，文件中未定义的内置角色保持默认值
+func LoadPolicy(file string) (*Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("读取权限策略文件失败: %v", err)
+	}
+
+	var loaded Policy
+	if err := yaml.Unmarshal(data, &loaded); err != nil {
+		return nil, fmt.Errorf("解析权限策略文件失败: %v", err)
+	}
+
+	policy := DefaultPolicy()
+	for role, tools := range loaded.Roles {
+		policy.Roles[role] = tools
+	}
+	if loaded.Keys != nil {
+		policy.Keys = loaded.Keys
+	}
+	// 显式提供策略文件时，未列出的密钥默认没有任何权限
+	policy.DefaultRole = loaded.DefaultRole
+
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Validate 检查策略中引用的角色和通配符是否有效
+func (p *Policy) Validate() error {
+	for role, patterns := range p.Roles {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("角色 %s 的工具规则 %q 无效: %v", role, pattern, err)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(p.Keys))
+	for name := range p.Keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := p.Roles[p.Keys[name]]; !ok {
+			return fmt.Errorf("密钥 %s 引用了未定义的角色 %q", name, p.Keys[name])
+		}
+	}
+
+	if p.DefaultRole != "" {
+		if _, ok := p.Roles[p.DefaultRole]; !ok {
+			return fmt.Errorf("默认角色 %q 未定义", p.DefaultRole)
+		}
+	}
+	return nil
+}
+
+// RoleFor 返回调用方对应的角色
+func (p *Policy) RoleFor(id Identity) (string, bool) {
+	if role, ok := p.Keys[id.Name]; ok {
+		return role, true
+	}
+	return p.DefaultRole, p.DefaultRole != ""
+}
+
+// Allowed 判断角色是否可以调用指定工具
+func (p *Policy) Allowed(role, tool string) bool {
+	for _, pattern := range p.Roles[role] {
+		if matched, _ := path.Match(pattern, tool); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize 在工具处理函数执行前检查调用方的角色权限，拒绝时返回错误结果而不是执行工具
+func Authorize(policy *Policy, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		tool := request.Params.Name
+
+		id, ok := IdentityFromContext(ctx)
+		if !ok {
+			return deniedResult(request, fmt.Sprintf("权限不足: 未认证的调用方无权调用工具 %s", tool)), nil
+		}
+
+		role, ok := policy.RoleFor(id)
+		if !ok {
+			return deniedResult(request, fmt.Sprintf("权限不足: 密钥 %s 未分配角色，无权调用工具 %s", id.Name, tool)), nil
+		}
+
+		if !policy.Allowed(role, tool) {
+			return deniedResult(request, fmt.Sprintf("权限不足: 密钥 %s (角色 %s) 无权调用工具 %s", id.Name, role, tool)), nil
+		}
+
+		return next(ctx, request)
+	}
+}
+
+// ToolMiddleware 返回执行权限检查的工具中间件
+func ToolMiddleware(policy *Policy) registry.Middleware {
+	return func(spec *registry.Spec, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return Authorize(policy, next)
+	}
+}
+
+// deniedResult 构造权限拒绝的工具结果
+func deniedResult(request mcp.CallToolRequest, message string) *mcp.CallToolResult {
+	result, _ := toolerror.Coded(request, toolerror.CodePermissionDenied, message)
+	return result
+}
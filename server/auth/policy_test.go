@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-docker/server/toolerror"
+)
+
+// writePolicy 把策略写入临时文件并返回文件路径
+func writePolicy(t *testing.T, content string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatalf("写入策略文件失败: %v", err)
+	}
+	return file
+}
+
+func TestPolicyAllowed(t *testing.T) {
+	policy := DefaultPolicy()
+
+	tests := []struct {
+		role    string
+		tool    string
+		allowed bool
+	}{
+		{role: RoleViewer, tool: "list_containers", allowed: true},
+		{role: RoleViewer, tool: "inspect_container", allowed: true},
+		{role: RoleViewer, tool: "pod_logs", allowed: true},
+		{role: RoleViewer, tool: "system_info", allowed: true},
+		{role: RoleViewer, tool: "remove_container", allowed: false},
+		{role: RoleViewer, tool: "start_container", allowed: false},
+		{role: RoleOperator, tool: "start_container", allowed: true},
+		{role: RoleOperator, tool: "list_pods", allowed: true},
+		{role: RoleOperator, tool: "remove_container", allowed: false},
+		{role: RoleAdmin, tool: "remove_container", allowed: true},
+		{role: "unknown", tool: "list_containers", allowed: false},
+	}
+	for _, tt := range tests {
+		if allowed := policy.Allowed(tt.role, tt.tool); allowed != tt.allowed {
+			t.Errorf("角色 %s 调用 %s 应为 %t，实际为 %t", tt.role, tt.tool, tt.allowed, allowed)
+		}
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	file := writePolicy(t, `
+roles:
+  deployer: ["*_deployment", "list_*"]
+keys:
+  alice: viewer
+  ci: deployer
+default_role: viewer
+`)
+	policy, err := LoadPolicy(file)
+	if err != nil {
+		t.Fatalf("加载策略失败: %v", err)
+	}
+
+	tests := []struct {
+		key  string
+		role string
+	}{
+		{key: "alice", role: RoleViewer},
+		{key: "ci", role: "deployer"},
+		// 未列出的密钥使用 default_role
+		{key: "someone", role: RoleViewer},
+	}
+	for _, tt := range tests {
+		role, ok := policy.RoleFor(Identity{Name: tt.key})
+		if !ok || role != tt.role {
+			t.Errorf("密钥 %s 的角色应为 %s，实际为 %q (%t)", tt.key, tt.role, role, ok)
+		}
+	}
+
+	if !policy.Allowed("deployer", "scale_deployment") || policy.Allowed("deployer", "remove_container") {
+		t.Error("自定义角色 deployer 应只能调用匹配 *_deployment 和 list_* 的工具")
+	}
+	// 文件中未定义的内置角色保持默认值
+	if !policy.Allowed(RoleOperator, "start_container") {
+		t.Error("内置角色 operator 应保持默认权限")
+	}
+}
+
+func TestLoadPolicyWithoutDefaultRole(t *testing.T) {
+	policy, err := LoadPolicy(writePolicy(t, "keys:\n  alice: admin\n"))
+	if err != nil {
+		t.Fatalf("加载策略失败: %v", err)
+	}
+	if _, ok := policy.RoleFor(Identity{Name: "someone"}); ok {
+		t.Error("策略文件未设置 default_role 时，未列出的密钥不应有角色")
+	}
+}
+
+func TestLoadPolicyInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "引用未定义的角色", content: "keys:\n  alice: superuser\n"},
+		{name: "默认角色未定义", content: "default_role: superuser\n"},
+		{name: "通配符无效", content: "roles:\n  broken: [\"list_[\"]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadPolicy(writePolicy(t, tt.content)); err == nil {
+				t.Error("应返回错误")
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.Keys = map[string]string{"alice": RoleViewer}
+	policy.DefaultRole = ""
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		tool    string
+		allowed bool
+	}{
+		{name: "viewer调用只读工具", ctx: WithIdentity(context.Background(), Identity{Name: "alice"}), tool: "list_containers", allowed: true},
+		{name: "viewer调用修改类工具", ctx: WithIdentity(context.Background(), Identity{Name: "alice"}), tool: "stop_container"},
+		{name: "未分配角色的密钥", ctx: WithIdentity(context.Background(), Identity{Name: "bob"}), tool: "list_containers"},
+		{name: "未认证的调用方", ctx: context.Background(), tool: "list_containers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := Authorize(policy, func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				called = true
+				return mcp.NewToolResultText("ok"), nil
+			})
+
+			var request mcp.CallToolRequest
+			request.Params.Name = tt.tool
+			result, err := handler(tt.ctx, request)
+			if err != nil {
+				t.Fatalf("不应返回Go错误: %v", err)
+			}
+			if called != tt.allowed {
+				t.Errorf("处理函数是否被调用应为 %t，实际为 %t", tt.allowed, called)
+			}
+			code := toolerror.CodeOfResult(result)
+			if tt.allowed && code != "" {
+				t.Errorf("允许的调用不应返回错误结果，实际错误码为 %q", code)
+			}
+			if !tt.allowed && code != toolerror.CodePermissionDenied {
+				t.Errorf("错误码应为 %s，实际为 %q", toolerror.CodePermissionDenied, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+// Package cancellation 在客户端取消请求或断开连接时取消正在执行的工具调用
+//
+// 工具处理函数收到的上下文会一直传递到Docker SDK和client-go的请求，
+// 取消上下文即可中断拉取镜像、停止容器、读取日志等操作。
+package cancellation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp-docker/server/output"
+	"mcp-docker/server/registry"
+	"mcp-docker/server/toolerror"
+	"mcp-docker/server/transport"
+)
+
+// MethodCancelled 客户端取消请求时发送的通知
+const MethodCancelled = "notifications/cancelled"
+
+// maxBodySize 查找请求ID时读取的请求体大小上限，超过时不跟踪该请求
+const maxBodySize = 4 * 1024 * 1024
+
+// 工具调用被取消的原因，可以通过 context.Cause 从调用的上下文中取得
+var (
+	ErrCancelled    = toolerror.Wrap(toolerror.CodeCancelled, errors.New("客户端取消了请求"))
+	ErrDisconnected = toolerror.Wrap(toolerror.CodeCancelled, errors.New("客户端已断开连接"))
+)
+
+// 被取消的调用在结构化结果中的错误类型
+const (
+	KindCancelled = toolerror.CodeCancelled
+	KindTimeout   = toolerror.CodeTimeout
+)
+
+// Cancelled 被取消或超时的调用返回的结构化结果
+type Cancelled struct {
+	// Error 为 cancelled 或 timeout
+	Error   string `json:"error"`
+	Tool    string `json:"tool"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+	Hint    string `json:"hint"`
+}
+
+// Tracker 按会话和JSON-RPC请求ID记录正在执行的工具调用
+type Tracker struct {
+	mu    sync.Mutex
+	calls map[string]map[string]context.CancelCauseFunc
+}
+
+// New 创建 Tracker
+func New() *Tracker {
+	return &Tracker{calls: make(map[string]map[string]context.CancelCauseFunc)}
+}
+
+// Middleware 包装MCP的HTTP处理函数，为 tools/call 请求创建可以按请求ID取消的上下文
+//
+// SSE和streamable HTTP传输中每个请求都是一次独立的POST，客户端断开POST连接时调用同样会被取消。
+func (t *Tracker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("读取请求失败: %v", err), http.StatusBadRequest)
+			return
+		}
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+
+		session := r.URL.Query().Get("sessionId")
+		if session == "" {
+			session = r.Header.Get(transport.SessionHeader)
+		}
+		ids := toolCallIDs(body)
+		if session == "" || len(ids) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// 请求结束时不取消调用的上下文，连接断开由 AfterFunc 单独处理，这样可以记录取消的原因
+		ctx, cancel := context.WithCancelCause(context.WithoutCancel(r.Context()))
+		defer cancel(nil)
+		stop := context.AfterFunc(r.Context(), func() { cancel(ErrDisconnected) })
+		defer stop()
+
+		for _, id := range ids {
+			t.add(session, id, cancel)
+		}
+		defer func() {
+			for _, id := range ids {
+				t.remove(session, id)
+			}
+		}()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// HandleCancelled 处理 notifications/cancelled，取消同一会话中对应请求ID的调用
+func (t *Tracker) HandleCancelled(ctx context.Context, notification mcp.JSONRPCNotification) {
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return
+	}
+	fields := notification.Params.AdditionalFields
+	id, ok := fields["requestId"]
+	if !ok {
+		return
+	}
+	cause := ErrCancelled
+	if reason, _ := fields["reason"].(string); reason != "" {
+		cause = fmt.Errorf("%w: %s", ErrCancelled, reason)
+	}
+	t.cancel(session.SessionID(), requestKey(id), cause)
+}
+
+// CloseSession 取消会话中所有正在执行的调用，在客户端断开SSE连接或结束会话时调用
+func (t *Tracker) CloseSession(session string) {
+	t.mu.Lock()
+	calls := t.calls[session]
+	delete(t.calls, session)
+	t.mu.Unlock()
+
+	for _, cancel := range calls {
+		cancel(ErrDisconnected)
+	}
+}
+
+// add 记录一次调用
+func (t *Tracker) add(session, id string, cancel context.CancelCauseFunc) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	calls, ok := t.calls[session]
+	if !ok {
+		calls = make(map[string]context.CancelCauseFunc)
+		t.calls[session] = calls
+	}
+	calls[id] = cancel
+}
+
+// remove 移除已结束的调用
+func (t *Tracker) remove(session, id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.calls[session], id)
+	if len(t.calls[session]) == 0 {
+		delete(t.calls, session)
+	}
+}
+
+// cancel 取消一次调用，调用已结束时不做任何事
+func (t *Tracker) cancel(session, id string, cause error) {
+	t.mu.Lock()
+	cancel, ok := t.calls[session][id]
+	t.mu.Unlock()
+
+	if ok {
+		cancel(cause)
+	}
+}
+
+// Middleware 以工具中间件的形式把被取消或超时的调用转换为错误结果
+//
+// 应位于 registry.Timeouts 之内，这样可以区分客户端取消和超时。
+// 操作被中断时Docker SDK和client-go返回的错误只有 "context canceled"，
+// 这里改为说明取消的原因，并提醒调用方操作可能已经部分完成。
+func Middleware(spec *registry.Spec, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	tool := spec.Tool.Name
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, request)
+		if ctx.Err() == nil {
+			return result, err
+		}
+
+		cause := context.Cause(ctx)
+		kind := KindCancelled
+		message := fmt.Sprintf("工具 %s 已取消: %v", tool, cause)
+		if errors.Is(cause, registry.ErrTimeout) || errors.Is(cause, context.DeadlineExceeded) {
+			kind = KindTimeout
+			message = fmt.Sprintf("工具 %s 执行超时: %v", tool, cause)
+		}
+		if spec.Mutating {
+			message += "，操作可能已经部分完成，请检查资源的当前状态"
+		}
+
+		hint := toolerror.Hint(kind)
+		if kind == KindTimeout && spec.TimeoutHint != "" {
+			hint = spec.TimeoutHint
+		}
+		cancelled, formatErr := output.Result(request, toolerror.Text(message, hint), Cancelled{
+			Error:   kind,
+			Tool:    tool,
+			Reason:  cause.Error(),
+			Message: message,
+			Hint:    hint,
+		})
+		if formatErr != nil || cancelled == nil {
+			cancelled = mcp.NewToolResultText(toolerror.Text(message, hint))
+		}
+		return toolerror.Mark(cancelled, kind), nil
+	}
+}
+
+// toolCallIDs 返回请求体中 tools/call 请求的ID，支持批量请求
+func toolCallIDs(body []byte) []string {
+	body = bytes.TrimSpace(body)
+	var messages []json.RawMessage
+	if len(body) > 0 && body[0] == '[' {
+		if err := json.Unmarshal(body, &messages); err != nil {
+			return nil
+		}
+	} else {
+		messages = []json.RawMessage{body}
+	}
+
+	var ids []string
+	for _, message := range messages {
+		var request struct {
+			ID     interface{} `json:"id"`
+			Method string      `json:"method"`
+		}
+		if err := json.Unmarshal(message, &request); err != nil {
+			continue
+		}
+		if request.Method == string(mcp.MethodToolsCall) && request.ID != nil {
+			ids = append(ids, requestKey(request.ID))
+		}
+	}
+	return ids
+}
+
+// requestKey 把JSON-RPC请求ID转换为字符串，请求和取消通知中的ID都经过JSON解码，数字统一为 float64
+func requestKey(id interface{}) string {
+	return fmt.Sprint(id)
+}
@@ -0,0 +1,257 @@
+package cancellation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp-docker/server/output"
+	"mcp-docker/server/registry"
+	"mcp-docker/server/toolerror"
+	"mcp-docker/server/transport"
+)
+
+// testSession 只提供会话ID的客户端会话
+type testSession string
+
+func (s testSession) Initialize()                                         {}
+func (s testSession) Initialized() bool                                   { return true }
+func (s testSession) SessionID() string                                   { return string(s) }
+func (s testSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return nil }
+
+// blockingHandler 阻塞到请求的上下文结束，通过 causes 返回上下文结束的原因
+func blockingHandler(started chan<- struct{}, causes chan<- error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-r.Context().Done()
+		causes <- context.Cause(r.Context())
+	})
+}
+
+// toolCall 构造会话 session 中请求ID为 id 的 tools/call 请求
+func toolCall(ctx context.Context, session string, id int) *http.Request {
+	body := `{"jsonrpc":"2.0","id":` + strconv.Itoa(id) + `,"method":"tools/call","params":{"name":"pull_image"}}`
+	r := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body)).WithContext(ctx)
+	r.Header.Set(transport.SessionHeader, session)
+	return r
+}
+
+// cancelledNotification 构造取消请求的通知，请求ID按JSON解码为 float64
+func cancelledNotification(id int, reason string) mcp.JSONRPCNotification {
+	var notification mcp.JSONRPCNotification
+	notification.Method = MethodCancelled
+	notification.Params.AdditionalFields = map[string]interface{}{"requestId": float64(id), "reason": reason}
+	return notification
+}
+
+// sessionContext 返回属于会话 session 的上下文
+func sessionContext(session string) context.Context {
+	svr := server.NewMCPServer("test", "1.0")
+	return svr.WithContext(context.Background(), testSession(session))
+}
+
+// waitCause 等待处理函数返回上下文结束的原因
+func waitCause(t *testing.T, causes <-chan error) error {
+	t.Helper()
+
+	select {
+	case cause := <-causes:
+		return cause
+	case <-time.After(5 * time.Second):
+		t.Fatal("调用没有被取消")
+		return nil
+	}
+}
+
+func TestCancelByRequestID(t *testing.T) {
+	tracker := New()
+	started, causes := make(chan struct{}), make(chan error, 1)
+	handler := tracker.Middleware(blockingHandler(started, causes))
+
+	go handler.ServeHTTP(httptest.NewRecorder(), toolCall(context.Background(), "s1", 7))
+	<-started
+
+	// 其他会话中相同ID的通知不会取消调用
+	tracker.HandleCancelled(sessionContext("s2"), cancelledNotification(7, "用户取消"))
+	select {
+	case cause := <-causes:
+		t.Fatalf("其他会话的取消通知不应取消调用，实际原因: %v", cause)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	tracker.HandleCancelled(sessionContext("s1"), cancelledNotification(7, "用户取消"))
+	cause := waitCause(t, causes)
+	if !errors.Is(cause, ErrCancelled) || !strings.Contains(cause.Error(), "用户取消") {
+		t.Errorf("取消原因应为 ErrCancelled 并包含客户端给出的原因，实际为 %v", cause)
+	}
+	if code := toolerror.CodeOf(cause); code != toolerror.CodeCancelled {
+		t.Errorf("取消原因的错误码应为 %s，实际为 %q", toolerror.CodeCancelled, code)
+	}
+}
+
+func TestCancelUnknownRequestID(t *testing.T) {
+	tracker := New()
+	started, causes := make(chan struct{}), make(chan error, 1)
+	handler := tracker.Middleware(blockingHandler(started, causes))
+
+	go handler.ServeHTTP(httptest.NewRecorder(), toolCall(context.Background(), "s1", 7))
+	<-started
+
+	tracker.HandleCancelled(sessionContext("s1"), cancelledNotification(8, ""))
+	tracker.HandleCancelled(context.Background(), cancelledNotification(7, ""))
+	select {
+	case cause := <-causes:
+		t.Fatalf("未知的请求ID或没有会话的通知不应取消调用，实际原因: %v", cause)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	tracker.CloseSession("s1")
+	if cause := waitCause(t, causes); !errors.Is(cause, ErrDisconnected) {
+		t.Errorf("结束会话时取消原因应为 ErrDisconnected，实际为 %v", cause)
+	}
+}
+
+func TestFinishedCallsRemoved(t *testing.T) {
+	tracker := New()
+	handler := tracker.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), toolCall(context.Background(), "s1", 7))
+	if len(tracker.calls) != 0 {
+		t.Errorf("调用结束后不应再记录，实际: %v", tracker.calls)
+	}
+	// 调用已结束时取消不做任何事
+	tracker.HandleCancelled(sessionContext("s1"), cancelledNotification(7, ""))
+}
+
+func TestClosedConnectionCancels(t *testing.T) {
+	tracker := New()
+	started, causes := make(chan struct{}), make(chan error, 1)
+	ts := httptest.NewServer(tracker.Middleware(blockingHandler(started, causes)))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	request := toolCall(ctx, "s1", 7)
+	request.RequestURI = ""
+	request.URL, _ = request.URL.Parse(ts.URL + "/mcp")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if resp, err := http.DefaultClient.Do(request); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	// 客户端放弃请求时关闭连接
+	cancel()
+	if cause := waitCause(t, causes); !errors.Is(cause, ErrDisconnected) {
+		t.Errorf("连接断开时取消原因应为 ErrDisconnected，实际为 %v", cause)
+	}
+	<-done
+}
+
+func TestToolCallIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{name: "单条调用", body: `{"jsonrpc":"2.0","id":1,"method":"tools/call"}`, want: []string{"1"}},
+		{name: "字符串ID", body: `{"jsonrpc":"2.0","id":"abc","method":"tools/call"}`, want: []string{"abc"}},
+		{name: "批量请求只记录工具调用", body: `[{"id":1,"method":"tools/list"},{"id":2,"method":"tools/call"},{"method":"tools/call"}]`, want: []string{"2"}},
+		{name: "无效的JSON", body: `[{`, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toolCallIDs([]byte(tt.body))
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("请求ID应为 %v，实际为 %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     registry.Spec
+		cause    error
+		kind     string
+		contains string
+	}{
+		{
+			name:     "客户端取消",
+			spec:     registry.Spec{Tool: mcp.NewTool("list_pods")},
+			cause:    ErrCancelled,
+			kind:     KindCancelled,
+			contains: "已取消",
+		},
+		{
+			name:     "超时",
+			spec:     registry.Spec{Tool: mcp.NewTool("list_pods")},
+			cause:    registry.ErrTimeout,
+			kind:     KindTimeout,
+			contains: "执行超时",
+		},
+		{
+			name:     "修改资源的工具提醒检查状态",
+			spec:     registry.Spec{Tool: mcp.NewTool("stop_container"), Mutating: true},
+			cause:    ErrDisconnected,
+			kind:     KindCancelled,
+			contains: "操作可能已经部分完成",
+		},
+		{
+			name:     "超时使用工具声明的提示",
+			spec:     registry.Spec{Tool: mcp.NewTool("stop_container"), Mutating: true, TimeoutHint: "请先检查容器状态"},
+			cause:    registry.ErrTimeout,
+			kind:     KindTimeout,
+			contains: "请先检查容器状态",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancelCause(context.Background())
+			handler := Middleware(&tt.spec, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				cancel(tt.cause)
+				return nil, ctx.Err()
+			})
+
+			var request mcp.CallToolRequest
+			request.Params.Arguments = map[string]interface{}{output.FormatArg: output.FormatJSON}
+			result, err := handler(ctx, request)
+			if err != nil {
+				t.Fatalf("不应返回Go错误: %v", err)
+			}
+			if code := toolerror.CodeOfResult(result); code != tt.kind {
+				t.Errorf("错误码应为 %s，实际为 %q", tt.kind, code)
+			}
+			var cancelled Cancelled
+			if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &cancelled); err != nil {
+				t.Fatalf("解析结果失败: %v", err)
+			}
+			if cancelled.Error != tt.kind || cancelled.Reason != tt.cause.Error() {
+				t.Errorf("结构化结果不正确: %+v", cancelled)
+			}
+			if !strings.Contains(cancelled.Message+cancelled.Hint, tt.contains) {
+				t.Errorf("结果应包含 %q，实际: %+v", tt.contains, cancelled)
+			}
+		})
+	}
+
+	// 上下文没有结束时原样返回结果
+	handler := Middleware(&registry.Spec{Tool: mcp.NewTool("list_pods")}, func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	if result, err := handler(context.Background(), mcp.CallToolRequest{}); err != nil || result.IsError {
+		t.Errorf("未取消的调用应原样返回，实际 %+v, %v", result, err)
+	}
+}
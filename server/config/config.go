@@ -0,0 +1,524 @@
+// Package config 加载服务端配置
+//
+// 配置按以下顺序合并，后者覆盖前者：内置默认值、YAML配置文件、环境变量（包括 .env 文件）、命令行参数。
+// 只使用默认值时不需要配置文件和 .env 文件。
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+
+	"mcp-docker/server/audit"
+	"mcp-docker/server/docker"
+	"mcp-docker/server/drain"
+	"mcp-docker/server/health"
+	"mcp-docker/server/metrics"
+	"mcp-docker/server/ratelimit"
+	"mcp-docker/server/registry"
+	"mcp-docker/server/tracing"
+	"mcp-docker/server/transport"
+)
+
+// 日志级别
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// DefaultAddress 默认监听地址
+const DefaultAddress = "0.0.0.0:12345"
+
+// Config 服务端配置
+type Config struct {
+	// Address 监听地址，stdio 传输时不使用
+	Address string `yaml:"address"`
+	// Transport 传输方式: sse、http 或 stdio
+	Transport string    `yaml:"transport"`
+	TLS       TLSConfig `yaml:"tls"`
+	// LogLevel 日志级别: debug、info、warn 或 error
+	LogLevel string     `yaml:"log_level"`
+	Auth     AuthConfig `yaml:"auth"`
+	// Groups 启用的工具分组: docker、k8s、audit
+	Groups []string `yaml:"groups"`
+	// ReadOnly 只读模式，只注册不修改资源的工具，修改类工具既不出现在工具列表中也无法调用
+	ReadOnly   bool               `yaml:"read_only"`
+	Docker     docker.HostsConfig `yaml:"docker"`
+	Kubernetes KubernetesConfig   `yaml:"kubernetes"`
+	Timeouts   TimeoutsConfig     `yaml:"timeouts"`
+	Audit      AuditConfig        `yaml:"audit"`
+	Metrics    MetricsConfig      `yaml:"metrics"`
+	Tracing    TracingConfig      `yaml:"tracing"`
+	RateLimit  RateLimitConfig    `yaml:"rate_limit"`
+}
+
+// TLSConfig HTTPS证书，两项都为空时使用HTTP
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// Enabled 是否启用了HTTPS
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+// AuthConfig API密钥和权限策略
+type AuthConfig struct {
+	// Keys API密钥列表，每项可以写成 "名称:密钥" 或仅写密钥
+	Keys []string `yaml:"keys"`
+	// KeysFile 每行一个密钥的文件，格式同上
+	KeysFile string `yaml:"keys_file"`
+	// PolicyFile 权限策略文件，为空时所有密钥拥有管理员权限
+	PolicyFile string `yaml:"policy_file"`
+}
+
+// KubernetesConfig Kubernetes相关配置
+type KubernetesConfig struct {
+	// Kubeconfig kubeconfig文件路径，为空时使用 ~/.kube/config
+	Kubeconfig string `yaml:"kubeconfig"`
+	// Contexts 允许使用的context，为空表示不限制
+	Contexts []string `yaml:"contexts"`
+	// Namespaces 允许操作的命名空间，为空表示不限制
+	Namespaces []string `yaml:"namespaces"`
+}
+
+// TimeoutsConfig 工具调用的超时时间
+type TimeoutsConfig struct {
+	// Default 没有内置超时的工具使用的超时时间，为0时使用2分钟；拉取镜像等耗时工具有各自更长的超时
+	Default time.Duration `yaml:"default"`
+	// Tools 按工具名称单独配置的超时时间，优先于工具的内置超时
+	Tools map[string]time.Duration `yaml:"tools"`
+	// Shutdown 收到退出信号后等待正在执行的工具调用结束的时间，超时后取消这些调用
+	Shutdown time.Duration `yaml:"shutdown"`
+}
+
+// AuditConfig 审计日志配置
+type AuditConfig struct {
+	Path       string `yaml:"path"`
+	MaxSizeMB  int    `yaml:"max_size_mb"`
+	MaxBackups int    `yaml:"max_backups"`
+}
+
+// MetricsConfig Prometheus指标配置，指标与MCP服务使用同一个监听地址，stdio 传输时不导出
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"`
+	// RequireAuth 抓取指标时是否需要API密钥，默认需要：指标与MCP服务共用监听地址，会暴露工具调用和后端状态等信息
+	RequireAuth bool `yaml:"require_auth"`
+}
+
+// TracingConfig OpenTelemetry链路追踪配置，Endpoint 为空时不启用
+type TracingConfig struct {
+	// Endpoint OTLP/HTTP 接收地址，例如 http://otel-collector:4318
+	Endpoint    string  `yaml:"endpoint"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// Enabled 是否启用了链路追踪
+func (t TracingConfig) Enabled() bool {
+	return t.Endpoint != ""
+}
+
+// RateLimitConfig 工具调用的限流配置，速率为0表示不限制
+type RateLimitConfig struct {
+	// PerKey 每个API密钥所有工具调用合计的限制
+	PerKey ratelimit.Limit `yaml:"per_key"`
+	// Tools 按工具名称配置的限制，每个密钥单独计算
+	Tools map[string]ratelimit.Limit `yaml:"tools"`
+	// MaxHeavy 同时执行的耗时操作（拉取镜像、创建容器、系统清理）数量上限，0表示不限制
+	MaxHeavy int `yaml:"max_heavy"`
+}
+
+// Default 返回内置的默认配置
+func Default() *Config {
+	return &Config{
+		Address:   DefaultAddress,
+		Transport: transport.SSE,
+		LogLevel:  LogLevelInfo,
+		Groups:    []string{registry.GroupDocker, registry.GroupK8s, registry.GroupAudit},
+		Timeouts: TimeoutsConfig{
+			Shutdown: drain.DefaultTimeout,
+		},
+		Audit: AuditConfig{
+			MaxSizeMB:  audit.DefaultMaxSize / 1024 / 1024,
+			MaxBackups: audit.DefaultMaxBackups,
+		},
+		Metrics: MetricsConfig{
+			Enabled:     true,
+			Path:        metrics.DefaultPath,
+			RequireAuth: true,
+		},
+		Tracing: TracingConfig{
+			ServiceName: tracing.DefaultServiceName,
+			SampleRatio: 1,
+		},
+		RateLimit: RateLimitConfig{
+			PerKey:   ratelimit.Limit{Rate: ratelimit.DefaultRate, Burst: ratelimit.DefaultBurst},
+			MaxHeavy: ratelimit.DefaultMaxHeavy,
+		},
+	}
+}
+
+// Load 加载配置，args 为命令行参数（不含程序名）
+func Load(args []string) (*Config, error) {
+	flags := flag.NewFlagSet("server", flag.ContinueOnError)
+	configFile := flags.String("config", "", "YAML配置文件路径，未指定时读取 MCP_CONFIG")
+	address := flags.String("address", "", "监听地址，例如 0.0.0.0:12345")
+	transportName := flags.String("transport", "", "传输方式: sse、http 或 stdio")
+	logLevel := flags.String("log-level", "", "日志级别: debug、info、warn 或 error")
+	tlsCert := flags.String("tls-cert", "", "HTTPS证书文件")
+	tlsKey := flags.String("tls-key", "", "HTTPS私钥文件")
+	groups := flags.String("groups", "", "启用的工具分组，以逗号分隔，例如 docker,k8s,audit")
+	readOnly := flags.Bool("read-only", false, "只读模式，只提供查询类工具，不提供任何修改资源的工具")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// .env 文件是可选的，不存在时直接使用环境变量
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("读取.env文件失败: %v", err)
+	}
+
+	cfg := Default()
+	if *configFile == "" {
+		*configFile = os.Getenv("MCP_CONFIG")
+	}
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	// 只有显式指定的命令行参数才覆盖配置
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "address":
+			cfg.Address = *address
+		case "transport":
+			cfg.Transport = *transportName
+		case "log-level":
+			cfg.LogLevel = *logLevel
+		case "tls-cert":
+			cfg.TLS.CertFile = *tlsCert
+		case "tls-key":
+			cfg.TLS.KeyFile = *tlsKey
+		case "groups":
+			cfg.Groups = splitList(*groups)
+		case "read-only":
+			cfg.ReadOnly = *readOnly
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile 读取YAML配置文件，文件中未出现的配置项保持原值
+func (c *Config) loadFile(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	// 拼错的配置项直接报错，避免配置悄悄不生效
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("解析配置文件 %s 失败: %v", file, err)
+	}
+	return nil
+}
+
+// applyEnv 使用环境变量覆盖配置
+func (c *Config) applyEnv() error {
+	setString := func(name string, target *string) {
+		if value := os.Getenv(name); value != "" {
+			*target = value
+		}
+	}
+	setList := func(name string, target *[]string) {
+		if value := os.Getenv(name); value != "" {
+			*target = splitList(value)
+		}
+	}
+	setInt := func(name string, target *int) error {
+		if value := os.Getenv(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("环境变量 %s 应为整数: %q", name, value)
+			}
+			*target = n
+		}
+		return nil
+	}
+	setFloat := func(name string, target *float64) error {
+		if value := os.Getenv(name); value != "" {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("环境变量 %s 应为数字: %q", name, value)
+			}
+			*target = f
+		}
+		return nil
+	}
+	setBool := func(name string, target *bool) error {
+		if value := os.Getenv(name); value != "" {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("环境变量 %s 应为 true 或 false: %q", name, value)
+			}
+			*target = b
+		}
+		return nil
+	}
+	setDuration := func(name string, target *time.Duration) error {
+		if value := os.Getenv(name); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("环境变量 %s 应为时间间隔，例如 2m: %q", name, value)
+			}
+			*target = d
+		}
+		return nil
+	}
+
+	setString("MCP_SERVER_ADDRESS", &c.Address)
+	setString("MCP_TRANSPORT", &c.Transport)
+	setString("MCP_LOG_LEVEL", &c.LogLevel)
+	setString("MCP_TLS_CERT", &c.TLS.CertFile)
+	setString("MCP_TLS_KEY", &c.TLS.KeyFile)
+	setList("API_KEY", &c.Auth.Keys)
+	setString("API_KEYS_FILE", &c.Auth.KeysFile)
+	setString("MCP_POLICY_FILE", &c.Auth.PolicyFile)
+	setList("MCP_GROUPS", &c.Groups)
+	setString("KUBECONFIG", &c.Kubernetes.Kubeconfig)
+	setList("MCP_KUBE_CONTEXTS", &c.Kubernetes.Contexts)
+	setList("MCP_NAMESPACES", &c.Kubernetes.Namespaces)
+	setString("MCP_AUDIT_LOG", &c.Audit.Path)
+	setString("MCP_METRICS_PATH", &c.Metrics.Path)
+	// 链路追踪使用OpenTelemetry的标准环境变量
+	setString("OTEL_EXPORTER_OTLP_ENDPOINT", &c.Tracing.Endpoint)
+	setString("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", &c.Tracing.Endpoint)
+	setString("OTEL_SERVICE_NAME", &c.Tracing.ServiceName)
+	if err := setBool("MCP_READ_ONLY", &c.ReadOnly); err != nil {
+		return err
+	}
+	if err := setInt("MCP_AUDIT_MAX_SIZE_MB", &c.Audit.MaxSizeMB); err != nil {
+		return err
+	}
+	if err := setInt("MCP_AUDIT_MAX_BACKUPS", &c.Audit.MaxBackups); err != nil {
+		return err
+	}
+
+	if err := setFloat("MCP_RATE_LIMIT", &c.RateLimit.PerKey.Rate); err != nil {
+		return err
+	}
+	if err := setInt("MCP_RATE_BURST", &c.RateLimit.PerKey.Burst); err != nil {
+		return err
+	}
+	if err := setInt("MCP_MAX_HEAVY_OPS", &c.RateLimit.MaxHeavy); err != nil {
+		return err
+	}
+	if err := setDuration("MCP_TOOL_TIMEOUT", &c.Timeouts.Default); err != nil {
+		return err
+	}
+	if err := setDuration("MCP_SHUTDOWN_TIMEOUT", &c.Timeouts.Shutdown); err != nil {
+		return err
+	}
+
+	// 兼容单独的Docker主机配置文件
+	if hostsFile := os.Getenv("MCP_DOCKER_HOSTS_FILE"); hostsFile != "" {
+		hosts, err := docker.LoadHostsConfig(hostsFile)
+		if err != nil {
+			return err
+		}
+		c.Docker = *hosts
+	}
+	return nil
+}
+
+// Validate 检查配置，返回的错误列出全部无效的配置项
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if err := transport.Validate(c.Transport); err != nil {
+		add("transport: %v", err)
+	}
+	network := c.Transport == transport.SSE || c.Transport == transport.HTTP
+	if network && c.Address == "" {
+		add("address: %s 传输需要监听地址", c.Transport)
+	}
+
+	if c.TLS.Enabled() {
+		if c.TLS.CertFile == "" || c.TLS.KeyFile == "" {
+			add("tls: cert_file 和 key_file 需要同时配置")
+		}
+		for _, file := range []string{c.TLS.CertFile, c.TLS.KeyFile} {
+			if file == "" {
+				continue
+			}
+			if _, err := os.Stat(file); err != nil {
+				add("tls: 无法读取 %s: %v", file, err)
+			}
+		}
+	}
+
+	switch c.LogLevel {
+	case LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError:
+	default:
+		add("log_level: 不支持 %q，可选值: debug、info、warn、error", c.LogLevel)
+	}
+
+	if network && len(c.Auth.Keys) == 0 && c.Auth.KeysFile == "" {
+		add("auth: %s 传输需要API密钥，请配置 auth.keys、auth.keys_file 或环境变量 API_KEY", c.Transport)
+	}
+	for _, file := range []string{c.Auth.KeysFile, c.Auth.PolicyFile} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			add("auth: 无法读取 %s: %v", file, err)
+		}
+	}
+
+	if len(c.Groups) == 0 {
+		add("groups: 至少需要启用一个工具分组")
+	}
+	for _, group := range c.Groups {
+		switch group {
+		case registry.GroupDocker, registry.GroupK8s, registry.GroupAudit:
+		default:
+			add("groups: 未知的工具分组 %q，可选值: docker、k8s、audit", group)
+		}
+	}
+
+	if c.Kubernetes.Kubeconfig != "" {
+		if _, err := os.Stat(c.Kubernetes.Kubeconfig); err != nil {
+			add("kubernetes.kubeconfig: 无法读取 %s: %v", c.Kubernetes.Kubeconfig, err)
+		}
+	}
+	for _, namespace := range c.Kubernetes.Namespaces {
+		if namespace == "" {
+			add("kubernetes.namespaces: 命名空间不能为空")
+		}
+	}
+
+	if c.Timeouts.Default < 0 {
+		add("timeouts.default: 不能为负数")
+	}
+	for tool, timeout := range c.Timeouts.Tools {
+		if timeout <= 0 {
+			add("timeouts.tools.%s: 应大于0", tool)
+		}
+	}
+	if c.Timeouts.Shutdown <= 0 {
+		add("timeouts.shutdown: 应大于0")
+	}
+
+	if c.Audit.MaxSizeMB <= 0 {
+		add("audit.max_size_mb: 应大于0")
+	}
+	if c.Audit.MaxBackups < 0 {
+		add("audit.max_backups: 不能为负数")
+	}
+
+	if c.Metrics.Enabled {
+		switch c.Metrics.Path {
+		case "/sse", "/message", transport.DefaultEndpoint:
+			add("metrics.path: %s 与MCP服务的路径冲突", c.Metrics.Path)
+		case health.LivenessPath, health.ReadinessPath:
+			add("metrics.path: %s 与健康检查的路径冲突", c.Metrics.Path)
+		default:
+			if !strings.HasPrefix(c.Metrics.Path, "/") {
+				add("metrics.path: 应以 / 开头: %q", c.Metrics.Path)
+			}
+		}
+	}
+
+	if c.Tracing.Enabled() {
+		if _, err := tracing.EndpointURL(c.Tracing.Endpoint); err != nil {
+			add("tracing.endpoint: %v", err)
+		}
+		if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+			add("tracing.sample_ratio: 应在0到1之间")
+		}
+	}
+
+	validateLimit := func(name string, limit ratelimit.Limit) {
+		if limit.Rate < 0 {
+			add("%s.rate: 不能为负数", name)
+		}
+		if limit.Burst < 0 {
+			add("%s.burst: 不能为负数", name)
+		}
+	}
+	validateLimit("rate_limit.per_key", c.RateLimit.PerKey)
+	for tool, limit := range c.RateLimit.Tools {
+		validateLimit("rate_limit.tools."+tool, limit)
+	}
+	if c.RateLimit.MaxHeavy < 0 {
+		add("rate_limit.max_heavy: 不能为负数")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("配置无效:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// Level 返回日志级别对应的 slog.Level
+func (c *Config) Level() slog.Level {
+	switch c.LogLevel {
+	case LogLevelDebug:
+		return slog.LevelDebug
+	case LogLevelWarn:
+		return slog.LevelWarn
+	case LogLevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// GroupEnabled 判断工具分组是否启用
+func (c *Config) GroupEnabled(group string) bool {
+	for _, g := range c.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList 拆分以逗号分隔的列表，忽略空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
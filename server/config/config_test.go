@@ -0,0 +1,259 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mcp-docker/server/transport"
+)
+
+// envNames 测试中会用到的环境变量，每个测试开始前清空，避免受运行环境的影响
+var envNames = []string{
+	"MCP_CONFIG", "MCP_SERVER_ADDRESS", "MCP_TRANSPORT", "MCP_LOG_LEVEL", "MCP_TLS_CERT", "MCP_TLS_KEY",
+	"API_KEY", "API_KEYS_FILE", "MCP_POLICY_FILE", "MCP_GROUPS", "KUBECONFIG", "MCP_KUBE_CONTEXTS",
+	"MCP_NAMESPACES", "MCP_AUDIT_LOG", "MCP_METRICS_PATH", "OTEL_EXPORTER_OTLP_ENDPOINT",
+	"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "OTEL_SERVICE_NAME", "MCP_READ_ONLY", "MCP_AUDIT_MAX_SIZE_MB",
+	"MCP_AUDIT_MAX_BACKUPS", "MCP_RATE_LIMIT", "MCP_RATE_BURST", "MCP_MAX_HEAVY_OPS", "MCP_TOOL_TIMEOUT",
+	"MCP_SHUTDOWN_TIMEOUT", "MCP_DOCKER_HOSTS_FILE",
+}
+
+// setEnv 清空测试用到的环境变量后设置 env
+func setEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+
+	for _, name := range envNames {
+		t.Setenv(name, "")
+	}
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+}
+
+// writeConfig 写入YAML配置文件并返回路径
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+	return file
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	const yamlConfig = `
+address: 127.0.0.1:1000
+log_level: warn
+groups: [docker]
+read_only: true
+timeouts:
+  default: 1m
+`
+
+	tests := []struct {
+		name     string
+		yaml     string
+		env      map[string]string
+		args     []string
+		address  string
+		logLevel string
+		groups   string
+		readOnly bool
+		timeout  time.Duration
+	}{
+		{
+			name:     "默认值",
+			address:  DefaultAddress,
+			logLevel: LogLevelInfo,
+			groups:   "docker,k8s,audit",
+		},
+		{
+			name:     "配置文件覆盖默认值",
+			yaml:     yamlConfig,
+			address:  "127.0.0.1:1000",
+			logLevel: LogLevelWarn,
+			groups:   "docker",
+			readOnly: true,
+			timeout:  time.Minute,
+		},
+		{
+			name: "环境变量覆盖配置文件",
+			yaml: yamlConfig,
+			env: map[string]string{
+				"MCP_SERVER_ADDRESS": "127.0.0.1:2000",
+				"MCP_GROUPS":         "k8s, audit",
+				"MCP_READ_ONLY":      "false",
+				"MCP_TOOL_TIMEOUT":   "90s",
+			},
+			address:  "127.0.0.1:2000",
+			logLevel: LogLevelWarn,
+			groups:   "k8s,audit",
+			timeout:  90 * time.Second,
+		},
+		{
+			name:     "显式指定的命令行参数覆盖环境变量",
+			yaml:     yamlConfig,
+			env:      map[string]string{"MCP_SERVER_ADDRESS": "127.0.0.1:2000", "MCP_LOG_LEVEL": "error"},
+			args:     []string{"--address", "127.0.0.1:3000", "--groups", "audit"},
+			address:  "127.0.0.1:3000",
+			logLevel: LogLevelError,
+			groups:   "audit",
+			readOnly: true,
+			timeout:  time.Minute,
+		},
+		{
+			name:     "未指定的布尔参数不覆盖配置",
+			yaml:     yamlConfig,
+			args:     []string{"--log-level", "debug"},
+			address:  "127.0.0.1:1000",
+			logLevel: LogLevelDebug,
+			groups:   "docker",
+			readOnly: true,
+			timeout:  time.Minute,
+		},
+		{
+			name:     "显式关闭只读模式",
+			yaml:     yamlConfig,
+			env:      map[string]string{"MCP_READ_ONLY": "true"},
+			args:     []string{"--read-only=false"},
+			address:  "127.0.0.1:1000",
+			logLevel: LogLevelWarn,
+			groups:   "docker",
+			timeout:  time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{"API_KEY": "secret"}
+			for name, value := range tt.env {
+				env[name] = value
+			}
+			args := tt.args
+			if tt.yaml != "" {
+				args = append([]string{"--config", writeConfig(t, tt.yaml)}, args...)
+			}
+			setEnv(t, env)
+
+			cfg, err := Load(args)
+			if err != nil {
+				t.Fatalf("加载配置失败: %v", err)
+			}
+			if cfg.Address != tt.address {
+				t.Errorf("address 应为 %s，实际为 %s", tt.address, cfg.Address)
+			}
+			if cfg.LogLevel != tt.logLevel {
+				t.Errorf("log_level 应为 %s，实际为 %s", tt.logLevel, cfg.LogLevel)
+			}
+			if groups := strings.Join(cfg.Groups, ","); groups != tt.groups {
+				t.Errorf("groups 应为 %s，实际为 %s", tt.groups, groups)
+			}
+			if cfg.ReadOnly != tt.readOnly {
+				t.Errorf("read_only 应为 %v，实际为 %v", tt.readOnly, cfg.ReadOnly)
+			}
+			if cfg.Timeouts.Default != tt.timeout {
+				t.Errorf("timeouts.default 应为 %s，实际为 %s", tt.timeout, cfg.Timeouts.Default)
+			}
+			// 没有出现在任何一层的配置保持默认值
+			if cfg.Transport != transport.SSE || cfg.Audit.MaxSizeMB != Default().Audit.MaxSizeMB {
+				t.Errorf("未配置的项应保持默认值，实际 transport=%s audit.max_size_mb=%d", cfg.Transport, cfg.Audit.MaxSizeMB)
+			}
+			if !cfg.Metrics.RequireAuth {
+				t.Error("指标端点默认应需要API密钥")
+			}
+		})
+	}
+}
+
+func TestConfigFileFromEnv(t *testing.T) {
+	setEnv(t, map[string]string{"API_KEY": "secret", "MCP_CONFIG": writeConfig(t, "log_level: warn\n")})
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if cfg.LogLevel != LogLevelWarn {
+		t.Errorf("未指定 --config 时应读取 MCP_CONFIG 指定的文件，实际 log_level=%s", cfg.LogLevel)
+	}
+}
+
+func TestLoadRejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		env  map[string]string
+		want string
+	}{
+		{
+			name: "未知的配置项",
+			yaml: "address: 127.0.0.1:1000\nlog_levl: debug\n",
+			want: "log_levl",
+		},
+		{
+			name: "嵌套的未知配置项",
+			yaml: "audit:\n  max_size: 10\n",
+			want: "max_size",
+		},
+		{
+			name: "环境变量不是整数",
+			env:  map[string]string{"MCP_AUDIT_MAX_BACKUPS": "three"},
+			want: "MCP_AUDIT_MAX_BACKUPS",
+		},
+		{
+			name: "环境变量不是时间间隔",
+			env:  map[string]string{"MCP_TOOL_TIMEOUT": "30"},
+			want: "MCP_TOOL_TIMEOUT",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{"API_KEY": "secret"}
+			for name, value := range tt.env {
+				env[name] = value
+			}
+			var args []string
+			if tt.yaml != "" {
+				args = []string{"--config", writeConfig(t, tt.yaml)}
+			}
+			setEnv(t, env)
+
+			_, err := Load(args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("错误应指出 %s，实际为 %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	cfg := Default()
+	cfg.Transport = "websocket"
+	cfg.LogLevel = "verbose"
+	cfg.Groups = []string{"docker", "swarm"}
+	cfg.Audit.MaxSizeMB = 0
+	cfg.Timeouts.Tools = map[string]time.Duration{"pull_image": -time.Second}
+	cfg.Metrics.Path = "/mcp"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("无效的配置应返回错误")
+	}
+	for _, want := range []string{"transport:", "log_level:", `"swarm"`, "audit.max_size_mb:", "timeouts.tools.pull_image:", "metrics.path:"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("错误应包含 %s，实际为:\n%v", want, err)
+		}
+	}
+}
+
+func TestValidateNetworkTransportNeedsKeys(t *testing.T) {
+	for _, name := range []string{transport.SSE, transport.HTTP, transport.Stdio} {
+		cfg := Default()
+		cfg.Transport = name
+		err := cfg.Validate()
+		if wantErr := name != transport.Stdio; (err != nil) != wantErr {
+			t.Errorf("%s 传输在没有API密钥时应返回错误: %v，实际为 %v", name, wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package confirm
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp-docker/server/args"
+	"mcp-docker/server/auth"
+	"mcp-docker/server/output"
+	"mcp-docker/server/registry"
+	"mcp-docker/server/toolerror"
+)
+
+// TokenArg 危险操作携带确认令牌使用的参数名
+const TokenArg = "confirm_token"
+
+// DryRunArg 预演参数名，预演调用不需要确认
+const DryRunArg = "dry_run"
+
+// DefaultTTL 确认令牌的默认有效期
+const DefaultTTL = 2 * time.Minute
+
+// Preview 危险操作在 json/yaml 格式下返回的待确认预览
+type Preview struct {
+	Tool string `json:"tool"`
+	// Arguments 填充了默认值的调用参数，再次调用时参数须与之一致
+	Arguments map[string]interface{} `json:"arguments"`
+	// DryRun 预演结果，说明将要进行的修改
+	DryRun interface{} `json:"dry_run,omitempty"`
+	// ConfirmToken 确认令牌，用户确认后携带它再次调用才会执行
+	ConfirmToken     string  `json:"confirm_token"`
+	ExpiresInSeconds float64 `json:"expires_in_seconds"`
+	Message          string  `json:"message"`
+}
+
+// pending 一个等待确认的操作
+type pending struct {
+	tool    string
+	digest  string
+	caller  string
+	expires time.Time
+}
+
+// Manager 签发并校验危险操作的确认令牌
+//
+// 第一次调用危险工具时不执行操作，只返回预览和一个短期有效的确认令牌；
+// 只有使用相同参数并携带该令牌再次调用时才真正执行。令牌只能使用一次，
+// 且与工具名称、参数和调用方绑定。
+type Manager struct {
+	mu     sync.Mutex
+	ttl    time.Duration
+	tokens map[string]pending
+}
+
+// NewManager 创建确认令牌管理器，ttl<=0 时使用默认有效期
+func NewManager(ttl time.Duration) *Manager {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Manager{
+		ttl:    ttl,
+		tokens: make(map[string]pending),
+	}
+}
+
+// WithToken 为工具声明确认令牌参数
+func WithToken() mcp.ToolOption {
+	return mcp.WithString(TokenArg,
+		mcp.Description("危险操作的确认令牌。首次调用不要传入，服务器会返回操作预览和令牌；用户确认后使用相同参数并携带该令牌再次调用才会执行"),
+	)
+}
+
+// Middleware 为标记为危险操作的工具增加确认令牌参数和两阶段确认
+func (m *Manager) Middleware(spec *registry.Spec, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if !spec.Destructive {
+		return next
+	}
+	WithToken()(&spec.Tool)
+	return m.Wrap(&spec.Tool, next)
+}
+
+// Wrap 为工具处理函数加上两阶段确认
+//
+// tool 为工具的声明，调用时按它检查参数并填充默认值后再计算摘要，
+// 因此两次调用中省略默认值和显式写出默认值视为相同的参数。
+func (m *Manager) Wrap(tool *mcp.Tool, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name := request.Params.Name
+		arguments := withoutToken(request.Params.Arguments)
+		caller := callerName(ctx)
+
+		// 预演不会修改任何资源，无需确认
+		if dryRun, _ := arguments[DryRunArg].(bool); dryRun {
+			request.Params.Arguments = arguments
+			return next(ctx, request)
+		}
+
+		validated, err := args.Validate(*tool, arguments)
+		if err != nil {
+			return args.ErrorResult(request, err)
+		}
+		operation := operationArgs(validated)
+		digest, err := digestArgs(operation)
+		if err != nil {
+			return toolerror.Coded(request, toolerror.CodeInvalidArgument, fmt.Sprintf("无法解析工具参数: %v", err))
+		}
+
+		token, _ := request.Params.Arguments[TokenArg].(string)
+		if token == "" {
+			// 先预演一次，预演失败说明操作本身无法执行，不必签发令牌
+			format, _ := output.FormatOf(request)
+			dryRun, err := m.dryRun(ctx, request, next, arguments, format)
+			if err != nil || dryRun.IsError {
+				return dryRun, err
+			}
+
+			token, err = m.issue(name, digest, caller)
+			if err != nil {
+				return toolerror.Coded(request, toolerror.CodeInternal, fmt.Sprintf("生成确认令牌失败: %v", err))
+			}
+			return m.previewResult(request, operation, dryRun, format, token)
+		}
+
+		if !m.consume(token, name, digest, caller) {
+			return toolerror.Coded(request, toolerror.CodeInvalidConfirmation, fmt.Sprintf("确认令牌无效或已过期，或者参数与预览时不同，操作 %s 未执行。请不带 %s 重新调用以获取新的预览和令牌", name, TokenArg))
+		}
+
+		request.Params.Arguments = arguments
+		return next(ctx, request)
+	}
+}
+
+// issue 签发一个新的确认令牌
+func (m *Manager) issue(tool, digest, caller string) (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 顺便清理已过期的令牌
+	now := time.Now()
+	for key, p := range m.tokens {
+		if now.After(p.expires) {
+			delete(m.tokens, key)
+		}
+	}
+
+	m.tokens[token] = pending{
+		tool:    tool,
+		digest:  digest,
+		caller:  caller,
+		expires: now.Add(m.ttl),
+	}
+	return token, nil
+}
+
+// consume 校验并作废确认令牌
+func (m *Manager) consume(token, tool, digest, caller string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.tokens[token]
+	if !ok {
+		return false
+	}
+	if time.Now().After(p.expires) {
+		delete(m.tokens, token)
+		return false
+	}
+	if p.tool != tool || p.digest != digest || p.caller != caller {
+		return false
+	}
+
+	delete(m.tokens, token)
+	return true
+}
+
+// dryRun 以预演模式调用工具，获取将要进行的修改
+//
+// text 格式的预演结果嵌入文本预览中，json/yaml 格式统一以JSON预演，解析后嵌入 Preview。
+func (m *Manager) dryRun(ctx context.Context, request mcp.CallToolRequest, next server.ToolHandlerFunc, arguments map[string]interface{}, format string) (*mcp.CallToolResult, error) {
+	dryRunArgs := make(map[string]interface{}, len(arguments)+1)
+	for name, value := range arguments {
+		dryRunArgs[name] = value
+	}
+	dryRunArgs[DryRunArg] = true
+	dryRunArgs[output.FormatArg] = output.FormatText
+	if format != output.FormatText {
+		dryRunArgs[output.FormatArg] = output.FormatJSON
+	}
+
+	request.Params.Arguments = dryRunArgs
+	return next(ctx, request)
+}
+
+// previewResult 按调用方要求的格式返回待确认的预览
+func (m *Manager) previewResult(request mcp.CallToolRequest, arguments map[string]interface{}, dryRun *mcp.CallToolResult, format, token string) (*mcp.CallToolResult, error) {
+	tool := request.Params.Name
+	if format == output.FormatText {
+		return mcp.NewToolResultText(m.preview(tool, arguments, resultText(dryRun), token)), nil
+	}
+
+	var details interface{}
+	if err := json.Unmarshal([]byte(resultText(dryRun)), &details); err != nil {
+		details = resultText(dryRun)
+	}
+	return output.Result(request, "", Preview{
+		Tool:             tool,
+		Arguments:        arguments,
+		DryRun:           details,
+		ConfirmToken:     token,
+		ExpiresInSeconds: m.ttl.Seconds(),
+		Message: fmt.Sprintf("该操作不可撤销，尚未执行。请向用户展示预览并确认，用户确认后请在 %s 内使用完全相同的参数并携带 %s 再次调用 %s",
+			m.ttl, TokenArg, tool),
+	})
+}
+
+// preview 生成危险操作的预览信息
+func (m *Manager) preview(tool string, arguments map[string]interface{}, dryRun string, token string) string {
+	var result strings.Builder
+	result.WriteString("【待确认】该操作不可撤销，尚未执行\n")
+	result.WriteString(fmt.Sprintf("工具: %s\n", tool))
+
+	if len(arguments) > 0 {
+		result.WriteString("参数:\n")
+		names := make([]string, 0, len(arguments))
+		for name := range arguments {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			result.WriteString(fmt.Sprintf("  %s: %v\n", name, arguments[name]))
+		}
+	}
+
+	if dryRun != "" {
+		result.WriteString("\n")
+		result.WriteString(dryRun)
+	}
+
+	result.WriteString(fmt.Sprintf("\n请向用户展示以上内容并确认。用户确认后，请在 %s 内使用完全相同的参数并携带 %s=%q 再次调用 %s。\n",
+		m.ttl, TokenArg, token, tool))
+	return result.String()
+}
+
+// withoutToken 复制参数并去掉确认令牌
+func withoutToken(arguments map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(arguments))
+	for name, value := range arguments {
+		if name != TokenArg {
+			copied[name] = value
+		}
+	}
+	return copied
+}
+
+// operationArgs 返回决定操作内容的参数，去掉确认令牌、预演开关和输出格式
+func operationArgs(arguments map[string]interface{}) map[string]interface{} {
+	operation := make(map[string]interface{}, len(arguments))
+	for name, value := range arguments {
+		switch name {
+		case TokenArg, DryRunArg, output.FormatArg:
+		default:
+			operation[name] = value
+		}
+	}
+	return operation
+}
+
+// digestArgs 计算参数摘要，encoding/json 会按键名排序，保证结果稳定
+func digestArgs(args map[string]interface{}) (string, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// callerName 返回当前调用方的名称
+func callerName(ctx context.Context) string {
+	if id, ok := auth.IdentityFromContext(ctx); ok {
+		return id.Name
+	}
+	return ""
+}
+
+// resultText 拼接工具结果中的文本内容
+func resultText(result *mcp.CallToolResult) string {
+	if result == nil {
+		return ""
+	}
+	var text strings.Builder
+	for _, content := range result.Content {
+		if textContent, ok := content.(mcp.TextContent); ok {
+			text.WriteString(textContent.Text)
+		}
+	}
+	return text.String()
+}
@@ -0,0 +1,229 @@
+package confirm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp-docker/server/auth"
+	"mcp-docker/server/output"
+	"mcp-docker/server/toolerror"
+)
+
+// testTool 需要确认的工具声明
+var testTool = mcp.NewTool("remove_container",
+	mcp.WithString("container_id", mcp.Required()),
+	mcp.WithBoolean("force", mcp.DefaultBool(false)),
+	mcp.WithBoolean(DryRunArg, mcp.DefaultBool(false)),
+	output.WithFormat(),
+	WithToken(),
+)
+
+// recorder 记录处理函数收到的调用，预演调用返回JSON格式的预演结果
+type recorder struct {
+	calls   int
+	dryRuns int
+}
+
+func (r *recorder) handler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if dryRun, _ := request.Params.Arguments[DryRunArg].(bool); dryRun {
+		r.dryRuns++
+		return output.DryRun(request, "web", "将删除容器 web", nil)
+	}
+	r.calls++
+	return output.Action(request, "web", "容器 web 已删除")
+}
+
+// newHandler 返回加上两阶段确认的处理函数
+func newHandler(m *Manager) (server.ToolHandlerFunc, *recorder) {
+	r := &recorder{}
+	tool := testTool
+	return m.Wrap(&tool, r.handler), r
+}
+
+// callerContext 返回带有调用方身份的上下文
+func callerContext(name string) context.Context {
+	return auth.WithIdentity(context.Background(), auth.Identity{Name: name})
+}
+
+// call 以json格式调用工具
+func call(t *testing.T, ctx context.Context, handler server.ToolHandlerFunc, arguments map[string]interface{}) *mcp.CallToolResult {
+	t.Helper()
+
+	copied := map[string]interface{}{output.FormatArg: output.FormatJSON}
+	for name, value := range arguments {
+		copied[name] = value
+	}
+	var request mcp.CallToolRequest
+	request.Params.Name = testTool.Name
+	request.Params.Arguments = copied
+	result, err := handler(ctx, request)
+	if err != nil {
+		t.Fatalf("不应返回Go错误: %v", err)
+	}
+	return result
+}
+
+// issueToken 不带令牌调用工具，返回预览中的确认令牌
+func issueToken(t *testing.T, ctx context.Context, handler server.ToolHandlerFunc, arguments map[string]interface{}) string {
+	t.Helper()
+
+	result := call(t, ctx, handler, arguments)
+	if result.IsError {
+		t.Fatalf("首次调用应返回预览，实际返回了错误结果: %s", resultText(result))
+	}
+	var preview Preview
+	if err := json.Unmarshal([]byte(resultText(result)), &preview); err != nil {
+		t.Fatalf("解析预览失败: %v", err)
+	}
+	if preview.ConfirmToken == "" {
+		t.Fatal("预览中缺少确认令牌")
+	}
+	if preview.DryRun == nil {
+		t.Error("预览中应包含预演结果")
+	}
+	return preview.ConfirmToken
+}
+
+// withToken 复制参数并加上确认令牌
+func withToken(arguments map[string]interface{}, token string) map[string]interface{} {
+	copied := map[string]interface{}{TokenArg: token}
+	for name, value := range arguments {
+		copied[name] = value
+	}
+	return copied
+}
+
+func TestIssueAndConsume(t *testing.T) {
+	handler, r := newHandler(NewManager(time.Minute))
+	ctx := callerContext("alice")
+	arguments := map[string]interface{}{"container_id": "web"}
+
+	token := issueToken(t, ctx, handler, arguments)
+	if r.calls != 0 || r.dryRuns != 1 {
+		t.Fatalf("首次调用应只预演一次，实际执行 %d 次、预演 %d 次", r.calls, r.dryRuns)
+	}
+
+	// 显式写出默认值视为相同的参数
+	result := call(t, ctx, handler, withToken(map[string]interface{}{"container_id": "web", "force": false}, token))
+	if result.IsError {
+		t.Fatalf("携带令牌的调用应执行，实际返回: %s", resultText(result))
+	}
+	if r.calls != 1 {
+		t.Fatalf("携带令牌的调用应执行一次，实际为 %d 次", r.calls)
+	}
+
+	// 令牌只能使用一次
+	result = call(t, ctx, handler, withToken(arguments, token))
+	if code := toolerror.CodeOfResult(result); code != toolerror.CodeInvalidConfirmation {
+		t.Errorf("重复使用令牌的错误码应为 %s，实际为 %q", toolerror.CodeInvalidConfirmation, code)
+	}
+	if r.calls != 1 {
+		t.Errorf("重复使用令牌不应执行操作，实际执行了 %d 次", r.calls)
+	}
+}
+
+func TestTokenRejected(t *testing.T) {
+	arguments := map[string]interface{}{"container_id": "web"}
+
+	tests := []struct {
+		name string
+		// use 在令牌签发后再次调用，返回调用方上下文和参数
+		use func(m *Manager, token string) (context.Context, map[string]interface{})
+	}{
+		{
+			name: "未知令牌",
+			use: func(m *Manager, token string) (context.Context, map[string]interface{}) {
+				return callerContext("alice"), withToken(arguments, "0000000000000000")
+			},
+		},
+		{
+			name: "令牌已过期",
+			use: func(m *Manager, token string) (context.Context, map[string]interface{}) {
+				m.mu.Lock()
+				p := m.tokens[token]
+				p.expires = time.Now().Add(-time.Second)
+				m.tokens[token] = p
+				m.mu.Unlock()
+				return callerContext("alice"), withToken(arguments, token)
+			},
+		},
+		{
+			name: "不同的调用方",
+			use: func(m *Manager, token string) (context.Context, map[string]interface{}) {
+				return callerContext("bob"), withToken(arguments, token)
+			},
+		},
+		{
+			name: "不同的参数",
+			use: func(m *Manager, token string) (context.Context, map[string]interface{}) {
+				return callerContext("alice"), withToken(map[string]interface{}{"container_id": "db"}, token)
+			},
+		},
+		{
+			name: "增加了参数",
+			use: func(m *Manager, token string) (context.Context, map[string]interface{}) {
+				return callerContext("alice"), withToken(map[string]interface{}{"container_id": "web", "force": true}, token)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(time.Minute)
+			handler, r := newHandler(m)
+			token := issueToken(t, callerContext("alice"), handler, arguments)
+
+			ctx, useArgs := tt.use(m, token)
+			result := call(t, ctx, handler, useArgs)
+			if code := toolerror.CodeOfResult(result); code != toolerror.CodeInvalidConfirmation {
+				t.Errorf("错误码应为 %s，实际为 %q: %s", toolerror.CodeInvalidConfirmation, code, resultText(result))
+			}
+			if r.calls != 0 {
+				t.Errorf("令牌被拒绝时不应执行操作，实际执行了 %d 次", r.calls)
+			}
+		})
+	}
+}
+
+func TestDryRunSkipsConfirmation(t *testing.T) {
+	m := NewManager(time.Minute)
+	handler, r := newHandler(m)
+
+	result := call(t, callerContext("alice"), handler, map[string]interface{}{"container_id": "web", DryRunArg: true})
+	if result.IsError {
+		t.Fatalf("预演调用不应返回错误结果: %s", resultText(result))
+	}
+	var action output.ActionResult
+	if err := json.Unmarshal([]byte(resultText(result)), &action); err != nil {
+		t.Fatalf("解析预演结果失败: %v", err)
+	}
+	if !action.DryRun {
+		t.Errorf("应直接返回预演结果，实际: %+v", action)
+	}
+	if r.dryRuns != 1 || r.calls != 0 {
+		t.Errorf("应只预演一次，实际执行 %d 次、预演 %d 次", r.calls, r.dryRuns)
+	}
+	if len(m.tokens) != 0 {
+		t.Errorf("预演调用不应签发令牌，实际有 %d 个", len(m.tokens))
+	}
+}
+
+func TestFailedDryRunIssuesNoToken(t *testing.T) {
+	m := NewManager(time.Minute)
+	tool := testTool
+	handler := m.Wrap(&tool, func(_ context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return toolerror.Coded(request, toolerror.CodeNotFound, "容器 web 不存在")
+	})
+
+	result := call(t, callerContext("alice"), handler, map[string]interface{}{"container_id": "web"})
+	if code := toolerror.CodeOfResult(result); code != toolerror.CodeNotFound {
+		t.Errorf("应返回预演的错误码 %s，实际为 %q", toolerror.CodeNotFound, code)
+	}
+	if len(m.tokens) != 0 {
+		t.Errorf("预演失败时不应签发令牌，实际有 %d 个", len(m.tokens))
+	}
+}
@@ -0,0 +1,151 @@
+package docker
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/client"
+
+	"mcp-docker/server/toolerror"
+)
+
+// 客户端健康检查配置
+const (
+	// HealthCheckInterval 两次健康检查的最小间隔，间隔内复用上次的检查结果
+	HealthCheckInterval = 30 * time.Second
+	// pingTimeout 单次健康检查的超时时间
+	pingTimeout = 5 * time.Second
+)
+
+// Client 长期复用的Docker客户端
+//
+// 客户端在第一次使用时创建，之后所有工具调用共享同一个连接。每隔 HealthCheckInterval
+// 通过 Ping 检查一次守护进程，检查失败时换上新创建的客户端。
+type Client struct {
+	mu        sync.Mutex
+	opts      []client.Opt
+	cli       *client.Client
+	lastCheck time.Time
+	// owned 为 false 表示客户端由调用方提供，不重新创建也不关闭
+	owned bool
+}
+
+// NewClient 创建托管的Docker客户端，未指定 opts 时按环境变量（DOCKER_HOST等）连接
+func NewClient(opts ...client.Opt) *Client {
+	if len(opts) == 0 {
+		opts = []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	}
+	return &Client{opts: opts, owned: true}
+}
+
+// WrapClient 使用调用方提供的Docker客户端，例如连接测试服务器的客户端
+//
+// 包装后的客户端同样会做健康检查，但失败时只返回错误，不会重新创建；
+// 客户端由调用方负责关闭。
+func WrapClient(cli *client.Client) *Client {
+	return &Client{cli: cli}
+}
+
+// Get 返回可用的Docker客户端，调用方不需要关闭
+//
+// 健康检查和重新连接时不持有锁，一台守护进程响应缓慢不会阻塞其他工具调用。
+func (c *Client) Get(ctx context.Context) (*client.Client, error) {
+	c.mu.Lock()
+	cli, lastCheck := c.cli, c.lastCheck
+	c.mu.Unlock()
+
+	if cli != nil && time.Since(lastCheck) < HealthCheckInterval {
+		return cli, nil
+	}
+
+	if cli != nil {
+		err := ping(ctx, cli)
+		if err == nil {
+			c.checked(cli)
+			return cli, nil
+		}
+		if !c.owned {
+			return cli, err
+		}
+		// 连接失败，丢弃旧客户端后重新连接；其他调用可能已经换上了新的客户端
+		//
+		// 旧客户端不关闭：其他工具调用可能仍在使用它，它的空闲连接会在传输层的空闲超时后自行释放。
+		c.mu.Lock()
+		current := c.cli
+		if current == cli {
+			slog.Warn("Docker守护进程连接失败，正在重新连接", "error", err)
+			c.cli = nil
+		}
+		c.mu.Unlock()
+		if current != nil && current != cli {
+			return current, nil
+		}
+	}
+
+	fresh, err := client.NewClientWithOpts(c.opts...)
+	if err != nil {
+		return nil, toolerror.New(toolerror.CodeBackendUnavailable, "创建Docker客户端失败: %v", err)
+	}
+	if err := ping(ctx, fresh); err != nil {
+		fresh.Close()
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 并发的调用已经重新连接时使用它的客户端
+	if c.cli != nil {
+		fresh.Close()
+		return c.cli, nil
+	}
+	c.cli = fresh
+	c.lastCheck = time.Now()
+	return fresh, nil
+}
+
+// checked 记录 cli 刚刚通过了健康检查，期间客户端被替换时不记录
+func (c *Client) checked(cli *client.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cli == cli {
+		c.lastCheck = time.Now()
+	}
+}
+
+// Ping 立即检查守护进程是否可用，不复用上次的检查结果，检查失败时与 Get 一样会尝试重新连接
+func (c *Client) Ping(ctx context.Context) error {
+	c.mu.Lock()
+	c.lastCheck = time.Time{}
+	c.mu.Unlock()
+
+	_, err := c.Get(ctx)
+	return err
+}
+
+// Close 关闭托管的客户端，调用方提供的客户端不会被关闭
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cli == nil || !c.owned {
+		return nil
+	}
+	err := c.cli.Close()
+	c.cli = nil
+	return err
+}
+
+// ping 检查守护进程是否可用
+func ping(ctx context.Context, cli *client.Client) error {
+	pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	if _, err := cli.Ping(pingCtx); err != nil {
+		return toolerror.New(toolerror.CodeBackendUnavailable, "Docker守护进程不可用: %v", err)
+	}
+	return nil
+}
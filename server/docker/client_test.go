@@ -0,0 +1,71 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/docker/docker/client"
+)
+
+func TestClientReconnectKeepsOldClientUsable(t *testing.T) {
+	var failPings atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/_ping") {
+			if failPings.Add(-1) >= 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("OK"))
+			return
+		}
+		// 模拟执行中的调用，直到测试放行才返回
+		started <- struct{}{}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(runningContainer))
+	}))
+	defer daemon.Close()
+	// 测试提前失败时同样放行，否则关闭测试服务器会一直等待执行中的请求
+	unblock := sync.OnceFunc(func() { close(release) })
+	defer unblock()
+
+	c := NewClient(client.WithHost("tcp://"+strings.TrimPrefix(daemon.URL, "http://")), client.WithVersion("1.45"))
+	defer c.Close()
+
+	old, err := c.Get(context.Background())
+	if err != nil {
+		t.Fatalf("获取客户端失败: %v", err)
+	}
+
+	inFlight := make(chan error, 1)
+	go func() {
+		_, err := old.ContainerInspect(context.Background(), "web")
+		inFlight <- err
+	}()
+	<-started
+
+	// 健康检查失败一次（HEAD 失败后客户端会再用 GET 重试），重新连接后换上新的客户端
+	failPings.Store(2)
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatalf("重新连接应成功: %v", err)
+	}
+	fresh, err := c.Get(context.Background())
+	if err != nil {
+		t.Fatalf("获取客户端失败: %v", err)
+	}
+	if fresh == old {
+		t.Fatal("健康检查失败后应换上新的客户端")
+	}
+
+	// 旧客户端上执行中的调用不受影响
+	unblock()
+	if err := <-inFlight; err != nil {
+		t.Errorf("重新连接不应影响正在使用旧客户端的调用: %v", err)
+	}
+}
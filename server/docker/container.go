@@ -4,136 +4,185 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"strings"
 	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
 	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-docker/server/args"
+	"mcp-docker/server/output"
+	"mcp-docker/server/page"
+	"mcp-docker/server/progress"
+	"mcp-docker/server/toolerror"
 )
 
+// containerTimeoutHint 启动、停止、删除、重启容器超时时的处理建议，操作可能已经在Docker守护进程中完成
+const containerTimeoutHint = "请先使用 list_containers 检查容器的当前状态，确认操作没有完成后再重试"
+
+// listArgs list_containers 和 list_images 的参数
+type listArgs struct {
+	ShowAll bool `json:"show_all"`
+}
+
+// containerArgs 只操作一个容器的工具的参数
+type containerArgs struct {
+	ContainerID string `json:"container_id"`
+}
+
 // 列出容器的工具函数
-func ListContainersTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	apiKey := request.Params.Arguments["api_key"].(string)
-	if apiKey == "" || apiKey != "654321" {
-		return mcp.NewToolResultText("API密钥不正确"), nil
+func (t *Toolset) ListContainersTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params listArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
 	}
-	showAll, _ := request.Params.Arguments["show_all"].(bool)
+	showAll := params.ShowAll
 
-	fmt.Println("ai 正在调用mcp server的tool: list_containers, show_all=", showAll)
+	paging, err := page.Of(request)
+	if err != nil {
+		return args.ErrorResult(request, err)
+	}
 
-	// 创建Docker客户端
-	cli, err := CreateDockerClient()
+	slog.Info("ai 正在调用mcp server的tool", "tool", "list_containers", "show_all", showAll)
+
+	// 获取Docker客户端
+	cli, err := t.hosts.Get(ctx, hostOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Docker客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Docker客户端失败", err)
 	}
-	defer cli.Close()
 
 	// 获取容器列表
 	options := container.ListOptions{All: showAll}
 	containers, err := cli.ContainerList(ctx, options)
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("获取容器列表失败: %v", err)), err
+		return toolerror.Result(request, "获取容器列表失败", err)
 	}
 
 	// 格式化输出
 	var result strings.Builder
+	// Docker按创建时间从新到旧返回容器
+	containers, info := page.Slice(containers, paging)
+	summaries := make([]ContainerSummary, 0, len(containers))
 	result.WriteString("CONTAINER ID\tIMAGE\tCOMMAND\tCREATED\tSTATUS\tPORTS\tNAMES\n")
 	for _, container := range containers {
 		result.WriteString(fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-			container.ID[:12],
+			shortID(container.ID),
 			container.Image,
 			container.Command,
 			fmt.Sprintf("%d seconds ago", container.Created),
 			container.Status,
 			FormatPorts(container.Ports),
 			FormatNames(container.Names)))
+
+		summaries = append(summaries, ContainerSummary{
+			ID:      container.ID,
+			Names:   trimNames(container.Names),
+			Image:   container.Image,
+			Command: container.Command,
+			Created: time.Unix(container.Created, 0),
+			State:   container.State,
+			Status:  container.Status,
+			Ports:   portMappings(container.Ports),
+		})
 	}
 
-	return mcp.NewToolResultText(result.String()), nil
+	return page.Result(request, result.String(), summaries, info)
 }
 
 // 启动容器的工具函数
-func StartContainerTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	containerID := request.Params.Arguments["container_id"].(string)
-
-	fmt.Println("ai 正在调用mcp server的tool: start_container, container_id=", containerID)
+func (t *Toolset) StartContainerTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params containerArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	containerID := params.ContainerID
 
-	// 创建带超时的上下文
-	timeoutCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
+	slog.Info("ai 正在调用mcp server的tool", "tool", "start_container", "container_id", containerID)
 
-	// 创建Docker客户端
-	cli, err := CreateDockerClient()
+	// 获取Docker客户端
+	cli, err := t.hosts.Get(ctx, hostOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Docker客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Docker客户端失败", err)
 	}
-	defer cli.Close()
-
-	// 创建一个结果通道
-	resultChan := make(chan error, 1)
-
-	// 在goroutine中运行容器操作
-	go func() {
-		err = cli.ContainerStart(timeoutCtx, containerID, container.StartOptions{})
-		resultChan <- err
-	}()
 
-	// 等待操作完成或超时
-	select {
-	case err = <-resultChan:
+	// 预演模式只检查将要发生的变化，不做任何修改
+	if IsDryRun(request) {
+		preview, err := previewStartContainer(ctx, cli, containerID)
 		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("启动容器失败: %v", err)), err
+			return toolerror.Result(request, "预演失败", err)
 		}
-		return mcp.NewToolResultText(fmt.Sprintf("容器 %s 已成功启动", containerID)), nil
-	case <-time.After(5 * time.Second):
-		return mcp.NewToolResultText(fmt.Sprintf("启动容器操作超时，但容器可能已启动。请使用 list_containers 检查状态")), nil
+		return output.DryRun(request, containerID, preview, nil)
 	}
+
+	if err := cli.ContainerStart(ctx, containerID, container.StartOptions{}); err != nil {
+		return toolerror.Result(request, "启动容器失败", err)
+	}
+	return output.Action(request, containerID, fmt.Sprintf("容器 %s 已成功启动", containerID))
+}
+
+// createContainerArgs create_container 的参数
+type createContainerArgs struct {
+	Image string `json:"image"`
+	Name  string `json:"name"`
+	// Ports 端口映射，格式为 宿主机端口:容器端口[/协议]
+	Ports   []string `json:"ports"`
+	Volumes []string `json:"volumes"`
+	Env     []string `json:"env"`
+	Command string   `json:"command"`
+	Detach  bool     `json:"detach"`
 }
 
 // 创建容器的工具函数
-func CreateContainerTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	imageName := request.Params.Arguments["image"].(string)
-	containerName, _ := request.Params.Arguments["name"].(string)
-	portsArray, _ := request.Params.Arguments["ports"].([]interface{})
-	volumesArray, _ := request.Params.Arguments["volumes"].([]interface{})
-	envArray, _ := request.Params.Arguments["env"].([]interface{})
-	cmd, _ := request.Params.Arguments["command"].(string)
-	detach, _ := request.Params.Arguments["detach"].(bool)
-
-	fmt.Println("ai 正在调用mcp server的tool: create_container, image=", imageName)
-	fmt.Println("开始创建容器，将显示实时进度...")
-
-	// 创建Docker客户端
-	cli, err := CreateDockerClient()
+func (t *Toolset) CreateContainerTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params createContainerArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	imageName, containerName, cmd, detach := params.Image, params.Name, params.Command, params.Detach
+
+	slog.Info("ai 正在调用mcp server的tool", "tool", "create_container", "image", imageName)
+
+	// 获取Docker客户端
+	cli, err := t.hosts.Get(ctx, hostOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Docker客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Docker客户端失败", err)
 	}
-	defer cli.Close()
 
-	// 准备进度输出
+	// 准备进度输出，客户端提供了 progressToken 时每个步骤都会发送一次进度通知
 	var progressOutput strings.Builder
 	progressOutput.WriteString(fmt.Sprintf("开始创建容器，基于镜像：%s\n", imageName))
-	fmt.Printf("开始创建容器，基于镜像：%s\n", imageName)
+
+	reporter := progress.FromRequest(ctx, request)
+	steps, step := 7, 0
+	if detach {
+		steps = 8
+	}
+	nextStep := func(message string) {
+		step++
+		reporter.Step(step, steps, strings.TrimSpace(message))
+	}
 
 	// 准备端口映射
 	message := "准备端口映射...\n"
 	progressOutput.WriteString(message)
-	fmt.Print(message)
+	nextStep(message)
 
 	portBindings := nat.PortMap{}
 	exposedPorts := nat.PortSet{}
 
-	for _, p := range portsArray {
-		portMapping := p.(string)
+	for _, portMapping := range params.Ports {
 		parts := strings.Split(portMapping, ":")
 		if len(parts) == 2 {
 			hostPort, containerPort := parts[0], parts[1]
 			if !strings.Contains(containerPort, "/") {
 				containerPort = containerPort + "/tcp"
 			}
-			natPort, _ := nat.NewPort("tcp", strings.TrimSuffix(containerPort, "/tcp"))
+			port, proto, _ := strings.Cut(containerPort, "/")
+			natPort, _ := nat.NewPort(proto, port)
 
 			portBindings[natPort] = append(portBindings[natPort], nat.PortBinding{
 				HostIP:   "0.0.0.0",
@@ -143,34 +192,31 @@ func CreateContainerTool(ctx context.Context, request mcp.CallToolRequest) (*mcp
 
 			detail := fmt.Sprintf("  添加端口映射: %s:%s\n", hostPort, containerPort)
 			progressOutput.WriteString(detail)
-			fmt.Print(detail)
 		}
 	}
 
 	// 准备环境变量
 	message = "准备环境变量...\n"
 	progressOutput.WriteString(message)
-	fmt.Print(message)
+	nextStep(message)
 
 	var env []string
-	for _, e := range envArray {
-		env = append(env, e.(string))
-		detail := fmt.Sprintf("  添加环境变量: %s\n", e.(string))
+	for _, e := range params.Env {
+		env = append(env, e)
+		detail := fmt.Sprintf("  添加环境变量: %s\n", e)
 		progressOutput.WriteString(detail)
-		fmt.Print(detail)
 	}
 
 	// 准备卷映射
 	message = "准备卷映射...\n"
 	progressOutput.WriteString(message)
-	fmt.Print(message)
+	nextStep(message)
 
 	var volumes []string
-	for _, v := range volumesArray {
-		volumes = append(volumes, v.(string))
-		detail := fmt.Sprintf("  添加卷映射: %s\n", v.(string))
+	for _, v := range params.Volumes {
+		volumes = append(volumes, v)
+		detail := fmt.Sprintf("  添加卷映射: %s\n", v)
 		progressOutput.WriteString(detail)
-		fmt.Print(detail)
 	}
 
 	// 准备命令
@@ -179,13 +225,12 @@ func CreateContainerTool(ctx context.Context, request mcp.CallToolRequest) (*mcp
 		cmdSlice = strings.Split(cmd, " ")
 		detail := fmt.Sprintf("设置启动命令: %s\n", cmd)
 		progressOutput.WriteString(detail)
-		fmt.Print(detail)
 	}
 
 	// 创建容器配置
 	message = "创建容器配置...\n"
 	progressOutput.WriteString(message)
-	fmt.Print(message)
+	nextStep(message)
 
 	config := &container.Config{
 		Image:        imageName,
@@ -200,13 +245,25 @@ func CreateContainerTool(ctx context.Context, request mcp.CallToolRequest) (*mcp
 		Binds:        volumes,
 	}
 
+	// 预演模式只检查将要发生的变化，不做任何修改
+	if IsDryRun(request) {
+		preview, err := previewCreateContainer(ctx, cli, containerName, config, hostConfig, detach)
+		if err != nil {
+			return toolerror.Result(request, "预演失败", err)
+		}
+		return output.DryRun(request, containerName, preview, map[string]interface{}{
+			"config":      config,
+			"host_config": hostConfig,
+		})
+	}
+
 	// 创建网络配置
 	networkConfig := &network.NetworkingConfig{}
 
 	// 创建容器
 	message = "创建容器中...\n"
 	progressOutput.WriteString(message)
-	fmt.Print(message)
+	nextStep(message)
 
 	resp, err := cli.ContainerCreate(
 		ctx,
@@ -217,22 +274,22 @@ func CreateContainerTool(ctx context.Context, request mcp.CallToolRequest) (*mcp
 		containerName,
 	)
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建容器失败: %v", err)), err
+		return toolerror.Result(request, "创建容器失败", err)
 	}
 
 	message = fmt.Sprintf("容器创建成功，ID: %s\n", resp.ID)
 	progressOutput.WriteString(message)
-	fmt.Print(message)
+	nextStep(message)
 
 	// 如果设置了分离模式，启动容器
 	if detach {
 		message = "正在启动容器...\n"
 		progressOutput.WriteString(message)
-		fmt.Print(message)
+		nextStep(message)
 
 		err = cli.ContainerStart(ctx, resp.ID, container.StartOptions{})
 		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("容器创建成功，但启动失败: %v", err)), err
+			return toolerror.Result(request, "容器创建成功，但启动失败", err)
 		}
 
 		// 等待一下，给容器启动一些时间
@@ -243,204 +300,243 @@ func CreateContainerTool(ctx context.Context, request mcp.CallToolRequest) (*mcp
 		if err == nil && containerInfo.State.Running {
 			message = "容器成功启动并正在运行!\n"
 			progressOutput.WriteString(message)
-			fmt.Print(message)
 		}
 	}
 
 	message = "操作完成!\n"
 	progressOutput.WriteString(message)
-	fmt.Print(message)
-
-	fmt.Println("容器创建完成!")
+	reporter.Step(steps, steps, strings.TrimSpace(message))
 
 	// 返回结果
 	if detach {
-		return mcp.NewToolResultText(fmt.Sprintf("容器已创建并启动，ID: %s\n\n%s", resp.ID, progressOutput.String())), nil
+		return output.Action(request, resp.ID, fmt.Sprintf("容器已创建并启动，ID: %s\n\n%s", resp.ID, progressOutput.String()))
 	}
-	return mcp.NewToolResultText(fmt.Sprintf("容器已创建，ID: %s\n\n%s", resp.ID, progressOutput.String())), nil
+	return output.Action(request, resp.ID, fmt.Sprintf("容器已创建，ID: %s\n\n%s", resp.ID, progressOutput.String()))
 }
 
 // 停止容器的工具函数
-func StopContainerTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	containerID := request.Params.Arguments["container_id"].(string)
-
-	fmt.Println("ai 正在调用mcp server的tool: stop_container, container_id=", containerID)
+func (t *Toolset) StopContainerTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params containerArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	containerID := params.ContainerID
 
-	// 创建带超时的上下文
-	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
+	slog.Info("ai 正在调用mcp server的tool", "tool", "stop_container", "container_id", containerID)
 
-	// 创建Docker客户端
-	cli, err := CreateDockerClient()
+	// 获取Docker客户端
+	cli, err := t.hosts.Get(ctx, hostOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Docker客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Docker客户端失败", err)
 	}
-	defer cli.Close()
-
-	// 创建一个结果通道
-	resultChan := make(chan error, 1)
 
-	// 在goroutine中运行容器操作
-	go func() {
-		err = cli.ContainerStop(timeoutCtx, containerID, container.StopOptions{})
-		resultChan <- err
-	}()
-
-	// 等待操作完成或超时
-	select {
-	case err = <-resultChan:
+	// 预演模式只检查将要发生的变化，不做任何修改
+	if IsDryRun(request) {
+		preview, err := previewStopContainer(ctx, cli, containerID)
 		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("停止容器失败: %v", err)), err
+			return toolerror.Result(request, "预演失败", err)
 		}
-		return mcp.NewToolResultText(fmt.Sprintf("容器 %s 已成功停止", containerID)), nil
-	case <-time.After(15 * time.Second):
-		return mcp.NewToolResultText(fmt.Sprintf("停止容器操作超时，但容器可能已停止。请使用 list_containers 检查状态")), nil
+		return output.DryRun(request, containerID, preview, nil)
 	}
+
+	if err := cli.ContainerStop(ctx, containerID, container.StopOptions{}); err != nil {
+		return toolerror.Result(request, "停止容器失败", err)
+	}
+	return output.Action(request, containerID, fmt.Sprintf("容器 %s 已成功停止", containerID))
 }
 
-// 删除容器的工具函数
-func RemoveContainerTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	containerID := request.Params.Arguments["container_id"].(string)
-	force, _ := request.Params.Arguments["force"].(bool)
+// removeContainerArgs remove_container 的参数
+type removeContainerArgs struct {
+	ContainerID string `json:"container_id"`
+	Force       bool   `json:"force"`
+}
 
-	fmt.Println("ai 正在调用mcp server的tool: remove_container, container_id=", containerID, ", force=", force)
+// 删除容器的工具函数
+func (t *Toolset) RemoveContainerTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params removeContainerArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	containerID, force := params.ContainerID, params.Force
 
-	// 创建带超时的上下文
-	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
+	slog.Info("ai 正在调用mcp server的tool", "tool", "remove_container", "container_id", containerID, "force", force)
 
-	// 创建Docker客户端
-	cli, err := CreateDockerClient()
+	// 获取Docker客户端
+	cli, err := t.hosts.Get(ctx, hostOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Docker客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Docker客户端失败", err)
 	}
-	defer cli.Close()
 
-	// 创建一个结果通道
-	resultChan := make(chan error, 1)
-
-	// 在goroutine中运行容器操作
-	go func() {
-		err = cli.ContainerRemove(timeoutCtx, containerID, container.RemoveOptions{
-			Force: force,
-		})
-		resultChan <- err
-	}()
-
-	// 等待操作完成或超时
-	select {
-	case err = <-resultChan:
+	// 预演模式只检查将要发生的变化，不做任何修改
+	if IsDryRun(request) {
+		preview, err := previewRemoveContainer(ctx, cli, containerID, force)
 		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("删除容器失败: %v", err)), err
+			return toolerror.Result(request, "预演失败", err)
 		}
-		return mcp.NewToolResultText(fmt.Sprintf("容器 %s 已成功删除", containerID)), nil
-	case <-time.After(15 * time.Second):
-		return mcp.NewToolResultText(fmt.Sprintf("删除容器操作超时，但容器可能已删除。请使用 list_containers 检查状态")), nil
+		return output.DryRun(request, containerID, preview, nil)
+	}
+
+	if err := cli.ContainerRemove(ctx, containerID, container.RemoveOptions{
+		Force: force,
+	}); err != nil {
+		return toolerror.Result(request, "删除容器失败", err)
 	}
+	return output.Action(request, containerID, fmt.Sprintf("容器 %s 已成功删除", containerID))
 }
 
-// 重启容器的工具函数
-func RestartContainerTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	containerID := request.Params.Arguments["container_id"].(string)
-	timeout, _ := request.Params.Arguments["timeout"].(float64)
+// restartContainerArgs restart_container 的参数
+type restartContainerArgs struct {
+	ContainerID string `json:"container_id"`
+	// Timeout 停止容器前的等待秒数
+	Timeout int `json:"timeout"`
+}
 
-	fmt.Println("ai 正在调用mcp server的tool: restart_container, container_id=", containerID, ", timeout=", timeout)
+// 重启容器的工具函数
+func (t *Toolset) RestartContainerTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params restartContainerArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	containerID, timeout := params.ContainerID, params.Timeout
 
-	// 创建带超时的上下文
-	timeoutCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
+	slog.Info("ai 正在调用mcp server的tool", "tool", "restart_container", "container_id", containerID, "timeout", timeout)
 
-	// 创建Docker客户端
-	cli, err := CreateDockerClient()
+	// 获取Docker客户端
+	cli, err := t.hosts.Get(ctx, hostOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Docker客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Docker客户端失败", err)
 	}
-	defer cli.Close()
 
-	// 创建一个结果通道
-	resultChan := make(chan error, 1)
-
-	// 在goroutine中运行容器操作
-	go func() {
-		err = cli.ContainerRestart(timeoutCtx, containerID, container.StopOptions{
-			Timeout: IntPtr(int(timeout)),
-		})
-		resultChan <- err
-	}()
-
-	// 等待操作完成或超时
-	select {
-	case err = <-resultChan:
+	// 预演模式只检查将要发生的变化，不做任何修改
+	if IsDryRun(request) {
+		preview, err := previewRestartContainer(ctx, cli, containerID, timeout)
 		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("重启容器失败: %v", err)), err
+			return toolerror.Result(request, "预演失败", err)
 		}
-		return mcp.NewToolResultText(fmt.Sprintf("容器 %s 已成功重启", containerID)), nil
-	case <-time.After(35 * time.Second):
-		return mcp.NewToolResultText(fmt.Sprintf("重启容器操作超时，但容器可能已重启。请使用 list_containers 检查状态")), nil
+		return output.DryRun(request, containerID, preview, nil)
+	}
+
+	if err := cli.ContainerRestart(ctx, containerID, container.StopOptions{
+		Timeout: IntPtr(timeout),
+	}); err != nil {
+		return toolerror.Result(request, "重启容器失败", err)
 	}
+	return output.Action(request, containerID, fmt.Sprintf("容器 %s 已成功重启", containerID))
+}
+
+// containerLogsArgs container_logs 的参数
+type containerLogsArgs struct {
+	ContainerID string `json:"container_id"`
+	Tail        int    `json:"tail"`
+	Timestamps  bool   `json:"timestamps"`
 }
 
 // 查看容器日志的工具函数
-func ContainerLogsTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	containerID := request.Params.Arguments["container_id"].(string)
-	tail, _ := request.Params.Arguments["tail"].(float64)
-	timestamps, _ := request.Params.Arguments["timestamps"].(bool)
+func (t *Toolset) ContainerLogsTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params containerLogsArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	containerID, tail, timestamps := params.ContainerID, params.Tail, params.Timestamps
+
+	slog.Info("ai 正在调用mcp server的tool", "tool", "container_logs", "container_id", containerID, "tail", tail)
 
-	fmt.Println("ai 正在调用mcp server的tool: container_logs, container_id=", containerID, ", tail=", tail)
+	// 获取Docker客户端
+	cli, err := t.hosts.Get(ctx, hostOf(request))
+	if err != nil {
+		return toolerror.Result(request, "获取Docker客户端失败", err)
+	}
+
+	budget, err := page.LogsOf(request, tail)
+	if err != nil {
+		return args.ErrorResult(request, err)
+	}
 
-	// 创建Docker客户端
-	cli, err := CreateDockerClient()
+	// 非TTY容器的日志流中stdout和stderr是复用的，需要先拆分
+	info, err := cli.ContainerInspect(ctx, containerID)
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Docker客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取容器日志失败", err)
 	}
-	defer cli.Close()
 
-	tailStr := fmt.Sprintf("%d", int(tail))
+	// 始终请求时间戳，用于按游标继续读取；游标之后的日志由 budget 过滤，
+	// Docker 先取最后 tail 行再按时间过滤，因此带游标时不能使用 tail
 	options := container.LogsOptions{
 		ShowStdout: true,
 		ShowStderr: true,
-		Timestamps: timestamps,
-		Tail:       tailStr,
+		Timestamps: true,
+		Tail:       "all",
+	}
+	tailed := false
+	switch {
+	case !budget.Until.IsZero():
+		options.Until = budget.Until.Format(time.RFC3339Nano)
+	case !budget.Since.IsZero():
+		options.Since = budget.Since.Format(time.RFC3339Nano)
+	case tail > 0 && budget.Truncate == page.TruncateHead:
+		options.Tail = fmt.Sprintf("%d", tail)
+		tailed = true
 	}
 
 	// 获取日志
 	logs, err := cli.ContainerLogs(ctx, containerID, options)
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("获取容器日志失败: %v", err)), err
+		return toolerror.Result(request, "获取容器日志失败", err)
 	}
 	defer logs.Close()
 
-	// 读取日志内容
-	logBytes, err := io.ReadAll(logs)
-	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("读取容器日志失败: %v", err)), err
+	var reader io.Reader = logs
+	if info.Config == nil || !info.Config.Tty {
+		pr, pw := io.Pipe()
+		defer pr.Close()
+		go func() {
+			_, err := stdcopy.StdCopy(pw, pw, logs)
+			pw.CloseWithError(err)
+		}()
+		reader = pr
 	}
 
-	return mcp.NewToolResultText(string(logBytes)), nil
+	// 按预算读取日志内容
+	read, err := budget.Read(reader, timestamps, tailed)
+	if err != nil {
+		return toolerror.Result(request, "读取容器日志失败", err)
+	}
+
+	return output.Result(request, read.String(), ContainerLogs{
+		ContainerID: containerID,
+		Tail:        tail,
+		Logs:        read.Text,
+		Lines:       read.Lines,
+		Truncated:   read.Truncated,
+		NextCursor:  read.Next,
+		Message:     read.Message,
+	})
 }
 
 // 检查容器状态的工具函数
-func ContainerStatusTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	containerID := request.Params.Arguments["container_id"].(string)
+func (t *Toolset) ContainerStatusTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params containerArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	containerID := params.ContainerID
 
-	fmt.Println("ai 正在调用mcp server的tool: container_status, container_id=", containerID)
+	slog.Info("ai 正在调用mcp server的tool", "tool", "container_status", "container_id", containerID)
 
-	// 创建Docker客户端
-	cli, err := CreateDockerClient()
+	// 获取Docker客户端
+	cli, err := t.hosts.Get(ctx, hostOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Docker客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Docker客户端失败", err)
 	}
-	defer cli.Close()
 
 	// 获取容器信息
 	container, err := cli.ContainerInspect(ctx, containerID)
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("检查容器状态失败: %v", err)), err
+		return toolerror.Result(request, "检查容器状态失败", err)
 	}
 
 	// 格式化输出
 	var result strings.Builder
-	result.WriteString(fmt.Sprintf("容器 ID: %s\n", container.ID[:12]))
+	result.WriteString(fmt.Sprintf("容器 ID: %s\n", shortID(container.ID)))
 	result.WriteString(fmt.Sprintf("名称: %s\n", strings.TrimPrefix(container.Name, "/")))
 	result.WriteString(fmt.Sprintf("状态: %s\n", container.State.Status))
 
@@ -466,12 +562,32 @@ func ContainerStatusTool(ctx context.Context, request mcp.CallToolRequest) (*mcp
 	result.WriteString(fmt.Sprintf("镜像: %s\n", container.Config.Image))
 	result.WriteString(fmt.Sprintf("命令: %s\n", strings.Join(container.Config.Cmd, " ")))
 
+	status := ContainerStatus{
+		ID:         container.ID,
+		Name:       strings.TrimPrefix(container.Name, "/"),
+		Status:     container.State.Status,
+		Running:    container.State.Running,
+		StartedAt:  container.State.StartedAt,
+		FinishedAt: container.State.FinishedAt,
+		ExitCode:   container.State.ExitCode,
+		Error:      container.State.Error,
+		Image:      container.Config.Image,
+		Command:    container.Config.Cmd,
+		Ports:      []PortMapping{},
+		Mounts:     []MountSummary{},
+	}
+
 	// 添加端口信息
 	if len(container.NetworkSettings.Ports) > 0 {
 		result.WriteString("端口映射:\n")
 		for port, bindings := range container.NetworkSettings.Ports {
 			for _, binding := range bindings {
 				result.WriteString(fmt.Sprintf("  %s -> %s:%s\n", port, binding.HostIP, binding.HostPort))
+				status.Ports = append(status.Ports, PortMapping{
+					ContainerPort: string(port),
+					HostIP:        binding.HostIP,
+					HostPort:      binding.HostPort,
+				})
 			}
 		}
 	}
@@ -481,29 +597,39 @@ func ContainerStatusTool(ctx context.Context, request mcp.CallToolRequest) (*mcp
 		result.WriteString("卷挂载:\n")
 		for _, mount := range container.Mounts {
 			result.WriteString(fmt.Sprintf("  %s -> %s (%s)\n", mount.Source, mount.Destination, mount.Type))
+			status.Mounts = append(status.Mounts, MountSummary{
+				Type:        string(mount.Type),
+				Name:        mount.Name,
+				Source:      mount.Source,
+				Destination: mount.Destination,
+				RW:          mount.RW,
+			})
 		}
 	}
 
-	return mcp.NewToolResultText(result.String()), nil
+	return output.Result(request, result.String(), status)
 }
 
 // 查看容器详细信息的工具函数
-func InspectContainerTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	containerID := request.Params.Arguments["container_id"].(string)
+func (t *Toolset) InspectContainerTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params containerArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	containerID := params.ContainerID
 
-	fmt.Println("ai 正在调用mcp server的tool: inspect_container, container_id=", containerID)
+	slog.Info("ai 正在调用mcp server的tool", "tool", "inspect_container", "container_id", containerID)
 
-	// 创建Docker客户端
-	cli, err := CreateDockerClient()
+	// 获取Docker客户端
+	cli, err := t.hosts.Get(ctx, hostOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Docker客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Docker客户端失败", err)
 	}
-	defer cli.Close()
 
 	// 获取容器信息
 	container, err := cli.ContainerInspect(ctx, containerID)
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("检查容器详情失败: %v", err)), err
+		return toolerror.Result(request, "检查容器详情失败", err)
 	}
 
 	// 格式化完整的容器详情
@@ -573,7 +699,8 @@ func InspectContainerTool(ctx context.Context, request mcp.CallToolRequest) (*mc
 	result.WriteString(fmt.Sprintf("  命令: %s\n", strings.Join(container.Config.Cmd, " ")))
 	result.WriteString(fmt.Sprintf("  入口点: %s\n", strings.Join(container.Config.Entrypoint, " ")))
 
-	return mcp.NewToolResultText(result.String()), nil
+	// 结构化输出与 docker inspect 的结果一致
+	return output.Result(request, result.String(), container)
 }
 
 // 创建一个整数指针
@@ -0,0 +1,331 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-docker/server/internal/tooltest"
+	"mcp-docker/server/output"
+	"mcp-docker/server/toolerror"
+)
+
+// runningContainer 假守护进程返回的正在运行的容器 web
+var runningContainer = `{"Id":"` + strings.Repeat("a", 64) + `","Name":"/web","State":{"Status":"running","Running":true},"Config":{"Image":"nginx"}}`
+
+// newTestToolset 创建连接到假守护进程的工具集，handler 处理除 /_ping 以外的Docker API请求
+func newTestToolset(t *testing.T, handler http.HandlerFunc) *Toolset {
+	t.Helper()
+
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/_ping") {
+			w.Write([]byte("OK"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		handler(w, r)
+	}))
+	t.Cleanup(daemon.Close)
+
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://"+strings.TrimPrefix(daemon.URL, "http://")), client.WithVersion("1.45"))
+	if err != nil {
+		t.Fatalf("创建Docker客户端失败: %v", err)
+	}
+	t.Cleanup(func() { cli.Close() })
+	return NewToolset(SingleHost(DefaultHostName, cli.DaemonHost(), WrapClient(cli)))
+}
+
+func TestListContainersTool(t *testing.T) {
+	toolset := newTestToolset(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/containers/json") {
+			t.Errorf("未预期的请求: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("all") != "1" {
+			t.Errorf("show_all=true 时应请求全部容器，实际查询参数: %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`[
+			{"Id":"` + strings.Repeat("a", 64) + `","Names":["/web"],"Image":"nginx","State":"running","Status":"Up 1 minute"},
+			{"Id":"` + strings.Repeat("b", 64) + `","Names":["/db"],"Image":"postgres","State":"exited","Status":"Exited (0)"}
+		]`))
+	})
+
+	result, err := toolset.ListContainersTool(context.Background(), tooltest.Request("list_containers", map[string]interface{}{
+		"show_all":       true,
+		"limit":          1,
+		output.FormatArg: output.FormatJSON,
+	}))
+	if err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+
+	var list struct {
+		Items      []ContainerSummary `json:"items"`
+		Total      int                `json:"total"`
+		NextCursor string             `json:"next_cursor"`
+	}
+	tooltest.JSON(t, result, &list)
+	if len(list.Items) != 1 || list.Items[0].Names[0] != "web" || list.Items[0].Image != "nginx" {
+		t.Errorf("第一页应只包含容器 web，实际: %+v", list.Items)
+	}
+	if list.Total != 2 || list.NextCursor == "" {
+		t.Errorf("应返回总数 2 和下一页的游标，实际 total=%d next_cursor=%q", list.Total, list.NextCursor)
+	}
+}
+
+func TestListContainersForgedCursor(t *testing.T) {
+	toolset := newTestToolset(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"Id":"` + strings.Repeat("a", 64) + `","Names":["/web"],"Image":"nginx","State":"running"}]`))
+	})
+
+	// 直接调用处理函数，不经过 args.Middleware 的 recover
+	cursor := base64.RawURLEncoding.EncodeToString([]byte(`{"tool":"list_containers","offset":-5}`))
+	result, err := toolset.ListContainersTool(context.Background(), tooltest.Request("list_containers", map[string]interface{}{
+		"cursor": cursor,
+	}))
+	if err != nil {
+		t.Fatalf("不应返回Go错误: %v", err)
+	}
+	if code := toolerror.CodeOfResult(result); code != toolerror.CodeInvalidArgument {
+		t.Errorf("伪造的游标应返回错误码 %s，实际为 %q: %s", toolerror.CodeInvalidArgument, code, tooltest.Text(t, result))
+	}
+}
+
+func TestContainerActions(t *testing.T) {
+	type handlerFunc = func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error)
+
+	tests := []struct {
+		tool      string
+		handler   func(*Toolset) handlerFunc
+		arguments map[string]interface{}
+		method    string
+		path      string
+		query     string
+	}{
+		{
+			tool:    "start_container",
+			handler: func(ts *Toolset) handlerFunc { return ts.StartContainerTool },
+			method:  http.MethodPost,
+			path:    "/containers/web/start",
+		},
+		{
+			tool:    "stop_container",
+			handler: func(ts *Toolset) handlerFunc { return ts.StopContainerTool },
+			method:  http.MethodPost,
+			path:    "/containers/web/stop",
+		},
+		{
+			tool:      "restart_container",
+			handler:   func(ts *Toolset) handlerFunc { return ts.RestartContainerTool },
+			arguments: map[string]interface{}{"timeout": 5},
+			method:    http.MethodPost,
+			path:      "/containers/web/restart",
+			query:     "t=5",
+		},
+		{
+			tool:      "remove_container",
+			handler:   func(ts *Toolset) handlerFunc { return ts.RemoveContainerTool },
+			arguments: map[string]interface{}{"force": true},
+			method:    http.MethodDelete,
+			path:      "/containers/web",
+			query:     "force=1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			called := false
+			toolset := newTestToolset(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != tt.method || !strings.HasSuffix(r.URL.Path, tt.path) {
+					t.Errorf("未预期的请求: %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				if tt.query != "" && !strings.Contains(r.URL.RawQuery, tt.query) {
+					t.Errorf("查询参数应包含 %s，实际为 %s", tt.query, r.URL.RawQuery)
+				}
+				called = true
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			arguments := map[string]interface{}{"container_id": "web", output.FormatArg: output.FormatJSON}
+			for name, value := range tt.arguments {
+				arguments[name] = value
+			}
+			result, err := tt.handler(toolset)(context.Background(), tooltest.Request(tt.tool, arguments))
+			if err != nil {
+				t.Fatalf("不应返回Go错误: %v", err)
+			}
+
+			var action output.ActionResult
+			tooltest.JSON(t, result, &action)
+			if !called {
+				t.Error("没有请求Docker守护进程")
+			}
+			if action.Action != tt.tool || action.Target != "web" || action.DryRun {
+				t.Errorf("执行结果不正确: %+v", action)
+			}
+		})
+	}
+}
+
+func TestContainerActionsDryRun(t *testing.T) {
+	type handlerFunc = func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error)
+
+	tests := []struct {
+		tool      string
+		handler   func(*Toolset) handlerFunc
+		arguments map[string]interface{}
+		// preview 预演说明中应包含的内容
+		preview string
+	}{
+		{
+			tool:    "start_container",
+			handler: func(ts *Toolset) handlerFunc { return ts.StartContainerTool },
+			preview: "容器已在运行",
+		},
+		{
+			tool:    "stop_container",
+			handler: func(ts *Toolset) handlerFunc { return ts.StopContainerTool },
+			preview: "running -> exited",
+		},
+		{
+			tool:      "restart_container",
+			handler:   func(ts *Toolset) handlerFunc { return ts.RestartContainerTool },
+			arguments: map[string]interface{}{"timeout": 5},
+			preview:   "最多等待 5 秒",
+		},
+		{
+			tool:      "remove_container",
+			handler:   func(ts *Toolset) handlerFunc { return ts.RemoveContainerTool },
+			arguments: map[string]interface{}{"force": true},
+			preview:   "强制终止后删除",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			toolset := newTestToolset(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet || !strings.HasSuffix(r.URL.Path, "/containers/web/json") {
+					t.Errorf("预演不应修改容器，实际请求: %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.Write([]byte(runningContainer))
+			})
+
+			arguments := map[string]interface{}{"container_id": "web", DryRunArg: true, output.FormatArg: output.FormatJSON}
+			for name, value := range tt.arguments {
+				arguments[name] = value
+			}
+			result, err := tt.handler(toolset)(context.Background(), tooltest.Request(tt.tool, arguments))
+			if err != nil {
+				t.Fatalf("不应返回Go错误: %v", err)
+			}
+
+			var action output.ActionResult
+			tooltest.JSON(t, result, &action)
+			if !action.DryRun || action.Target != "web" {
+				t.Errorf("应返回预演结果，实际: %+v", action)
+			}
+			if !strings.Contains(action.Message, tt.preview) {
+				t.Errorf("预演说明应包含 %q，实际为 %q", tt.preview, action.Message)
+			}
+		})
+	}
+}
+
+func TestRemoveRunningContainerPreviewConflict(t *testing.T) {
+	toolset := newTestToolset(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || !strings.HasSuffix(r.URL.Path, "/containers/web/json") {
+			t.Errorf("预演不应修改容器，实际请求: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(runningContainer))
+	})
+
+	result, err := toolset.RemoveContainerTool(context.Background(), tooltest.Request("remove_container", map[string]interface{}{
+		"container_id": "web",
+		DryRunArg:      true,
+	}))
+	if err != nil {
+		t.Fatalf("不应返回Go错误: %v", err)
+	}
+	if code := toolerror.CodeOfResult(result); code != toolerror.CodeConflict {
+		t.Errorf("删除正在运行且未设置 force 的容器，预演的错误码应为 %s，实际为 %q: %s", toolerror.CodeConflict, code, tooltest.Text(t, result))
+	}
+}
+
+func TestContainerToolsNotFound(t *testing.T) {
+	toolset := newTestToolset(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"No such container: missing"}`))
+	})
+
+	tests := []struct {
+		name    string
+		handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error)
+		request mcp.CallToolRequest
+	}{
+		{
+			name:    "inspect_container",
+			handler: toolset.InspectContainerTool,
+			request: tooltest.Request("inspect_container", map[string]interface{}{"container_id": "missing"}),
+		},
+		{
+			// 预演的错误同样要保留 errdefs，两阶段确认总是先预演
+			name:    "remove_container dry_run",
+			handler: toolset.RemoveContainerTool,
+			request: tooltest.Request("remove_container", map[string]interface{}{"container_id": "missing", DryRunArg: true}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.handler(context.Background(), tt.request)
+			if err != nil {
+				t.Fatalf("不应返回Go错误: %v", err)
+			}
+			if code := toolerror.CodeOfResult(result); code != toolerror.CodeNotFound {
+				t.Errorf("错误码应为 %s，实际为 %q: %s", toolerror.CodeNotFound, code, tooltest.Text(t, result))
+			}
+		})
+	}
+}
+
+func TestContainerErrorCodes(t *testing.T) {
+	tests := []struct {
+		status int
+		code   string
+	}{
+		{status: http.StatusNotFound, code: toolerror.CodeNotFound},
+		{status: http.StatusConflict, code: toolerror.CodeConflict},
+		{status: http.StatusForbidden, code: toolerror.CodePermissionDenied},
+		{status: http.StatusBadRequest, code: toolerror.CodeInvalidArgument},
+		{status: http.StatusServiceUnavailable, code: toolerror.CodeBackendUnavailable},
+		{status: http.StatusInternalServerError, code: toolerror.CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			toolset := newTestToolset(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{"message":"` + http.StatusText(tt.status) + `"}`))
+			})
+
+			result, err := toolset.StartContainerTool(context.Background(), tooltest.Request("start_container", map[string]interface{}{
+				"container_id": "web",
+			}))
+			if err != nil {
+				t.Fatalf("不应返回Go错误: %v", err)
+			}
+			if code := toolerror.CodeOfResult(result); code != tt.code {
+				t.Errorf("状态码 %d 的错误码应为 %s，实际为 %q: %s", tt.status, tt.code, code, tooltest.Text(t, result))
+			}
+		})
+	}
+}
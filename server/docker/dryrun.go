@@ -0,0 +1,435 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-docker/server/toolerror"
+)
+
+// DryRunHeader 预演结果的统一开头
+const DryRunHeader = "【预演】以下为将要进行的修改，未做任何实际更改\n"
+
+// anonymousVolumeLabel Docker为匿名卷添加的标签，默认情况下卷清理只会删除匿名卷
+const anonymousVolumeLabel = "com.docker.volume.anonymous"
+
+// predefinedNetworks Docker内置网络，清理时不会被删除
+var predefinedNetworks = map[string]bool{
+	"bridge": true,
+	"host":   true,
+	"none":   true,
+}
+
+// DryRunArg 修改类工具选择预演模式的参数名
+const DryRunArg = "dry_run"
+
+// WithDryRun 为修改类工具声明预演参数
+func WithDryRun() mcp.ToolOption {
+	return mcp.WithBoolean(DryRunArg,
+		mcp.Description("是否只预演，返回将要进行的修改而不实际执行"),
+		mcp.DefaultBool(false),
+	)
+}
+
+// IsDryRun 判断本次调用是否为预演
+func IsDryRun(request mcp.CallToolRequest) bool {
+	dryRun, _ := request.Params.Arguments[DryRunArg].(bool)
+	return dryRun
+}
+
+// 辅助函数：预演启动容器
+func previewStartContainer(ctx context.Context, cli *client.Client, containerID string) (string, error) {
+	info, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("检查容器失败: %w", err)
+	}
+
+	var result strings.Builder
+	result.WriteString(DryRunHeader)
+	writeContainerSummary(&result, info)
+	if info.State.Running {
+		result.WriteString("容器已在运行，启动操作不会产生任何变化\n")
+	} else {
+		result.WriteString(fmt.Sprintf("将启动容器，状态变化: %s -> running\n", info.State.Status))
+	}
+	return result.String(), nil
+}
+
+// 辅助函数：预演停止容器
+func previewStopContainer(ctx context.Context, cli *client.Client, containerID string) (string, error) {
+	info, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("检查容器失败: %w", err)
+	}
+
+	var result strings.Builder
+	result.WriteString(DryRunHeader)
+	writeContainerSummary(&result, info)
+	if !info.State.Running {
+		result.WriteString("容器未在运行，停止操作不会产生任何变化\n")
+	} else {
+		result.WriteString(fmt.Sprintf("将停止容器，状态变化: %s -> exited\n", info.State.Status))
+	}
+	return result.String(), nil
+}
+
+// 辅助函数：预演重启容器
+func previewRestartContainer(ctx context.Context, cli *client.Client, containerID string, timeout int) (string, error) {
+	info, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("检查容器失败: %w", err)
+	}
+
+	var result strings.Builder
+	result.WriteString(DryRunHeader)
+	writeContainerSummary(&result, info)
+	if info.State.Running {
+		result.WriteString(fmt.Sprintf("将停止容器(最多等待 %d 秒后强制终止)，然后重新启动\n", timeout))
+	} else {
+		result.WriteString(fmt.Sprintf("容器当前未运行，将直接启动，状态变化: %s -> running\n", info.State.Status))
+	}
+	return result.String(), nil
+}
+
+// 辅助函数：预演删除容器
+func previewRemoveContainer(ctx context.Context, cli *client.Client, containerID string, force bool) (string, error) {
+	info, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("检查容器失败: %w", err)
+	}
+
+	var result strings.Builder
+	result.WriteString(DryRunHeader)
+	writeContainerSummary(&result, info)
+	if info.State.Running && !force {
+		return "", toolerror.New(toolerror.CodeConflict, "容器 %s 正在运行，未设置 force 时无法删除，请先停止容器或设置 force=true", containerID)
+	}
+	if info.State.Running {
+		result.WriteString("容器正在运行，将被强制终止后删除\n")
+	} else {
+		result.WriteString("将删除该容器\n")
+	}
+	for _, mount := range info.Mounts {
+		if mount.Type == "volume" {
+			result.WriteString(fmt.Sprintf("  卷 %s 将被保留，不会随容器删除\n", mount.Name))
+		}
+	}
+	return result.String(), nil
+}
+
+// 辅助函数：预演创建容器，展示最终提交给Docker的配置
+func previewCreateContainer(ctx context.Context, cli *client.Client, containerName string, config *container.Config, hostConfig *container.HostConfig, detach bool) (string, error) {
+	var result strings.Builder
+	result.WriteString(DryRunHeader)
+
+	if containerName != "" {
+		result.WriteString(fmt.Sprintf("容器名称: %s\n", containerName))
+	} else {
+		result.WriteString("容器名称: <由Docker自动生成>\n")
+	}
+
+	if _, err := cli.ImageInspect(ctx, config.Image); err != nil {
+		result.WriteString(fmt.Sprintf("注意: 本地不存在镜像 %s，实际创建会失败，请先使用 pull_image 拉取\n", config.Image))
+	}
+
+	configJSON, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("序列化容器配置失败: %w", err)
+	}
+	hostConfigJSON, err := json.MarshalIndent(hostConfig, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("序列化主机配置失败: %w", err)
+	}
+
+	result.WriteString(fmt.Sprintf("container.Config:\n%s\n", configJSON))
+	result.WriteString(fmt.Sprintf("container.HostConfig:\n%s\n", hostConfigJSON))
+	if detach {
+		result.WriteString("创建后将立即启动容器\n")
+	} else {
+		result.WriteString("仅创建容器，不会启动\n")
+	}
+	return result.String(), nil
+}
+
+// 辅助函数：预演删除镜像
+func previewRemoveImage(ctx context.Context, cli *client.Client, imageID string, force bool) (string, error) {
+	info, err := cli.ImageInspect(ctx, imageID)
+	if err != nil {
+		return "", fmt.Errorf("检查镜像失败: %w", err)
+	}
+
+	var result strings.Builder
+	result.WriteString(DryRunHeader)
+	result.WriteString(fmt.Sprintf("镜像ID: %s\n", info.ID))
+	if len(info.RepoTags) > 0 {
+		result.WriteString(fmt.Sprintf("标签: %s\n", strings.Join(info.RepoTags, ", ")))
+	}
+	result.WriteString(fmt.Sprintf("大小: %s\n", FormatSize(uint64(info.Size))))
+
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("ancestor", info.ID)),
+	})
+	if err != nil {
+		return "", fmt.Errorf("获取容器列表失败: %w", err)
+	}
+	if len(containers) > 0 {
+		result.WriteString("使用该镜像的容器:\n")
+		for _, c := range containers {
+			result.WriteString(fmt.Sprintf("  %s %s (%s)\n", shortID(c.ID), FormatNames(c.Names), c.State))
+		}
+		if !force {
+			result.WriteString("镜像仍被容器使用且未设置 force，实际执行时删除会失败\n")
+			return result.String(), nil
+		}
+	}
+
+	// 镜像有多个标签且按标签删除时，只会移除该标签
+	if len(info.RepoTags) > 1 {
+		for _, tag := range info.RepoTags {
+			if tag == imageID {
+				result.WriteString(fmt.Sprintf("镜像有多个标签，将只移除标签 %s\n", imageID))
+				return result.String(), nil
+			}
+		}
+	}
+	result.WriteString("将删除该镜像及其未被其他镜像引用的父层\n")
+	return result.String(), nil
+}
+
+// 辅助函数：预演拉取镜像
+func previewPullImage(ctx context.Context, cli *client.Client, imageName string) (string, error) {
+	var result strings.Builder
+	result.WriteString(DryRunHeader)
+	result.WriteString(fmt.Sprintf("镜像: %s\n", imageName))
+
+	local, err := cli.ImageInspect(ctx, imageName)
+	if err == nil {
+		result.WriteString(fmt.Sprintf("本地已存在该镜像 (ID: %s)\n", local.ID))
+		if len(local.RepoDigests) > 0 {
+			result.WriteString(fmt.Sprintf("本地摘要: %s\n", strings.Join(local.RepoDigests, ", ")))
+		}
+	} else {
+		result.WriteString("本地不存在该镜像，将从镜像仓库完整拉取\n")
+	}
+
+	remote, err := cli.DistributionInspect(ctx, imageName, "")
+	if err != nil {
+		result.WriteString(fmt.Sprintf("无法查询镜像仓库中的镜像信息: %v\n", err))
+		return result.String(), nil
+	}
+	result.WriteString(fmt.Sprintf("仓库摘要: %s\n", remote.Descriptor.Digest))
+
+	for _, digest := range local.RepoDigests {
+		if strings.HasSuffix(digest, "@"+remote.Descriptor.Digest.String()) {
+			result.WriteString("本地镜像已是最新版本，拉取不会产生变化\n")
+			return result.String(), nil
+		}
+	}
+	result.WriteString("将从镜像仓库下载新版本并更新本地标签\n")
+	return result.String(), nil
+}
+
+// 辅助函数：预演系统清理，按实际清理顺序列出将被删除的对象
+func previewSystemPrune(ctx context.Context, cli *client.Client, all bool) (string, SystemPruneReport, error) {
+	var result strings.Builder
+	result.WriteString(DryRunHeader)
+	report := newSystemPruneReport(true)
+
+	// 正在运行的容器使用的对象不会被清理
+	running, err := cli.ContainerList(ctx, container.ListOptions{})
+	if err != nil {
+		return "", report, fmt.Errorf("获取容器列表失败: %w", err)
+	}
+	usedNetworks := map[string]bool{}
+	usedImages := map[string]bool{}
+	usedVolumes := map[string]bool{}
+	for _, c := range running {
+		usedImages[c.ImageID] = true
+		if c.NetworkSettings != nil {
+			for _, endpoint := range c.NetworkSettings.Networks {
+				usedNetworks[endpoint.NetworkID] = true
+			}
+		}
+		for _, mount := range c.Mounts {
+			if mount.Type == "volume" {
+				usedVolumes[mount.Name] = true
+			}
+		}
+	}
+
+	// 已停止的容器
+	stopped, err := cli.ContainerList(ctx, container.ListOptions{
+		All:  true,
+		Size: true,
+		Filters: filters.NewArgs(
+			filters.Arg("status", "created"),
+			filters.Arg("status", "exited"),
+			filters.Arg("status", "dead"),
+		),
+	})
+	if err != nil {
+		return "", report, fmt.Errorf("获取已停止容器失败: %w", err)
+	}
+	if len(stopped) > 0 {
+		result.WriteString("将删除的容器:\n")
+		for _, c := range stopped {
+			result.WriteString(fmt.Sprintf("  %s %s (%s, %s)\n", shortID(c.ID), FormatNames(c.Names), c.Status, FormatSize(uint64(c.SizeRw))))
+			report.ContainersDeleted = append(report.ContainersDeleted, c.ID)
+			report.SpaceReclaimed += uint64(c.SizeRw)
+		}
+	} else {
+		result.WriteString("没有容器会被删除\n")
+	}
+
+	// 未被运行中容器使用的自定义网络
+	networks, err := cli.NetworkList(ctx, network.ListOptions{})
+	if err != nil {
+		return "", report, fmt.Errorf("获取网络列表失败: %w", err)
+	}
+	var prunableNetworks []network.Summary
+	for _, n := range networks {
+		if predefinedNetworks[n.Name] || usedNetworks[n.ID] || n.Scope == "swarm" {
+			continue
+		}
+		prunableNetworks = append(prunableNetworks, n)
+	}
+	if len(prunableNetworks) > 0 {
+		result.WriteString("将删除的网络:\n")
+		for _, n := range prunableNetworks {
+			result.WriteString(fmt.Sprintf("  %s %s (%s)\n", shortID(n.ID), n.Name, n.Driver))
+			report.NetworksDeleted = append(report.NetworksDeleted, n.Name)
+		}
+	} else {
+		result.WriteString("没有网络会被删除\n")
+	}
+
+	// 悬空镜像，仅在 all=true 时清理
+	if all {
+		dangling, err := cli.ImageList(ctx, image.ListOptions{
+			Filters: filters.NewArgs(filters.Arg("dangling", "true")),
+		})
+		if err != nil {
+			return "", report, fmt.Errorf("获取悬空镜像失败: %w", err)
+		}
+		var prunableImages []image.Summary
+		for _, img := range dangling {
+			if !usedImages[img.ID] {
+				prunableImages = append(prunableImages, img)
+			}
+		}
+		if len(prunableImages) > 0 {
+			result.WriteString("将删除的镜像:\n")
+			for _, img := range prunableImages {
+				result.WriteString(fmt.Sprintf("  %s (%s)\n", shortID(img.ID), FormatSize(uint64(img.Size))))
+				report.ImagesDeleted = append(report.ImagesDeleted, ImageDeleteItem{Deleted: img.ID})
+				report.SpaceReclaimed += uint64(img.Size)
+			}
+		} else {
+			result.WriteString("没有镜像会被删除\n")
+		}
+	} else {
+		result.WriteString("未设置 all，镜像不会被清理\n")
+	}
+
+	// 未被运行中容器使用的匿名卷
+	volumes, err := cli.VolumeList(ctx, volume.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", anonymousVolumeLabel)),
+	})
+	if err != nil {
+		return "", report, fmt.Errorf("获取卷列表失败: %w", err)
+	}
+	var prunableVolumes []string
+	for _, v := range volumes.Volumes {
+		if !usedVolumes[v.Name] {
+			prunableVolumes = append(prunableVolumes, v.Name)
+		}
+	}
+	if len(prunableVolumes) > 0 {
+		result.WriteString("将删除的匿名卷(占用空间未计入估算):\n")
+		for _, name := range prunableVolumes {
+			result.WriteString(fmt.Sprintf("  %s\n", name))
+			report.VolumesDeleted = append(report.VolumesDeleted, name)
+		}
+	} else {
+		result.WriteString("没有卷会被删除\n")
+	}
+
+	result.WriteString(fmt.Sprintf("预计释放空间: %s\n", FormatSize(report.SpaceReclaimed)))
+	return result.String(), report, nil
+}
+
+// 辅助函数：预演删除卷
+func previewRemoveVolume(ctx context.Context, cli *client.Client, volumeName string) (string, error) {
+	info, err := cli.VolumeInspect(ctx, volumeName)
+	if err != nil {
+		return "", fmt.Errorf("检查卷失败: %w", err)
+	}
+
+	var result strings.Builder
+	result.WriteString(DryRunHeader)
+	result.WriteString(fmt.Sprintf("卷: %s\n", info.Name))
+	result.WriteString(fmt.Sprintf("驱动: %s\n", info.Driver))
+	result.WriteString(fmt.Sprintf("挂载点: %s\n", info.Mountpoint))
+
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("volume", info.Name)),
+	})
+	if err != nil {
+		return "", fmt.Errorf("获取容器列表失败: %w", err)
+	}
+	if len(containers) > 0 {
+		result.WriteString("卷仍被以下容器使用，实际执行时删除会失败:\n")
+		for _, c := range containers {
+			result.WriteString(fmt.Sprintf("  %s %s (%s)\n", shortID(c.ID), FormatNames(c.Names), c.State))
+		}
+		return result.String(), nil
+	}
+	result.WriteString("将删除该卷及其中的全部数据\n")
+	return result.String(), nil
+}
+
+// 辅助函数：预演删除网络
+func previewRemoveNetwork(ctx context.Context, cli *client.Client, networkID string) (string, error) {
+	info, err := cli.NetworkInspect(ctx, networkID, network.InspectOptions{})
+	if err != nil {
+		return "", fmt.Errorf("检查网络失败: %w", err)
+	}
+
+	var result strings.Builder
+	result.WriteString(DryRunHeader)
+	result.WriteString(fmt.Sprintf("网络: %s (%s)\n", info.Name, shortID(info.ID)))
+	result.WriteString(fmt.Sprintf("驱动: %s\n", info.Driver))
+
+	if predefinedNetworks[info.Name] {
+		result.WriteString("这是Docker内置网络，实际执行时删除会失败\n")
+		return result.String(), nil
+	}
+	if len(info.Containers) > 0 {
+		result.WriteString("网络仍连接着以下容器，实际执行时删除会失败:\n")
+		for id, endpoint := range info.Containers {
+			result.WriteString(fmt.Sprintf("  %s %s\n", shortID(id), endpoint.Name))
+		}
+		return result.String(), nil
+	}
+	result.WriteString("将删除该网络\n")
+	return result.String(), nil
+}
+
+// 辅助函数：输出容器的基本信息
+func writeContainerSummary(result *strings.Builder, info container.InspectResponse) {
+	result.WriteString(fmt.Sprintf("容器: %s (%s)\n", strings.TrimPrefix(info.Name, "/"), shortID(info.ID)))
+	result.WriteString(fmt.Sprintf("镜像: %s\n", info.Config.Image))
+	result.WriteString(fmt.Sprintf("当前状态: %s\n", info.State.Status))
+}
@@ -0,0 +1,58 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"mcp-docker/server/internal/tooltest"
+	"mcp-docker/server/output"
+)
+
+func TestShortID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{id: strings.Repeat("a", 64), want: strings.Repeat("a", 12)},
+		{id: "sha256:" + strings.Repeat("b", 64), want: strings.Repeat("b", 12)},
+		{id: "sha256:abc", want: "abc"},
+		{id: "abc", want: "abc"},
+		{id: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := shortID(tt.id); got != tt.want {
+			t.Errorf("shortID(%q) 应为 %q，实际为 %q", tt.id, tt.want, got)
+		}
+	}
+}
+
+func TestRemoveNetworkPreviewShortIDs(t *testing.T) {
+	// 非Moby守护进程返回的ID可能不足12位，预演不能因此panic
+	toolset := newTestToolset(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || !strings.HasSuffix(r.URL.Path, "/networks/backend") {
+			t.Errorf("预演不应修改网络，实际请求: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"Name":"backend","Id":"net1","Driver":"bridge","Containers":{"c1":{"Name":"web"}}}`))
+	})
+
+	result, err := toolset.RemoveNetworkTool(context.Background(), tooltest.Request("remove_network", map[string]interface{}{
+		"network_id":     "backend",
+		DryRunArg:        true,
+		output.FormatArg: output.FormatJSON,
+	}))
+	if err != nil {
+		t.Fatalf("不应返回Go错误: %v", err)
+	}
+
+	var action output.ActionResult
+	tooltest.JSON(t, result, &action)
+	for _, want := range []string{"backend (net1)", "c1 web"} {
+		if !strings.Contains(action.Message, want) {
+			t.Errorf("预演说明应包含 %q，实际为 %q", want, action.Message)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"gopkg.in/yaml.v3"
+
+	"mcp-docker/server/args"
+	"mcp-docker/server/page"
+	"mcp-docker/server/toolerror"
+)
+
+// HostArg 选择Docker主机的参数名
+const HostArg = "host"
+
+// DefaultHostName 未配置Docker主机时，按环境变量连接的主机名称
+const DefaultHostName = "default"
+
+// HostConfig 一个Docker守护进程的连接配置
+type HostConfig struct {
+	// Name 主机名称，工具通过 host 参数引用
+	Name string `yaml:"name"`
+	// Host 守护进程地址，支持 unix://、tcp:// 和 ssh://user@host:port
+	Host string `yaml:"host"`
+	// TLS tcp:// 地址使用的客户端证书，为空时不启用TLS
+	TLS *TLSConfig `yaml:"tls,omitempty"`
+}
+
+// TLSConfig 连接Docker守护进程的TLS证书路径
+type TLSConfig struct {
+	CA   string `yaml:"ca"`
+	Cert string `yaml:"cert"`
+	Key  string `yaml:"key"`
+}
+
+// HostsConfig Docker主机配置文件的内容
+type HostsConfig struct {
+	Hosts []HostConfig `yaml:"hosts"`
+	// Default 未指定 host 参数时使用的主机，为空时使用第一个主机
+	Default string `yaml:"default"`
+}
+
+// LoadHostsConfig 从YAML文件加载Docker主机配置
+func LoadHostsConfig(file string) (*HostsConfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("读取Docker主机配置文件失败: %v", err)
+	}
+
+	var config HostsConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("解析Docker主机配置文件失败: %v", err)
+	}
+	return &config, nil
+}
+
+// Hosts 按名称管理的Docker主机，每个主机对应一个长期复用的客户端
+type Hosts struct {
+	names       []string
+	endpoints   map[string]string
+	clients     map[string]*Client
+	defaultName string
+}
+
+// NewHosts 根据配置创建Docker主机，config 为空或没有主机时按环境变量连接名为 default 的主机
+func NewHosts(config *HostsConfig) (*Hosts, error) {
+	if config == nil || len(config.Hosts) == 0 {
+		endpoint := os.Getenv("DOCKER_HOST")
+		if endpoint == "" {
+			endpoint = client.DefaultDockerHost
+		}
+		return SingleHost(DefaultHostName, endpoint, NewClient()), nil
+	}
+
+	h := &Hosts{
+		endpoints: make(map[string]string, len(config.Hosts)),
+		clients:   make(map[string]*Client, len(config.Hosts)),
+	}
+	for _, host := range config.Hosts {
+		if host.Name == "" {
+			return nil, fmt.Errorf("Docker主机 %s 缺少名称", host.Host)
+		}
+		if _, ok := h.clients[host.Name]; ok {
+			return nil, fmt.Errorf("Docker主机 %s 重复定义", host.Name)
+		}
+		opts, err := hostOptions(host)
+		if err != nil {
+			return nil, fmt.Errorf("Docker主机 %s 配置无效: %v", host.Name, err)
+		}
+		h.names = append(h.names, host.Name)
+		h.endpoints[host.Name] = host.Host
+		h.clients[host.Name] = NewClient(opts...)
+	}
+
+	h.defaultName = config.Default
+	if h.defaultName == "" {
+		h.defaultName = h.names[0]
+	}
+	if _, ok := h.clients[h.defaultName]; !ok {
+		return nil, fmt.Errorf("默认Docker主机 %s 未定义", h.defaultName)
+	}
+	return h, nil
+}
+
+// SingleHost 只包含一个主机的 Hosts，用于嵌入时注入调用方的客户端
+func SingleHost(name, endpoint string, cli *Client) *Hosts {
+	return &Hosts{
+		names:       []string{name},
+		endpoints:   map[string]string{name: endpoint},
+		clients:     map[string]*Client{name: cli},
+		defaultName: name,
+	}
+}
+
+// Get 返回指定主机的Docker客户端，name 为空时使用默认主机
+func (h *Hosts) Get(ctx context.Context, name string) (*client.Client, error) {
+	if name == "" {
+		name = h.defaultName
+	}
+	cli, ok := h.clients[name]
+	if !ok {
+		return nil, toolerror.New(toolerror.CodeInvalidArgument, "未知的Docker主机 %s，可选值: %s", name, strings.Join(h.names, "、"))
+	}
+	return cli.Get(ctx)
+}
+
+// Names 按配置顺序返回所有主机的名称
+func (h *Hosts) Names() []string {
+	return append([]string(nil), h.names...)
+}
+
+// Ping 检查指定主机的守护进程是否可用
+func (h *Hosts) Ping(ctx context.Context, name string) error {
+	cli, ok := h.clients[name]
+	if !ok {
+		return toolerror.New(toolerror.CodeInvalidArgument, "未知的Docker主机 %s，可选值: %s", name, strings.Join(h.names, "、"))
+	}
+	return cli.Ping(ctx)
+}
+
+// Close 关闭所有主机的客户端
+func (h *Hosts) Close() error {
+	var errs []string
+	for _, name := range h.names {
+		if err := h.clients[name].Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("关闭Docker客户端失败: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// WithHost 为工具声明 host 参数
+func WithHost() mcp.ToolOption {
+	return mcp.WithString(HostArg,
+		mcp.Description("Docker主机名称，为空时使用默认主机；可通过 list_docker_hosts 查看"),
+	)
+}
+
+// hostOf 返回调用方选择的Docker主机
+func hostOf(request mcp.CallToolRequest) string {
+	host, _ := request.Params.Arguments[HostArg].(string)
+	return host
+}
+
+// hostOptions 根据主机配置生成客户端选项
+func hostOptions(host HostConfig) ([]client.Opt, error) {
+	opts := []client.Opt{client.WithAPIVersionNegotiation()}
+	switch {
+	case strings.HasPrefix(host.Host, "ssh://"):
+		if host.TLS != nil {
+			return nil, fmt.Errorf("ssh地址不支持TLS配置")
+		}
+		dialer, err := sshDialer(host.Host)
+		if err != nil {
+			return nil, err
+		}
+		// 请求经由ssh转发，这里的地址只用于构造HTTP请求
+		opts = append(opts, client.WithHost("http://docker.example.com"), client.WithDialContext(dialer))
+	case strings.HasPrefix(host.Host, "unix://"), strings.HasPrefix(host.Host, "tcp://"), strings.HasPrefix(host.Host, "npipe://"):
+		opts = append(opts, client.WithHost(host.Host))
+		if host.TLS != nil {
+			opts = append(opts, client.WithTLSClientConfig(host.TLS.CA, host.TLS.Cert, host.TLS.Key))
+		}
+	default:
+		return nil, fmt.Errorf("不支持的地址 %q，应以 unix://、tcp://、npipe:// 或 ssh:// 开头", host.Host)
+	}
+	return opts, nil
+}
+
+// 列出Docker主机的工具函数
+func (t *Toolset) ListDockerHostsTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	paging, err := page.Of(request)
+	if err != nil {
+		return args.ErrorResult(request, err)
+	}
+
+	slog.Info("ai 正在调用mcp server的tool", "tool", "list_docker_hosts")
+
+	// 只检查本页的主机，并发检查避免一台不可达的主机拖慢整个列表
+	names, info := page.Slice(t.hosts.names, paging)
+	summaries := make([]HostSummary, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			summaries[i] = t.hosts.check(ctx, name)
+		}(i, name)
+	}
+	wg.Wait()
+
+	// 格式化输出
+	var result strings.Builder
+	result.WriteString("DEFAULT\tNAME\tENDPOINT\tREACHABLE\tVERSION\tAPI VERSION\tERROR\n")
+	for _, summary := range summaries {
+		isDefault := ""
+		if summary.Default {
+			isDefault = "*"
+		}
+		result.WriteString(fmt.Sprintf("%s\t%s\t%s\t%t\t%s\t%s\t%s\n",
+			isDefault,
+			summary.Name,
+			summary.Endpoint,
+			summary.Reachable,
+			summary.Version,
+			summary.APIVersion,
+			summary.Error))
+	}
+
+	return page.Result(request, result.String(), summaries, info)
+}
+
+// check 检查主机是否可达并获取守护进程版本
+func (h *Hosts) check(ctx context.Context, name string) HostSummary {
+	summary := HostSummary{
+		Name:     name,
+		Endpoint: h.endpoints[name],
+		Default:  name == h.defaultName,
+	}
+
+	cli, err := h.clients[name].Get(ctx)
+	if err != nil {
+		summary.Error = err.Error()
+		return summary
+	}
+	version, err := cli.ServerVersion(ctx)
+	if err != nil {
+		summary.Error = fmt.Sprintf("获取版本信息失败: %v", err)
+		return summary
+	}
+	summary.Reachable = true
+	summary.Version = version.Version
+	summary.APIVersion = version.APIVersion
+	return summary
+}
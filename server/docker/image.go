@@ -3,36 +3,68 @@ package docker
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/api/types/image"
 	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-docker/server/args"
+	"mcp-docker/server/output"
+	"mcp-docker/server/page"
+	"mcp-docker/server/progress"
+	"mcp-docker/server/toolerror"
 )
 
 // 列出镜像的工具函数
-func ListImagesTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+func (t *Toolset) ListImagesTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params listArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	showAll := params.ShowAll
 
-	showAll, _ := request.Params.Arguments["show_all"].(bool)
+	paging, err := page.Of(request)
+	if err != nil {
+		return args.ErrorResult(request, err)
+	}
 
-	fmt.Println("ai 正在调用mcp server的tool: list_images, show_all=", showAll)
+	slog.Info("ai 正在调用mcp server的tool", "tool", "list_images", "show_all", showAll)
 
-	// 创建Docker客户端
-	cli, err := CreateDockerClient()
+	// 获取Docker客户端
+	cli, err := t.hosts.Get(ctx, hostOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Docker客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Docker客户端失败", err)
 	}
-	defer cli.Close()
 
 	// 获取镜像列表
 	images, err := cli.ImageList(ctx, image.ListOptions{All: showAll})
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("获取镜像列表失败: %v", err)), err
+		return toolerror.Result(request, "获取镜像列表失败", err)
 	}
 
 	// 格式化输出
 	var result strings.Builder
+	// 按创建时间从新到旧排序，保证分页的顺序稳定
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].Created != images[j].Created {
+			return images[i].Created > images[j].Created
+		}
+		return images[i].ID < images[j].ID
+	})
+	images, info := page.Slice(images, paging)
+	summaries := make([]ImageSummary, 0, len(images))
 	result.WriteString("REPOSITORY\tTAG\tIMAGE ID\tCREATED\tSIZE\n")
 	for _, img := range images {
+		summaries = append(summaries, ImageSummary{
+			ID:       img.ID,
+			RepoTags: img.RepoTags,
+			Created:  time.Unix(img.Created, 0),
+			Size:     img.Size,
+		})
+
 		var repo, tag string
 		if len(img.RepoTags) > 0 && img.RepoTags[0] != "<none>:<none>" {
 			for _, repoTag := range img.RepoTags {
@@ -40,34 +72,51 @@ func ListImagesTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.Call
 				result.WriteString(fmt.Sprintf("%s\t%s\t%s\t%s\t%s\n",
 					repo,
 					tag,
-					img.ID[7:19],
+					shortID(img.ID),
 					fmt.Sprintf("%d seconds ago", img.Created),
 					FormatSize(uint64(img.Size))))
 			}
 		} else {
 			result.WriteString(fmt.Sprintf("<none>\t<none>\t%s\t%s\t%s\n",
-				img.ID[7:19],
+				shortID(img.ID),
 				fmt.Sprintf("%d seconds ago", img.Created),
 				FormatSize(uint64(img.Size))))
 		}
 	}
 
-	return mcp.NewToolResultText(result.String()), nil
+	return page.Result(request, result.String(), summaries, info)
+}
+
+// removeImageArgs remove_image 的参数
+type removeImageArgs struct {
+	ImageID string `json:"image_id"`
+	Force   bool   `json:"force"`
 }
 
 // 删除镜像的工具函数
-func RemoveImageTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	imageID := request.Params.Arguments["image_id"].(string)
-	force, _ := request.Params.Arguments["force"].(bool)
+func (t *Toolset) RemoveImageTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params removeImageArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	imageID, force := params.ImageID, params.Force
 
-	fmt.Println("ai 正在调用mcp server的tool: remove_image, image_id=", imageID, ", force=", force)
+	slog.Info("ai 正在调用mcp server的tool", "tool", "remove_image", "image_id", imageID, "force", force)
 
-	// 创建Docker客户端
-	cli, err := CreateDockerClient()
+	// 获取Docker客户端
+	cli, err := t.hosts.Get(ctx, hostOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Docker客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Docker客户端失败", err)
+	}
+
+	// 预演模式只检查将要发生的变化，不做任何修改
+	if IsDryRun(request) {
+		preview, err := previewRemoveImage(ctx, cli, imageID, force)
+		if err != nil {
+			return toolerror.Result(request, "预演失败", err)
+		}
+		return output.DryRun(request, imageID, preview, nil)
 	}
-	defer cli.Close()
 
 	// 删除镜像
 	_, err = cli.ImageRemove(ctx, imageID, image.RemoveOptions{
@@ -75,50 +124,73 @@ func RemoveImageTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.Cal
 		PruneChildren: true,
 	})
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("删除镜像失败: %v", err)), err
+		return toolerror.Result(request, "删除镜像失败", err)
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("镜像 %s 已成功删除", imageID)), nil
+	return output.Action(request, imageID, fmt.Sprintf("镜像 %s 已成功删除", imageID))
+}
+
+// pullImageArgs pull_image 的参数
+type pullImageArgs struct {
+	ImageName string `json:"image_name"`
 }
 
 // 拉取镜像的工具函数
-func PullImageTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	imageName := request.Params.Arguments["image_name"].(string)
+func (t *Toolset) PullImageTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params pullImageArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	imageName := params.ImageName
 
-	fmt.Println("ai 正在调用mcp server的tool: pull_image, image_name=", imageName)
-	fmt.Println("开始拉取镜像，将显示实时进度...")
+	slog.Info("ai 正在调用mcp server的tool", "tool", "pull_image", "image_name", imageName)
 
-	// 创建Docker客户端
-	cli, err := CreateDockerClient()
+	// 获取Docker客户端
+	cli, err := t.hosts.Get(ctx, hostOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Docker客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Docker客户端失败", err)
+	}
+
+	// 预演模式只检查将要发生的变化，不做任何修改
+	if IsDryRun(request) {
+		preview, err := previewPullImage(ctx, cli, imageName)
+		if err != nil {
+			return toolerror.Result(request, "预演失败", err)
+		}
+		return output.DryRun(request, imageName, preview, nil)
 	}
-	defer cli.Close()
 
 	// 拉取镜像
 	reader, err := cli.ImagePull(ctx, imageName, image.PullOptions{})
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("拉取镜像失败: %v", err)), err
+		return toolerror.Result(request, "拉取镜像失败", err)
 	}
 	defer reader.Close()
 
-	// 创建进度读取器
+	// 创建进度读取器，客户端提供了 progressToken 时实时发送各层的字节数和总体进度
+	reporter := progress.FromRequest(ctx, request)
 	progressReader := NewProgressReader(reader)
+	progressReader.OnProgress = func(p PullProgress) {
+		reporter.Report(p.Percent, 100,
+			fmt.Sprintf("拉取镜像 %s: %.1f%%，%d/%d 层已完成", imageName, p.Percent, p.Completed, len(p.Layers)),
+			map[string]any{
+				"layers":        p.Layers,
+				"current_bytes": p.CurrentBytes,
+				"total_bytes":   p.TotalBytes,
+			})
+	}
 	progressReader.StartProgress()
 
 	// 收集所有进度更新
 	var progressOutput strings.Builder
 	progressOutput.WriteString(fmt.Sprintf("开始拉取镜像: %s\n", imageName))
-	fmt.Printf("开始拉取镜像: %s\n", imageName)
 
-	// 显示进度更新
 	for update := range progressReader.Updates {
 		progressOutput.WriteString(update)
-		// 实时打印进度到服务器控制台
-		fmt.Print(update)
+	}
+	if err := progressReader.Err(); err != nil {
+		return toolerror.Result(request, "拉取镜像失败", err)
 	}
 
-	fmt.Println("镜像拉取完成!")
-
-	return mcp.NewToolResultText(fmt.Sprintf("成功拉取镜像: %s\n\n%s", imageName, progressOutput.String())), nil
+	return output.Action(request, imageName, fmt.Sprintf("成功拉取镜像: %s\n\n%s", imageName, progressOutput.String()))
 }
@@ -3,61 +3,100 @@ package docker
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"sort"
 	"strings"
 
 	"github.com/docker/docker/api/types/network"
 	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-docker/server/args"
+	"mcp-docker/server/output"
+	"mcp-docker/server/page"
+	"mcp-docker/server/toolerror"
 )
 
 // 列出网络的工具函数
-func ListNetworksTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	fmt.Println("ai 正在调用mcp server的tool: list_networks")
+func (t *Toolset) ListNetworksTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	paging, err := page.Of(request)
+	if err != nil {
+		return args.ErrorResult(request, err)
+	}
 
-	// 创建Docker客户端
-	cli, err := CreateDockerClient()
+	slog.Info("ai 正在调用mcp server的tool", "tool", "list_networks")
+
+	// 获取Docker客户端
+	cli, err := t.hosts.Get(ctx, hostOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Docker客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Docker客户端失败", err)
 	}
-	defer cli.Close()
 
 	// 获取网络列表
 	networks, err := cli.NetworkList(ctx, network.ListOptions{})
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("获取网络列表失败: %v", err)), err
+		return toolerror.Result(request, "获取网络列表失败", err)
 	}
 
 	// 格式化输出
 	var result strings.Builder
+	// 按名称排序，保证分页的顺序稳定
+	sort.Slice(networks, func(i, j int) bool { return networks[i].Name < networks[j].Name })
+	networks, info := page.Slice(networks, paging)
+	summaries := make([]NetworkSummary, 0, len(networks))
 	result.WriteString("NETWORK ID\tNAME\tDRIVER\tSCOPE\n")
 	for _, network := range networks {
 		result.WriteString(fmt.Sprintf("%s\t%s\t%s\t%s\n",
-			network.ID[:12],
+			shortID(network.ID),
 			network.Name,
 			network.Driver,
 			network.Scope))
+
+		summaries = append(summaries, NetworkSummary{
+			ID:     network.ID,
+			Name:   network.Name,
+			Driver: network.Driver,
+			Scope:  network.Scope,
+		})
 	}
 
-	return mcp.NewToolResultText(result.String()), nil
+	return page.Result(request, result.String(), summaries, info)
+}
+
+// removeNetworkArgs remove_network 的参数
+type removeNetworkArgs struct {
+	NetworkID string `json:"network_id"`
 }
 
 // 删除网络的工具函数
-func RemoveNetworkTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	networkID := request.Params.Arguments["network_id"].(string)
+func (t *Toolset) RemoveNetworkTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params removeNetworkArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	networkID := params.NetworkID
 
-	fmt.Println("ai 正在调用mcp server的tool: remove_network, network_id=", networkID)
+	slog.Info("ai 正在调用mcp server的tool", "tool", "remove_network", "network_id", networkID)
 
-	// 创建Docker客户端
-	cli, err := CreateDockerClient()
+	// 获取Docker客户端
+	cli, err := t.hosts.Get(ctx, hostOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Docker客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Docker客户端失败", err)
+	}
+
+	// 预演模式只检查将要发生的变化，不做任何修改
+	if IsDryRun(request) {
+		preview, err := previewRemoveNetwork(ctx, cli, networkID)
+		if err != nil {
+			return toolerror.Result(request, "预演失败", err)
+		}
+		return output.DryRun(request, networkID, preview, nil)
 	}
-	defer cli.Close()
 
 	// 删除网络
 	err = cli.NetworkRemove(ctx, networkID)
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("删除网络失败: %v", err)), err
+		return toolerror.Result(request, "删除网络失败", err)
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("网络 %s 已成功删除", networkID)), nil
+	return output.Action(request, networkID, fmt.Sprintf("网络 %s 已成功删除", networkID))
 }
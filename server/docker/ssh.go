@@ -0,0 +1,159 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// exitWaitTimeout 连接断开后等待ssh进程退出的时间
+const exitWaitTimeout = 2 * time.Second
+
+// sshDialer 返回通过ssh连接远程Docker守护进程的拨号函数
+//
+// 与 docker CLI 的做法相同：在远程主机上执行 `docker system dial-stdio`，
+// 通过ssh进程的标准输入输出转发Docker API请求。认证使用本机的ssh配置和密钥。
+func sshDialer(host string) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("解析ssh地址失败: %v", err)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("ssh地址 %s 缺少主机名", host)
+	}
+	if u.Path != "" && u.Path != "/" {
+		return nil, fmt.Errorf("ssh地址 %s 不支持路径", host)
+	}
+
+	args := []string{"-o", "ConnectTimeout=30", "-T"}
+	if u.User != nil {
+		args = append(args, "-l", u.User.Username())
+	}
+	if u.Port() != "" {
+		args = append(args, "-p", u.Port())
+	}
+	args = append(args, "--", u.Hostname(), "docker", "system", "dial-stdio")
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// 连接的生命周期由HTTP客户端管理，不能随单次请求的ctx结束
+		cmd := exec.Command("ssh", args...)
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		// 自己创建管道，这样进程退出后仍能读完管道中剩余的数据
+		stdout, stdoutWriter, err := os.Pipe()
+		if err != nil {
+			return nil, err
+		}
+		cmd.Stdout = stdoutWriter
+		stderr := &lockedBuffer{}
+		cmd.Stderr = stderr
+		if err := cmd.Start(); err != nil {
+			stdout.Close()
+			stdoutWriter.Close()
+			return nil, fmt.Errorf("启动ssh失败: %v", err)
+		}
+		stdoutWriter.Close()
+
+		conn := &commandConn{
+			cmd:    cmd,
+			stdin:  stdin,
+			stdout: stdout,
+			stderr: stderr,
+			host:   u.Host,
+			exited: make(chan struct{}),
+		}
+		go func() {
+			cmd.Wait()
+			close(conn.exited)
+		}()
+		return conn, nil
+	}, nil
+}
+
+// commandConn 把子进程的标准输入输出包装成 net.Conn
+type commandConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	stderr *lockedBuffer
+	host   string
+	// exited 进程退出且错误输出收集完毕后关闭
+	exited chan struct{}
+
+	closeOnce sync.Once
+}
+
+func (c *commandConn) Read(p []byte) (int, error) {
+	n, err := c.stdout.Read(p)
+	if err == io.EOF {
+		// 等待进程退出，以便返回ssh输出的错误原因
+		select {
+		case <-c.exited:
+		case <-time.After(exitWaitTimeout):
+		}
+		if message := strings.TrimSpace(c.stderr.String()); message != "" {
+			return n, fmt.Errorf("ssh连接已断开: %s", message)
+		}
+	}
+	return n, err
+}
+
+func (c *commandConn) Write(p []byte) (int, error) {
+	return c.stdin.Write(p)
+}
+
+func (c *commandConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.stdin.Close()
+		c.stdout.Close()
+		c.cmd.Process.Kill()
+		<-c.exited
+	})
+	return nil
+}
+
+func (c *commandConn) LocalAddr() net.Addr {
+	return commandAddr("localhost")
+}
+
+func (c *commandConn) RemoteAddr() net.Addr {
+	return commandAddr(c.host)
+}
+
+// 子进程不支持超时设置，超时由请求的ctx控制
+func (c *commandConn) SetDeadline(t time.Time) error      { return nil }
+func (c *commandConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *commandConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// lockedBuffer 并发安全的缓冲区，用于收集ssh进程的错误输出
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// commandAddr ssh连接的地址
+type commandAddr string
+
+func (a commandAddr) Network() string { return "ssh" }
+func (a commandAddr) String() string  { return string(a) }
@@ -3,34 +3,58 @@ package docker
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"strings"
 
 	"github.com/docker/docker/api/types/filters"
 	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-docker/server/args"
+	"mcp-docker/server/output"
+	"mcp-docker/server/toolerror"
 )
 
-// 系统清理的响应结构体
+// 系统清理的响应结构体，预演时列出的是将被删除的对象，SpaceReclaimed 为估算值
 type SystemPruneReport struct {
-	ContainersDeleted []string
-	ImagesDeleted     []map[string]string
-	SpaceReclaimed    uint64
+	DryRun            bool              `json:"dry_run"`
+	ContainersDeleted []string          `json:"containers_deleted"`
+	ImagesDeleted     []ImageDeleteItem `json:"images_deleted"`
+	NetworksDeleted   []string          `json:"networks_deleted"`
+	VolumesDeleted    []string          `json:"volumes_deleted"`
+	SpaceReclaimed    uint64            `json:"space_reclaimed"`
+}
+
+// 被清理的镜像，取消标记和删除分别对应 docker image prune 输出中的 Untagged 和 Deleted
+type ImageDeleteItem struct {
+	Untagged string `json:"untagged,omitempty"`
+	Deleted  string `json:"deleted,omitempty"`
+}
+
+// 创建字段均为空列表的清理报告，保证JSON输出中不会出现 null
+func newSystemPruneReport(dryRun bool) SystemPruneReport {
+	return SystemPruneReport{
+		DryRun:            dryRun,
+		ContainersDeleted: []string{},
+		ImagesDeleted:     []ImageDeleteItem{},
+		NetworksDeleted:   []string{},
+		VolumesDeleted:    []string{},
+	}
 }
 
 // 系统信息工具函数
-func SystemInfoTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	fmt.Println("ai 正在调用mcp server的tool: system_info")
+func (t *Toolset) SystemInfoTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	slog.Info("ai 正在调用mcp server的tool", "tool", "system_info")
 
-	// 创建Docker客户端
-	cli, err := CreateDockerClient()
+	// 获取Docker客户端
+	cli, err := t.hosts.Get(ctx, hostOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Docker客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Docker客户端失败", err)
 	}
-	defer cli.Close()
 
 	// 获取系统信息
 	info, err := cli.Info(ctx)
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("获取系统信息失败: %v", err)), err
+		return toolerror.Result(request, "获取系统信息失败", err)
 	}
 
 	// 格式化输出
@@ -48,54 +72,93 @@ func SystemInfoTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.Call
 	result.WriteString(fmt.Sprintf("Docker根目录: %s\n", info.DockerRootDir))
 	result.WriteString(fmt.Sprintf("日志驱动: %s\n", info.LoggingDriver))
 	result.WriteString(fmt.Sprintf("Cgroup驱动: %s\n", info.CgroupDriver))
+	if t.readOnly {
+		result.WriteString("MCP服务器模式: 只读，不提供修改资源的工具\n")
+	} else {
+		result.WriteString("MCP服务器模式: 读写\n")
+	}
 
-	return mcp.NewToolResultText(result.String()), nil
+	return output.Result(request, result.String(), SystemInfo{
+		ServerVersion:     info.ServerVersion,
+		Containers:        info.Containers,
+		ContainersRunning: info.ContainersRunning,
+		ContainersPaused:  info.ContainersPaused,
+		ContainersStopped: info.ContainersStopped,
+		Images:            info.Images,
+		Driver:            info.Driver,
+		OperatingSystem:   info.OperatingSystem,
+		Architecture:      info.Architecture,
+		KernelVersion:     info.KernelVersion,
+		NCPU:              info.NCPU,
+		MemTotal:          info.MemTotal,
+		DockerRootDir:     info.DockerRootDir,
+		LoggingDriver:     info.LoggingDriver,
+		CgroupDriver:      info.CgroupDriver,
+		ReadOnly:          t.readOnly,
+	})
+}
+
+// systemPruneArgs system_prune 的参数
+type systemPruneArgs struct {
+	All bool `json:"all"`
 }
 
 // 系统清理工具函数
-func SystemPruneTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	all, _ := request.Params.Arguments["all"].(bool)
+func (t *Toolset) SystemPruneTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params systemPruneArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	all := params.All
 
-	fmt.Println("ai 正在调用mcp server的tool: system_prune, all=", all)
+	slog.Info("ai 正在调用mcp server的tool", "tool", "system_prune", "all", all)
 
-	// 创建Docker客户端
-	cli, err := CreateDockerClient()
+	// 获取Docker客户端
+	cli, err := t.hosts.Get(ctx, hostOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Docker客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Docker客户端失败", err)
+	}
+
+	// 预演模式只检查将要发生的变化，不做任何修改
+	if IsDryRun(request) {
+		preview, report, err := previewSystemPrune(ctx, cli, all)
+		if err != nil {
+			return toolerror.Result(request, "预演失败", err)
+		}
+		return output.Result(request, preview, report)
 	}
-	defer cli.Close()
 
 	// 手动实现系统清理功能
-	pruneReport := SystemPruneReport{}
+	pruneReport := newSystemPruneReport(false)
 
 	// 清理未使用的容器
 	containersPrune, err := cli.ContainersPrune(ctx, filters.NewArgs())
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("清理容器失败: %v", err)), err
+		return toolerror.Result(request, "清理容器失败", err)
 	}
-	pruneReport.ContainersDeleted = containersPrune.ContainersDeleted
+	pruneReport.ContainersDeleted = append(pruneReport.ContainersDeleted, containersPrune.ContainersDeleted...)
 	pruneReport.SpaceReclaimed += containersPrune.SpaceReclaimed
 
 	// 清理未使用的网络
-	_, err = cli.NetworksPrune(ctx, filters.NewArgs())
+	networksPrune, err := cli.NetworksPrune(ctx, filters.NewArgs())
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("清理网络失败: %v", err)), err
+		return toolerror.Result(request, "清理网络失败", err)
 	}
+	pruneReport.NetworksDeleted = append(pruneReport.NetworksDeleted, networksPrune.NetworksDeleted...)
 
 	// 清理未使用的镜像
 	if all {
 		imagesPrune, err := cli.ImagesPrune(ctx, filters.NewArgs())
 		if err != nil {
-			return mcp.NewToolResultText(fmt.Sprintf("清理镜像失败: %v", err)), err
+			return toolerror.Result(request, "清理镜像失败", err)
 		}
 
 		// 转换镜像删除响应项
 		for _, item := range imagesPrune.ImagesDeleted {
-			imgItem := map[string]string{
-				"Untagged": item.Untagged,
-				"Deleted":  item.Deleted,
-			}
-			pruneReport.ImagesDeleted = append(pruneReport.ImagesDeleted, imgItem)
+			pruneReport.ImagesDeleted = append(pruneReport.ImagesDeleted, ImageDeleteItem{
+				Untagged: item.Untagged,
+				Deleted:  item.Deleted,
+			})
 		}
 
 		pruneReport.SpaceReclaimed += imagesPrune.SpaceReclaimed
@@ -104,8 +167,9 @@ func SystemPruneTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.Cal
 	// 清理未使用的卷
 	volumesPrune, err := cli.VolumesPrune(ctx, filters.NewArgs())
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("清理卷失败: %v", err)), err
+		return toolerror.Result(request, "清理卷失败", err)
 	}
+	pruneReport.VolumesDeleted = append(pruneReport.VolumesDeleted, volumesPrune.VolumesDeleted...)
 	pruneReport.SpaceReclaimed += volumesPrune.SpaceReclaimed
 
 	// 格式化输出
@@ -122,18 +186,32 @@ func SystemPruneTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.Cal
 	if len(pruneReport.ImagesDeleted) > 0 {
 		result.WriteString("已删除的镜像:\n")
 		for _, img := range pruneReport.ImagesDeleted {
-			if img["Untagged"] != "" {
-				result.WriteString(fmt.Sprintf("  取消标记: %s\n", img["Untagged"]))
+			if img.Untagged != "" {
+				result.WriteString(fmt.Sprintf("  取消标记: %s\n", img.Untagged))
 			}
-			if img["Deleted"] != "" {
-				result.WriteString(fmt.Sprintf("  删除: %s\n", img["Deleted"]))
+			if img.Deleted != "" {
+				result.WriteString(fmt.Sprintf("  删除: %s\n", img.Deleted))
 			}
 		}
 	} else {
 		result.WriteString("没有镜像被删除\n")
 	}
 
+	if len(pruneReport.NetworksDeleted) > 0 {
+		result.WriteString("已删除的网络:\n")
+		for _, network := range pruneReport.NetworksDeleted {
+			result.WriteString(fmt.Sprintf("  %s\n", network))
+		}
+	}
+
+	if len(pruneReport.VolumesDeleted) > 0 {
+		result.WriteString("已删除的卷:\n")
+		for _, volume := range pruneReport.VolumesDeleted {
+			result.WriteString(fmt.Sprintf("  %s\n", volume))
+		}
+	}
+
 	result.WriteString(fmt.Sprintf("释放空间: %s\n", FormatSize(pruneReport.SpaceReclaimed)))
 
-	return mcp.NewToolResultText(result.String()), nil
+	return output.Result(request, result.String(), pruneReport)
 }
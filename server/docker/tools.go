@@ -0,0 +1,337 @@
+package docker
+
+import (
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-docker/server/page"
+	"mcp-docker/server/registry"
+)
+
+// 参数格式
+const (
+	// namePattern 容器和卷名称的格式，与Docker守护进程的校验规则一致
+	namePattern = `^/?[a-zA-Z0-9][a-zA-Z0-9_.-]+$`
+	// portMappingPattern 端口映射的格式，例如 8080:80 或 5353:53/udp
+	portMappingPattern = `^[0-9]{1,5}:[0-9]{1,5}(/(tcp|udp|sctp))?$`
+)
+
+// Toolset Docker工具集，工具处理函数通过 host 参数选择要操作的Docker主机
+type Toolset struct {
+	hosts    *Hosts
+	readOnly bool
+}
+
+// ToolsetOption 配置Docker工具集
+type ToolsetOption func(*Toolset)
+
+// WithReadOnly 标记服务器运行在只读模式，system_info 会显示服务器的模式；修改类工具由调用方负责不注册
+func WithReadOnly(readOnly bool) ToolsetOption {
+	return func(t *Toolset) {
+		t.readOnly = readOnly
+	}
+}
+
+// NewToolset 创建Docker工具集，hosts 为空时只使用按环境变量连接的默认主机
+func NewToolset(hosts *Hosts, opts ...ToolsetOption) *Toolset {
+	if hosts == nil {
+		hosts, _ = NewHosts(nil)
+	}
+	t := &Toolset{hosts: hosts}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Tools 返回Docker相关的全部工具
+func (t *Toolset) Tools() []registry.Spec {
+	specs := []registry.Spec{
+		// Docker容器相关工具
+		{
+			Tool: mcp.NewTool("list_containers",
+				mcp.WithDescription("列出所有容器"),
+				mcp.WithBoolean("show_all",
+					mcp.Description("是否显示所有容器，包括已停止的容器"),
+				),
+				page.WithPagination(),
+			),
+			Handler: t.ListContainersTool,
+		},
+		{
+			Tool: mcp.NewTool("start_container",
+				mcp.WithDescription("启动已停止的容器"),
+				mcp.WithString("container_id",
+					mcp.Required(),
+					mcp.Description("要启动的容器ID"),
+				),
+				WithDryRun(),
+			),
+			Handler:     t.StartContainerTool,
+			Mutating:    true,
+			TimeoutHint: containerTimeoutHint,
+		},
+		{
+			Tool: mcp.NewTool("create_container",
+				mcp.WithDescription("创建并运行一个新容器"),
+				mcp.WithString("image",
+					mcp.Required(),
+					mcp.Description("容器使用的镜像"),
+				),
+				mcp.WithString("name",
+					mcp.Description("容器名称"),
+					mcp.Pattern(namePattern),
+				),
+				mcp.WithArray("ports",
+					mcp.Description("端口映射，格式为 [\"宿主机端口:容器端口\", ...]，容器端口可以带 /tcp 或 /udp"),
+					mcp.Items(map[string]interface{}{"type": "string", "pattern": portMappingPattern}),
+				),
+				mcp.WithArray("volumes",
+					mcp.Description("卷挂载，格式为 [\"宿主机路径:容器路径\", ...]"),
+					mcp.Items(map[string]interface{}{"type": "string"}),
+				),
+				mcp.WithArray("env",
+					mcp.Description("环境变量，格式为 [\"KEY=VALUE\", ...]"),
+					mcp.Items(map[string]interface{}{"type": "string"}),
+				),
+				mcp.WithString("command",
+					mcp.Description("容器启动命令"),
+				),
+				mcp.WithBoolean("detach",
+					mcp.Description("是否在后台运行"),
+					mcp.DefaultBool(true),
+				),
+				WithDryRun(),
+			),
+			Handler:  t.CreateContainerTool,
+			Mutating: true,
+			Heavy:    true,
+			Timeout:  5 * time.Minute,
+		},
+		{
+			Tool: mcp.NewTool("stop_container",
+				mcp.WithDescription("停止指定的容器"),
+				mcp.WithString("container_id",
+					mcp.Required(),
+					mcp.Description("要停止的容器ID"),
+				),
+				WithDryRun(),
+			),
+			Handler:     t.StopContainerTool,
+			Mutating:    true,
+			TimeoutHint: containerTimeoutHint,
+		},
+		{
+			Tool: mcp.NewTool("remove_container",
+				mcp.WithDescription("删除指定的容器"),
+				mcp.WithString("container_id",
+					mcp.Required(),
+					mcp.Description("要删除的容器ID"),
+				),
+				mcp.WithBoolean("force",
+					mcp.Description("是否强制删除，即使容器正在运行"),
+					mcp.DefaultBool(false),
+				),
+				WithDryRun(),
+			),
+			Handler:     t.RemoveContainerTool,
+			Mutating:    true,
+			Destructive: true,
+			TimeoutHint: containerTimeoutHint,
+		},
+		{
+			Tool: mcp.NewTool("restart_container",
+				mcp.WithDescription("重启指定的容器"),
+				mcp.WithString("container_id",
+					mcp.Required(),
+					mcp.Description("要重启的容器ID"),
+				),
+				mcp.WithNumber("timeout",
+					mcp.Description("停止容器前的等待时间（秒）"),
+					mcp.DefaultNumber(1.0),
+					mcp.Min(0),
+					mcp.MultipleOf(1),
+				),
+				WithDryRun(),
+			),
+			Handler:     t.RestartContainerTool,
+			Mutating:    true,
+			TimeoutHint: containerTimeoutHint,
+		},
+		{
+			Tool: mcp.NewTool("container_logs",
+				mcp.WithDescription("查看容器日志"),
+				mcp.WithString("container_id",
+					mcp.Required(),
+					mcp.Description("要查看日志的容器ID"),
+				),
+				mcp.WithNumber("tail",
+					mcp.Description("最多返回的日志行数，0 表示不限制行数，只受 max_bytes 限制"),
+					mcp.DefaultNumber(100.0),
+					mcp.Min(0),
+					mcp.MultipleOf(1),
+				),
+				mcp.WithBoolean("timestamps",
+					mcp.Description("是否显示时间戳"),
+					mcp.DefaultBool(false),
+				),
+				page.WithLogBudget(),
+			),
+			Handler: t.ContainerLogsTool,
+		},
+		{
+			Tool: mcp.NewTool("inspect_container",
+				mcp.WithDescription("查看容器详细信息"),
+				mcp.WithString("container_id",
+					mcp.Required(),
+					mcp.Description("要查看的容器ID"),
+				),
+			),
+			Handler: t.InspectContainerTool,
+		},
+		{
+			Tool: mcp.NewTool("container_status",
+				mcp.WithDescription("快速检查容器的运行状态"),
+				mcp.WithString("container_id",
+					mcp.Required(),
+					mcp.Description("要检查的容器ID"),
+				),
+			),
+			Handler: t.ContainerStatusTool,
+		},
+
+		// Docker镜像相关工具
+		{
+			Tool: mcp.NewTool("list_images",
+				mcp.WithDescription("列出所有镜像"),
+				mcp.WithBoolean("show_all",
+					mcp.Description("是否显示所有镜像，包括中间层镜像"),
+					mcp.DefaultBool(false),
+				),
+				page.WithPagination(),
+			),
+			Handler: t.ListImagesTool,
+		},
+		{
+			Tool: mcp.NewTool("remove_image",
+				mcp.WithDescription("删除指定的镜像"),
+				mcp.WithString("image_id",
+					mcp.Required(),
+					mcp.Description("要删除的镜像ID或名称"),
+				),
+				mcp.WithBoolean("force",
+					mcp.Description("是否强制删除"),
+					mcp.DefaultBool(false),
+				),
+				WithDryRun(),
+			),
+			Handler:     t.RemoveImageTool,
+			Mutating:    true,
+			Destructive: true,
+		},
+		{
+			Tool: mcp.NewTool("pull_image",
+				mcp.WithDescription("拉取指定的镜像"),
+				mcp.WithString("image_name",
+					mcp.Required(),
+					mcp.Description("要拉取的镜像名称"),
+				),
+				WithDryRun(),
+			),
+			Handler:  t.PullImageTool,
+			Mutating: true,
+			Heavy:    true,
+			Timeout:  10 * time.Minute,
+		},
+
+		// Docker系统相关工具
+		{
+			Tool: mcp.NewTool("system_info",
+				mcp.WithDescription("显示Docker系统信息，以及MCP服务器是否运行在只读模式"),
+			),
+			Handler: t.SystemInfoTool,
+		},
+		{
+			Tool: mcp.NewTool("system_prune",
+				mcp.WithDescription("清理未使用的Docker对象"),
+				mcp.WithBoolean("all",
+					mcp.Description("是否清理所有未使用的对象，包括未使用的镜像"),
+					mcp.DefaultBool(false),
+				),
+				WithDryRun(),
+			),
+			Handler:     t.SystemPruneTool,
+			Mutating:    true,
+			Destructive: true,
+			Heavy:       true,
+			Timeout:     10 * time.Minute,
+		},
+
+		// Docker卷相关工具
+		{
+			Tool: mcp.NewTool("list_volumes",
+				mcp.WithDescription("列出所有卷"),
+				page.WithPagination(),
+			),
+			Handler: t.ListVolumesTool,
+		},
+		{
+			Tool: mcp.NewTool("remove_volume",
+				mcp.WithDescription("删除指定的卷"),
+				mcp.WithString("volume_name",
+					mcp.Required(),
+					mcp.Description("要删除的卷名称"),
+					mcp.Pattern(namePattern),
+				),
+				WithDryRun(),
+			),
+			Handler:     t.RemoveVolumeTool,
+			Mutating:    true,
+			Destructive: true,
+		},
+
+		// Docker网络相关工具
+		{
+			Tool: mcp.NewTool("list_networks",
+				mcp.WithDescription("列出所有网络"),
+				page.WithPagination(),
+			),
+			Handler: t.ListNetworksTool,
+		},
+		{
+			Tool: mcp.NewTool("remove_network",
+				mcp.WithDescription("删除指定的网络"),
+				mcp.WithString("network_id",
+					mcp.Required(),
+					mcp.Description("要删除的网络ID或名称"),
+				),
+				WithDryRun(),
+			),
+			Handler:     t.RemoveNetworkTool,
+			Mutating:    true,
+			Destructive: true,
+		},
+	}
+
+	// 所有资源相关的工具都可以通过 host 参数选择Docker主机
+	for i := range specs {
+		WithHost()(&specs[i].Tool)
+	}
+
+	specs = append(specs,
+		// Docker主机相关工具
+		registry.Spec{
+			Tool: mcp.NewTool("list_docker_hosts",
+				mcp.WithDescription("列出服务器配置的全部Docker主机，以及各主机的版本和连通性"),
+				page.WithPagination(),
+			),
+			Handler: t.ListDockerHostsTool,
+		},
+	)
+
+	for i := range specs {
+		specs[i].Group = registry.GroupDocker
+	}
+	return specs
+}
@@ -0,0 +1,148 @@
+package docker
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// 以下结构体是 output_format 为 json/yaml 时各工具返回的数据结构，字段名保持稳定
+
+// ContainerSummary list_containers 返回的容器摘要
+type ContainerSummary struct {
+	ID      string        `json:"id"`
+	Names   []string      `json:"names"`
+	Image   string        `json:"image"`
+	Command string        `json:"command"`
+	Created time.Time     `json:"created"`
+	State   string        `json:"state"`
+	Status  string        `json:"status"`
+	Ports   []PortMapping `json:"ports"`
+}
+
+// PortMapping 容器端口映射，未映射到宿主机时 host_ip 和 host_port 为空
+type PortMapping struct {
+	ContainerPort string `json:"container_port"`
+	HostIP        string `json:"host_ip,omitempty"`
+	HostPort      string `json:"host_port,omitempty"`
+}
+
+// MountSummary 容器挂载点
+type MountSummary struct {
+	Type        string `json:"type"`
+	Name        string `json:"name,omitempty"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	RW          bool   `json:"rw"`
+}
+
+// ContainerStatus container_status 返回的容器状态
+type ContainerStatus struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	Status     string         `json:"status"`
+	Running    bool           `json:"running"`
+	StartedAt  string         `json:"started_at,omitempty"`
+	FinishedAt string         `json:"finished_at,omitempty"`
+	ExitCode   int            `json:"exit_code"`
+	Error      string         `json:"error,omitempty"`
+	Image      string         `json:"image"`
+	Command    []string       `json:"command"`
+	Ports      []PortMapping  `json:"ports"`
+	Mounts     []MountSummary `json:"mounts"`
+}
+
+// ContainerLogs container_logs 返回的日志
+type ContainerLogs struct {
+	ContainerID string `json:"container_id"`
+	Tail        int    `json:"tail"`
+	Logs        string `json:"logs"`
+	Lines       int    `json:"lines"`
+	// Truncated 日志超出预算时被截断的位置（head 或 tail），没有截断时省略
+	Truncated string `json:"truncated,omitempty"`
+	// NextCursor 继续查看被截断部分的游标
+	NextCursor string `json:"next_cursor,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+// ImageSummary list_images 返回的镜像摘要
+type ImageSummary struct {
+	ID       string    `json:"id"`
+	RepoTags []string  `json:"repo_tags"`
+	Created  time.Time `json:"created"`
+	Size     int64     `json:"size"`
+}
+
+// VolumeSummary list_volumes 返回的卷摘要
+type VolumeSummary struct {
+	Name       string            `json:"name"`
+	Driver     string            `json:"driver"`
+	Mountpoint string            `json:"mountpoint"`
+	CreatedAt  string            `json:"created_at,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
+
+// NetworkSummary list_networks 返回的网络摘要
+type NetworkSummary struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Driver string `json:"driver"`
+	Scope  string `json:"scope"`
+}
+
+// HostSummary list_docker_hosts 返回的Docker主机信息
+type HostSummary struct {
+	Name       string `json:"name"`
+	Endpoint   string `json:"endpoint"`
+	Default    bool   `json:"default"`
+	Reachable  bool   `json:"reachable"`
+	Version    string `json:"version,omitempty"`
+	APIVersion string `json:"api_version,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// SystemInfo system_info 返回的Docker守护进程信息
+type SystemInfo struct {
+	ServerVersion     string `json:"server_version"`
+	Containers        int    `json:"containers"`
+	ContainersRunning int    `json:"containers_running"`
+	ContainersPaused  int    `json:"containers_paused"`
+	ContainersStopped int    `json:"containers_stopped"`
+	Images            int    `json:"images"`
+	Driver            string `json:"driver"`
+	OperatingSystem   string `json:"operating_system"`
+	Architecture      string `json:"architecture"`
+	KernelVersion     string `json:"kernel_version"`
+	NCPU              int    `json:"ncpu"`
+	MemTotal          int64  `json:"mem_total"`
+	DockerRootDir     string `json:"docker_root_dir"`
+	LoggingDriver     string `json:"logging_driver"`
+	CgroupDriver      string `json:"cgroup_driver"`
+	// ReadOnly MCP服务器是否运行在只读模式，只读模式下不提供修改资源的工具
+	ReadOnly bool `json:"read_only"`
+}
+
+// 辅助函数：转换容器列表中的端口信息
+func portMappings(ports []container.Port) []PortMapping {
+	mappings := make([]PortMapping, 0, len(ports))
+	for _, port := range ports {
+		mapping := PortMapping{ContainerPort: fmt.Sprintf("%d/%s", port.PrivatePort, port.Type)}
+		if port.PublicPort > 0 {
+			mapping.HostIP = port.IP
+			mapping.HostPort = fmt.Sprintf("%d", port.PublicPort)
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings
+}
+
+// 辅助函数：去掉容器名称前的斜杠
+func trimNames(names []string) []string {
+	trimmed := make([]string, 0, len(names))
+	for _, name := range names {
+		trimmed = append(trimmed, strings.TrimPrefix(name, "/"))
+	}
+	return trimmed
+}
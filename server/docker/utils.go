@@ -3,6 +3,7 @@ package docker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -15,11 +16,6 @@ import (
 	"github.com/docker/docker/client"
 )
 
-// 创建Docker客户端的辅助函数
-func CreateDockerClient() (*client.Client, error) {
-	return client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-}
-
 // 格式化端口信息的辅助函数
 func FormatPorts(ports []types.Port) string {
 	if len(ports) == 0 {
@@ -116,6 +112,15 @@ func FormatDuration(d time.Duration) string {
 	return fmt.Sprintf("%ds", seconds)
 }
 
+// shortID 返回ID的前12位，去掉镜像ID的 sha256: 前缀，ID不足12位时原样返回
+func shortID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
 // 进度显示相关功能 ----------------------------------------
 
 // ImagePullProgress 用于解析Docker进度JSON
@@ -127,24 +132,54 @@ type ImagePullProgress struct {
 	} `json:"progressDetail"`
 	Progress string `json:"progress"`
 	ID       string `json:"id"`
+	// Error 拉取失败时守护进程在进度流中返回的错误
+	Error string `json:"error"`
+}
+
+// LayerProgress 镜像层的下载进度
+type LayerProgress struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	// Current 已下载的字节数，下载完成后等于 Total
+	Current int64 `json:"current"`
+	// Total 层的大小，守护进程开始下载前为0
+	Total int64 `json:"total"`
+
+	bar string
+}
+
+// PullProgress 镜像拉取的总体进度
+type PullProgress struct {
+	Layers       []LayerProgress `json:"layers"`
+	CurrentBytes int64           `json:"current_bytes"`
+	TotalBytes   int64           `json:"total_bytes"`
+	// Percent 已下载字节数占已知总字节数的百分比，所有层完成后为100
+	Percent float64 `json:"percent"`
+	// Completed 已完成（拉取完成或本地已存在）的层数
+	Completed int `json:"completed_layers"`
 }
 
 // ProgressReader 是一个结构，用于追踪和处理Docker操作的进度
 type ProgressReader struct {
-	Reader        io.ReadCloser
-	BytesRead     int64
-	TotalBytes    int64
-	LayerProgress map[string]*ImagePullProgress
-	mu            sync.Mutex
-	Updates       chan string
+	Reader io.ReadCloser
+	// Updates 文本形式的进度，读取结束后关闭
+	Updates chan string
+	// OnProgress 每次更新进度时调用，需要在 StartProgress 之前设置
+	OnProgress func(PullProgress)
+
+	mu     sync.Mutex
+	layers map[string]*LayerProgress
+	// order 按第一次出现的顺序记录层ID，保证输出顺序稳定
+	order []string
+	err   error
 }
 
 // NewProgressReader 创建一个新的进度读取器
 func NewProgressReader(reader io.ReadCloser) *ProgressReader {
 	return &ProgressReader{
-		Reader:        reader,
-		LayerProgress: make(map[string]*ImagePullProgress),
-		Updates:       make(chan string, 10),
+		Reader:  reader,
+		layers:  make(map[string]*LayerProgress),
+		Updates: make(chan string, 10),
 	}
 }
 
@@ -162,19 +197,22 @@ func (pr *ProgressReader) StartProgress() {
 			if err := decoder.Decode(&progress); err != nil {
 				if err == io.EOF {
 					// 正常结束
+					pr.updateProgress()
 					pr.Updates <- "\n操作完成！"
 					break
 				}
+				pr.setErr(fmt.Errorf("读取进度时出错: %w", err))
 				pr.Updates <- fmt.Sprintf("\n读取进度时出错: %v", err)
 				break
 			}
+			if progress.Error != "" {
+				pr.setErr(errors.New(progress.Error))
+				pr.Updates <- fmt.Sprintf("\n拉取失败: %s", progress.Error)
+				break
+			}
 
 			// 更新进度信息
-			pr.mu.Lock()
-			if progress.ID != "" {
-				pr.LayerProgress[progress.ID] = &progress
-			}
-			pr.mu.Unlock()
+			pr.record(progress)
 
 			// 定期更新进度，避免过于频繁的更新
 			if time.Since(lastUpdateTime) > updateInterval {
@@ -185,39 +223,90 @@ func (pr *ProgressReader) StartProgress() {
 	}()
 }
 
-// updateProgress 更新并发送进度信息
-func (pr *ProgressReader) updateProgress() {
+// Err 返回读取进度时遇到的错误，包括守护进程返回的拉取错误，应在 Updates 关闭后调用
+func (pr *ProgressReader) Err() error {
 	pr.mu.Lock()
 	defer pr.mu.Unlock()
 
-	var (
-		message       strings.Builder
-		totalCurrent  int64
-		totalExpected int64
-	)
+	return pr.err
+}
+
+// Snapshot 返回当前的总体进度
+func (pr *ProgressReader) Snapshot() PullProgress {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	var snapshot PullProgress
+	for _, id := range pr.order {
+		layer := pr.layers[id]
+		snapshot.Layers = append(snapshot.Layers, *layer)
+		snapshot.CurrentBytes += layer.Current
+		snapshot.TotalBytes += layer.Total
+		if layerDone(layer.Status) {
+			snapshot.Completed++
+		}
+	}
+	switch {
+	case len(snapshot.Layers) > 0 && snapshot.Completed == len(snapshot.Layers):
+		snapshot.Percent = 100
+	case snapshot.TotalBytes > 0:
+		snapshot.Percent = float64(snapshot.CurrentBytes) / float64(snapshot.TotalBytes) * 100
+	}
+	return snapshot
+}
+
+// record 根据一条进度消息更新对应层的状态
+//
+// 没有ID的消息（例如摘要信息）不属于任何层，"Pulling from" 消息的ID是镜像标签，也不是层。
+func (pr *ProgressReader) record(progress ImagePullProgress) {
+	if progress.ID == "" || strings.HasPrefix(progress.Status, "Pulling from") {
+		return
+	}
+
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
 
-	// 统计总进度
-	for id, layer := range pr.LayerProgress {
-		if layer.ProgressDetail.Total > 0 {
-			totalCurrent += layer.ProgressDetail.Current
-			totalExpected += layer.ProgressDetail.Total
+	layer, ok := pr.layers[progress.ID]
+	if !ok {
+		layer = &LayerProgress{ID: progress.ID}
+		pr.layers[progress.ID] = layer
+		pr.order = append(pr.order, progress.ID)
+	}
+	layer.Status = progress.Status
+	layer.bar = progress.Progress
+
+	// Extracting 的进度是解压的字节数，只有 Downloading 的进度对应下载的字节数
+	switch progress.Status {
+	case "Downloading":
+		layer.Current = progress.ProgressDetail.Current
+		if progress.ProgressDetail.Total > 0 {
+			layer.Total = progress.ProgressDetail.Total
 		}
+	case "Download complete", "Verifying Checksum", "Extracting", "Pull complete":
+		layer.Current = layer.Total
+	}
+}
 
-		// 添加每个层的进度信息
-		if layer.Status != "" {
-			// 截取ID以避免过长
-			shortID := id
-			if len(id) > 12 {
-				shortID = id[:12]
-			}
-			fmt.Fprintf(&message, "[%s] %s %s\n", shortID, layer.Status, layer.Progress)
+// updateProgress 更新并发送进度信息
+func (pr *ProgressReader) updateProgress() {
+	snapshot := pr.Snapshot()
+
+	var message strings.Builder
+	for _, layer := range snapshot.Layers {
+		if layer.Status == "" {
+			continue
 		}
+		// 截取ID以避免过长
+		fmt.Fprintf(&message, "[%s] %s %s\n", shortID(layer.ID), layer.Status, layer.bar)
 	}
 
 	// 计算总体进度百分比
-	if totalExpected > 0 {
-		percentage := float64(totalCurrent) / float64(totalExpected) * 100
-		fmt.Fprintf(&message, "总体进度: %.2f%%\n", percentage)
+	if snapshot.TotalBytes > 0 || snapshot.Percent > 0 {
+		fmt.Fprintf(&message, "总体进度: %.2f%%\n", snapshot.Percent)
+	}
+
+	if pr.OnProgress != nil {
+		pr.OnProgress(snapshot)
 	}
 
 	// 发送进度更新
@@ -226,6 +315,21 @@ func (pr *ProgressReader) updateProgress() {
 	}
 }
 
+// setErr 记录第一个错误
+func (pr *ProgressReader) setErr(err error) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	if pr.err == nil {
+		pr.err = err
+	}
+}
+
+// layerDone 判断层是否已经完成
+func layerDone(status string) bool {
+	return status == "Pull complete" || status == "Already exists"
+}
+
 // PullImageWithProgress 拉取镜像并显示进度
 func PullImageWithProgress(ctx context.Context, cli *client.Client, imageName string) (string, error) {
 	// 拉取镜像
@@ -248,7 +352,7 @@ func PullImageWithProgress(ctx context.Context, cli *client.Client, imageName st
 		progressOutput.WriteString(update)
 	}
 
-	return progressOutput.String(), nil
+	return progressOutput.String(), progressReader.Err()
 }
 
 // CreateContainerWithProgress 创建容器并显示进度步骤
@@ -267,7 +371,7 @@ func CreateContainerWithProgress(ctx context.Context, cli *client.Client, config
 	progressOutput.WriteString(fmt.Sprintf("[%d/%d] 创建容器...\n", step, totalSteps))
 	resp, err := cli.ContainerCreate(ctx, config, hostConfig, nil, nil, containerName)
 	if err != nil {
-		return "", progressOutput.String(), fmt.Errorf("创建容器失败: %v", err)
+		return "", progressOutput.String(), fmt.Errorf("创建容器失败: %w", err)
 	}
 	step++
 
@@ -282,7 +386,7 @@ func CreateContainerWithProgress(ctx context.Context, cli *client.Client, config
 
 		err = cli.ContainerStart(ctx, resp.ID, container.StartOptions{})
 		if err != nil {
-			return resp.ID, progressOutput.String(), fmt.Errorf("启动容器失败: %v", err)
+			return resp.ID, progressOutput.String(), fmt.Errorf("启动容器失败: %w", err)
 		}
 
 		// 等待一下，给容器启动一些时间
@@ -3,36 +3,62 @@ package docker
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"sort"
 	"strings"
 
 	"github.com/docker/docker/api/types/volume"
 	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-docker/server/args"
+	"mcp-docker/server/output"
+	"mcp-docker/server/page"
+	"mcp-docker/server/toolerror"
 )
 
 // 列出卷的工具函数
-func ListVolumesTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	fmt.Println("ai 正在调用mcp server的tool: list_volumes")
+func (t *Toolset) ListVolumesTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	paging, err := page.Of(request)
+	if err != nil {
+		return args.ErrorResult(request, err)
+	}
+
+	slog.Info("ai 正在调用mcp server的tool", "tool", "list_volumes")
 
-	// 创建Docker客户端
-	cli, err := CreateDockerClient()
+	// 获取Docker客户端
+	cli, err := t.hosts.Get(ctx, hostOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Docker客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Docker客户端失败", err)
 	}
-	defer cli.Close()
 
 	// 获取卷列表
 	volumes, err := cli.VolumeList(ctx, volume.ListOptions{})
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("获取卷列表失败: %v", err)), err
+		return toolerror.Result(request, "获取卷列表失败", err)
 	}
 
 	// 格式化输出
 	var result strings.Builder
-	result.WriteString("DRIVER\tVOLUME NAME\tMOUNTPOINT\tLABELS\n")
+	// 按名称排序，保证分页的顺序稳定
+	list := make([]*volume.Volume, 0, len(volumes.Volumes))
 	for _, vol := range volumes.Volumes {
-		if vol == nil {
-			continue
+		if vol != nil {
+			list = append(list, vol)
 		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	list, info := page.Slice(list, paging)
+	summaries := make([]VolumeSummary, 0, len(list))
+	result.WriteString("DRIVER\tVOLUME NAME\tMOUNTPOINT\tLABELS\n")
+	for _, vol := range list {
+
+		summaries = append(summaries, VolumeSummary{
+			Name:       vol.Name,
+			Driver:     vol.Driver,
+			Mountpoint: vol.Mountpoint,
+			CreatedAt:  vol.CreatedAt,
+			Labels:     vol.Labels,
+		})
 
 		// 格式化标签
 		var labels []string
@@ -51,27 +77,44 @@ func ListVolumesTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.Cal
 			labelsStr))
 	}
 
-	return mcp.NewToolResultText(result.String()), nil
+	return page.Result(request, result.String(), summaries, info)
+}
+
+// removeVolumeArgs remove_volume 的参数
+type removeVolumeArgs struct {
+	VolumeName string `json:"volume_name"`
 }
 
 // 删除卷的工具函数
-func RemoveVolumeTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	volumeName := request.Params.Arguments["volume_name"].(string)
+func (t *Toolset) RemoveVolumeTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params removeVolumeArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	volumeName := params.VolumeName
 
-	fmt.Println("ai 正在调用mcp server的tool: remove_volume, volume_name=", volumeName)
+	slog.Info("ai 正在调用mcp server的tool", "tool", "remove_volume", "volume_name", volumeName)
 
-	// 创建Docker客户端
-	cli, err := CreateDockerClient()
+	// 获取Docker客户端
+	cli, err := t.hosts.Get(ctx, hostOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Docker客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Docker客户端失败", err)
+	}
+
+	// 预演模式只检查将要发生的变化，不做任何修改
+	if IsDryRun(request) {
+		preview, err := previewRemoveVolume(ctx, cli, volumeName)
+		if err != nil {
+			return toolerror.Result(request, "预演失败", err)
+		}
+		return output.DryRun(request, volumeName, preview, nil)
 	}
-	defer cli.Close()
 
 	// 删除卷
 	err = cli.VolumeRemove(ctx, volumeName, false)
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("删除卷失败: %v", err)), err
+		return toolerror.Result(request, "删除卷失败", err)
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("卷 %s 已成功删除", volumeName)), nil
+	return output.Action(request, volumeName, fmt.Sprintf("卷 %s 已成功删除", volumeName))
 }
@@ -0,0 +1,144 @@
+// Package drain 在服务器关闭时等待正在执行的工具调用结束
+package drain
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp-docker/server/registry"
+	"mcp-docker/server/toolerror"
+)
+
+// DefaultTimeout 默认等待工具调用结束的时间
+const DefaultTimeout = 30 * time.Second
+
+// ErrShuttingDown 关闭期限内未完成的工具调用被取消的原因
+var ErrShuttingDown = errors.New("服务器正在关闭")
+
+// cancelGrace 取消超时的工具调用后，等待它们返回并写入审计日志的时间
+const cancelGrace = 5 * time.Second
+
+// Drainer 记录正在执行的工具调用，关闭时拒绝新的调用并等待已有的调用结束
+type Drainer struct {
+	mu       sync.Mutex
+	stopping bool
+	nextID   uint64
+	calls    map[uint64]*call
+	// idle 在停止接受调用且没有正在执行的调用时关闭
+	idle chan struct{}
+}
+
+// call 一次正在执行的工具调用
+type call struct {
+	tool    string
+	session string
+	start   time.Time
+	cancel  context.CancelCauseFunc
+}
+
+// New 创建 Drainer
+func New() *Drainer {
+	return &Drainer{
+		calls: make(map[uint64]*call),
+		idle:  make(chan struct{}),
+	}
+}
+
+// Middleware 以工具中间件的形式记录正在执行的调用，开始关闭后新的调用直接返回错误
+//
+// 应作为最外层的中间件，这样等待的范围包括审计日志的写入。
+func (d *Drainer) Middleware(spec *registry.Spec, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	tool := spec.Tool.Name
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+
+		session := ""
+		if clientSession := server.ClientSessionFromContext(ctx); clientSession != nil {
+			session = clientSession.SessionID()
+		}
+		id, ok := d.begin(tool, session, cancel)
+		if !ok {
+			return toolerror.Coded(request, toolerror.CodeUnavailable, "服务器正在关闭，不再接受新的工具调用，请稍后重试")
+		}
+		defer d.end(id)
+
+		return next(ctx, request)
+	}
+}
+
+// InFlight 返回正在执行的工具调用数量
+func (d *Drainer) InFlight() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.calls)
+}
+
+// Drain 停止接受新的工具调用，并等待正在执行的调用结束
+//
+// ctx 结束时仍未完成的调用会被取消并记录到日志，取消后最多再等待几秒，
+// 让它们返回错误并写入审计日志。所有调用都在期限内结束时返回 true。
+func (d *Drainer) Drain(ctx context.Context) bool {
+	d.mu.Lock()
+	if !d.stopping {
+		d.stopping = true
+		if len(d.calls) == 0 {
+			close(d.idle)
+		}
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-d.idle:
+		return true
+	case <-ctx.Done():
+	}
+
+	d.mu.Lock()
+	for _, c := range d.calls {
+		slog.Warn("工具调用未在关闭期限内完成，已取消",
+			"tool", c.tool,
+			"session", c.session,
+			"elapsed", time.Since(c.start).Round(time.Millisecond))
+		c.cancel(ErrShuttingDown)
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-d.idle:
+	case <-time.After(cancelGrace):
+		slog.Error("部分工具调用在取消后仍未返回", "count", d.InFlight())
+	}
+	return false
+}
+
+// begin 登记一次调用，已经开始关闭时返回 false
+func (d *Drainer) begin(tool, session string, cancel context.CancelCauseFunc) (uint64, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stopping {
+		return 0, false
+	}
+	d.nextID++
+	d.calls[d.nextID] = &call{tool: tool, session: session, start: time.Now(), cancel: cancel}
+	return d.nextID, true
+}
+
+// end 移除已结束的调用，关闭过程中最后一个调用结束时通知 Drain
+func (d *Drainer) end(id uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.calls, id)
+	if d.stopping && len(d.calls) == 0 {
+		close(d.idle)
+	}
+}
@@ -0,0 +1,124 @@
+package drain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-docker/server/registry"
+	"mcp-docker/server/toolerror"
+)
+
+// blockingCall 在后台发起一次阻塞的工具调用，返回调用结果的通道
+//
+// 调用开始后关闭 started，之后由 block 决定何时返回。
+func blockingCall(d *Drainer, block func(ctx context.Context) error) (<-chan struct{}, <-chan *mcp.CallToolResult) {
+	started := make(chan struct{})
+	results := make(chan *mcp.CallToolResult, 1)
+	handler := d.Middleware(&registry.Spec{Tool: mcp.NewTool("pull_image")}, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		close(started)
+		if err := block(ctx); err != nil {
+			return toolerror.Coded(request, toolerror.CodeCancelled, err.Error())
+		}
+		return mcp.NewToolResultText("ok"), nil
+	})
+	go func() {
+		result, _ := handler(context.Background(), mcp.CallToolRequest{})
+		results <- result
+	}()
+	return started, results
+}
+
+// quickCall 同步调用一次立即返回的工具
+func quickCall(d *Drainer) *mcp.CallToolResult {
+	handler := d.Middleware(&registry.Spec{Tool: mcp.NewTool("list_images")}, func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	result, _ := handler(context.Background(), mcp.CallToolRequest{})
+	return result
+}
+
+func TestDrainIdle(t *testing.T) {
+	d := New()
+	if result := quickCall(d); result.IsError {
+		t.Fatal("关闭前的调用不应被拒绝")
+	}
+	if !d.Drain(context.Background()) {
+		t.Error("没有正在执行的调用时应立即返回 true")
+	}
+	// 重复调用不会重复关闭通道
+	if !d.Drain(context.Background()) {
+		t.Error("再次调用 Drain 应同样返回 true")
+	}
+}
+
+func TestDrainWaitsForInFlight(t *testing.T) {
+	d := New()
+	release := make(chan struct{})
+	started, results := blockingCall(d, func(context.Context) error {
+		<-release
+		return nil
+	})
+	<-started
+
+	drained := make(chan bool, 1)
+	go func() { drained <- d.Drain(context.Background()) }()
+
+	// 等待 Drain 开始拒绝新的调用
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		result := quickCall(d)
+		if toolerror.CodeOfResult(result) == toolerror.CodeUnavailable {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("开始关闭后新的调用应返回 unavailable")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-drained:
+		t.Fatal("正在执行的调用结束前 Drain 不应返回")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if d.InFlight() != 1 {
+		t.Errorf("应有 1 个正在执行的调用，实际为 %d", d.InFlight())
+	}
+
+	close(release)
+	if result := <-results; result.IsError {
+		t.Error("期限内完成的调用应正常返回")
+	}
+	if !<-drained {
+		t.Error("所有调用在期限内结束时应返回 true")
+	}
+}
+
+func TestDrainCancelsAfterDeadline(t *testing.T) {
+	d := New()
+	causes := make(chan error, 1)
+	started, results := blockingCall(d, func(ctx context.Context) error {
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return ctx.Err()
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if d.Drain(ctx) {
+		t.Error("有调用超过期限被取消时应返回 false")
+	}
+	if cause := <-causes; !errors.Is(cause, ErrShuttingDown) {
+		t.Errorf("取消原因应为 ErrShuttingDown，实际为 %v", cause)
+	}
+	if result := <-results; !result.IsError {
+		t.Error("被取消的调用应返回错误结果")
+	}
+	if d.InFlight() != 0 {
+		t.Errorf("取消的调用返回后不应再记录，实际为 %d", d.InFlight())
+	}
+}
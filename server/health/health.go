@@ -0,0 +1,156 @@
+// Package health 提供存活检查和就绪检查的HTTP端点
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"mcp-docker/server/docker"
+	"mcp-docker/server/k8s"
+)
+
+// 健康检查端点的路径
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+)
+
+// DefaultTimeout 单次就绪检查的超时时间，所有后端并发检查
+const DefaultTimeout = 5 * time.Second
+
+// 检查结果的状态
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// 后端类型
+const (
+	BackendDocker     = "docker"
+	BackendKubernetes = "kubernetes"
+)
+
+// Report 就绪检查的结果，所有后端都可用时 Status 为 ok
+type Report struct {
+	Status   string          `json:"status"`
+	Backends []BackendStatus `json:"backends"`
+}
+
+// BackendStatus 一个Docker主机或Kubernetes集群的检查结果
+type BackendStatus struct {
+	Type string `json:"type"`
+	// Name Docker主机名称或context名称，未限制context时为空，表示当前context
+	Name      string `json:"name"`
+	OK        bool   `json:"ok"`
+	Version   string `json:"version,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Checker 检查服务器依赖的Docker守护进程和Kubernetes API服务器
+type Checker struct {
+	dockerHosts *docker.Hosts
+	k8sClient   *k8s.Client
+	timeout     time.Duration
+}
+
+// NewChecker 创建就绪检查，dockerHosts 或 k8sClient 为空表示未启用对应的工具分组，不做检查
+func NewChecker(dockerHosts *docker.Hosts, k8sClient *k8s.Client) *Checker {
+	return &Checker{
+		dockerHosts: dockerHosts,
+		k8sClient:   k8sClient,
+		timeout:     DefaultTimeout,
+	}
+}
+
+// Check 并发检查所有后端：对每个Docker主机执行 Ping，对每个Kubernetes集群获取服务器版本
+func (c *Checker) Check(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var probes []func() BackendStatus
+	if c.dockerHosts != nil {
+		for _, name := range c.dockerHosts.Names() {
+			probes = append(probes, func() BackendStatus {
+				return probe(BackendDocker, name, func() (string, error) {
+					return "", c.dockerHosts.Ping(ctx, name)
+				})
+			})
+		}
+	}
+	if c.k8sClient != nil {
+		for _, kubeContext := range c.k8sClient.ProbeContexts() {
+			probes = append(probes, func() BackendStatus {
+				return probe(BackendKubernetes, kubeContext, func() (string, error) {
+					return c.k8sClient.ServerVersion(ctx, kubeContext)
+				})
+			})
+		}
+	}
+
+	report := Report{Status: StatusOK, Backends: make([]BackendStatus, len(probes))}
+	var wg sync.WaitGroup
+	for i, run := range probes {
+		wg.Add(1)
+		go func(i int, run func() BackendStatus) {
+			defer wg.Done()
+			report.Backends[i] = run()
+		}(i, run)
+	}
+	wg.Wait()
+
+	for _, backend := range report.Backends {
+		if !backend.OK {
+			report.Status = StatusUnavailable
+			break
+		}
+	}
+	return report
+}
+
+// probe 执行一次检查并记录耗时
+func probe(backendType, name string, check func() (string, error)) BackendStatus {
+	start := time.Now()
+	version, err := check()
+	status := BackendStatus{
+		Type:      backendType,
+		Name:      name,
+		OK:        err == nil,
+		Version:   version,
+		LatencyMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// LivenessHandler 存活检查，进程能处理HTTP请求即返回 200，不访问任何后端
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": StatusOK})
+	})
+}
+
+// ReadinessHandler 就绪检查，所有后端都可用时返回 200，否则返回 503，响应体为各后端的检查结果
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Check(r.Context())
+		code := http.StatusOK
+		if report.Status != StatusOK {
+			code = http.StatusServiceUnavailable
+		}
+		writeJSON(w, code, report)
+	})
+}
+
+// writeJSON 以JSON格式写入响应，健康检查的结果不应被缓存
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,44 @@
+// Package tooltest 提供测试工具处理函数时共用的辅助函数
+package tooltest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Request 构造工具调用请求
+func Request(tool string, arguments map[string]interface{}) mcp.CallToolRequest {
+	var request mcp.CallToolRequest
+	request.Params.Name = tool
+	request.Params.Arguments = arguments
+	return request
+}
+
+// Text 返回结果中的文本内容
+func Text(t testing.TB, result *mcp.CallToolResult) string {
+	t.Helper()
+
+	if result == nil || len(result.Content) == 0 {
+		t.Fatal("结果为空")
+	}
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		t.Fatalf("结果不是文本: %T", result.Content[0])
+	}
+	return text.Text
+}
+
+// JSON 把 json 格式的结果解析到 v，结果为错误结果时测试失败
+func JSON(t testing.TB, result *mcp.CallToolResult, v interface{}) {
+	t.Helper()
+
+	text := Text(t, result)
+	if result.IsError {
+		t.Fatalf("返回了错误结果: %s", text)
+	}
+	if err := json.Unmarshal([]byte(text), v); err != nil {
+		t.Fatalf("解析结果失败: %v\n%s", err, text)
+	}
+}
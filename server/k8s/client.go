@@ -1,55 +1,428 @@
 package k8s
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
+	"time"
 
+	"github.com/mark3labs/mcp-go/mcp"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+
+	"mcp-docker/server/toolerror"
+	"mcp-docker/server/tracing"
 )
 
-// CreateK8sClient 创建Kubernetes客户端
-func CreateK8sClient() (*kubernetes.Clientset, error) {
-	// 尝试获取集群内部配置
-	config, err := rest.InClusterConfig()
-	if err == nil {
-		// 成功获取集群内配置
-		clientset, err := kubernetes.NewForConfig(config)
+// Toolset Kubernetes工具集，所有工具处理函数共享同一个Kubernetes客户端
+type Toolset struct {
+	client *Client
+}
+
+// NewToolset 创建Kubernetes工具集，cli 为空时使用集群内配置或kubeconfig创建的共享客户端
+func NewToolset(cli *Client) *Toolset {
+	if cli == nil {
+		cli = NewClient()
+	}
+	return &Toolset{client: cli}
+}
+
+// InClusterContext 使用集群内配置时的context名称
+const InClusterContext = "in-cluster"
+
+// ContextArg 选择kubeconfig context的参数名
+const ContextArg = "context"
+
+// WithContext 为工具声明 context 参数
+func WithContext() mcp.ToolOption {
+	return mcp.WithString(ContextArg,
+		mcp.Description("kubeconfig中的context名称，用于选择集群，为空时使用当前context；可通过 list_kube_contexts 查看"),
+	)
+}
+
+// contextOf 返回调用方选择的context
+func contextOf(request mcp.CallToolRequest) string {
+	kubeContext, _ := request.Params.Arguments[ContextArg].(string)
+	return kubeContext
+}
+
+// Client 长期复用的Kubernetes客户端池，每个kubeconfig context对应一个客户端
+//
+// 客户端在第一次使用某个context时创建，之后一直复用；使用kubeconfig时每次获取客户端都会
+// 检查文件的修改时间，文件变化后重新加载，这样切换集群或更新证书后不需要重启服务器。
+type Client struct {
+	mu      sync.Mutex
+	entries map[string]*clientEntry
+	// owned 为 false 表示客户端由调用方提供，例如 fake 客户端
+	owned bool
+	// kubeconfig 为空时使用 KUBECONFIG 环境变量或 ~/.kube/config
+	kubeconfig string
+	// contexts 允许使用的context，为空表示不限制
+	contexts map[string]bool
+	// namespaces 允许操作的命名空间，为空表示不限制
+	namespaces map[string]bool
+}
+
+// ClientOption 配置Kubernetes客户端池
+type ClientOption func(*Client)
+
+// WithKubeconfig 指定kubeconfig文件路径
+func WithKubeconfig(path string) ClientOption {
+	return func(c *Client) {
+		c.kubeconfig = path
+	}
+}
+
+// WithAllowedContexts 限制可以使用的context，未指定 context 参数时使用的context同样受限制
+func WithAllowedContexts(names ...string) ClientOption {
+	return func(c *Client) {
+		c.contexts = toSet(names)
+	}
+}
+
+// WithAllowedNamespaces 限制工具可以操作的命名空间
+func WithAllowedNamespaces(namespaces ...string) ClientOption {
+	return func(c *Client) {
+		c.namespaces = toSet(namespaces)
+	}
+}
+
+// clientEntry 某个context的客户端
+type clientEntry struct {
+	clientset kubernetes.Interface
+	// context 实际使用的context名称
+	context string
+	// kubeconfig 为空表示使用集群内配置，不需要刷新
+	kubeconfig string
+	modTime    time.Time
+	// transport 客户端底层的HTTP传输，替换或关闭客户端时关闭它的空闲连接
+	transport http.RoundTripper
+}
+
+// closeIdle 关闭客户端的空闲连接
+//
+// client-go 的客户端没有关闭方法，tracing 包装后的传输也不会转发 CloseIdleConnections，
+// 因此直接关闭创建客户端时记录的底层传输。TLS配置相同的客户端共享同一个传输，
+// 关闭共享传输的空闲连接只会让之后的请求重新建立连接。
+func (e *clientEntry) closeIdle() {
+	if e.transport != nil {
+		utilnet.CloseIdleConnectionsFor(e.transport)
+	}
+}
+
+// NewClient 创建共享的Kubernetes客户端池，并立即尝试加载一次当前context
+//
+// 加载失败时只打印警告，之后每次获取客户端都会重试，这样没有集群的环境也能使用Docker工具。
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{entries: make(map[string]*clientEntry), owned: true}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if _, err := c.Get(""); err != nil {
+		slog.Warn("Kubernetes客户端暂不可用", "error", err)
+	}
+	return c
+}
+
+// WrapClient 使用调用方提供的Kubernetes客户端，例如 k8s.io/client-go/kubernetes/fake
+//
+// 包装后的客户端只对应默认context，不支持切换context，也不支持限制context。
+func WrapClient(clientset kubernetes.Interface, opts ...ClientOption) *Client {
+	c := &Client{entries: map[string]*clientEntry{"": {clientset: clientset}}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Get 返回指定context的Kubernetes客户端，kubeContext 为空时使用集群内配置或kubeconfig的当前context
+func (c *Client) Get(kubeContext string) (kubernetes.Interface, error) {
+	if kubeContext != "" && !c.contextAllowed(kubeContext) {
+		return nil, toolerror.New(toolerror.CodePermissionDenied, "context %s 不在允许使用的范围内", kubeContext)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := c.entries[kubeContext]
+	if !c.owned {
+		if entry == nil {
+			return nil, toolerror.New(toolerror.CodeInvalidArgument, "当前服务器不支持切换context: %s", kubeContext)
+		}
+		return entry.clientset, nil
+	}
+
+	if entry != nil {
+		if entry.kubeconfig == "" {
+			return c.checked(entry)
+		}
+		info, err := os.Stat(entry.kubeconfig)
+		if err != nil || info.ModTime().Equal(entry.modTime) {
+			// 文件暂时不可读时继续使用已加载的客户端
+			return c.checked(entry)
+		}
+		slog.Info("kubeconfig已变化，重新加载Kubernetes客户端", "kubeconfig", entry.kubeconfig)
+	}
+
+	loaded, err := c.load(kubeContext)
+	if err != nil {
+		if entry != nil {
+			slog.Warn("重新加载Kubernetes客户端失败，继续使用原有配置", "error", err)
+			return c.checked(entry)
+		}
+		return nil, toolerror.Wrap(toolerror.CodeBackendUnavailable, err)
+	}
+	// 替换前关闭旧客户端的空闲连接，否则每次kubeconfig变化都会遗留一批连接
+	if entry != nil {
+		entry.closeIdle()
+	}
+	c.entries[kubeContext] = loaded
+	return c.checked(loaded)
+}
+
+// checked 检查客户端实际使用的context是否允许使用
+//
+// 未指定 context 参数时使用的是kubeconfig的当前context，只有加载后才知道它的名称。
+func (c *Client) checked(entry *clientEntry) (kubernetes.Interface, error) {
+	if !c.contextAllowed(entry.context) {
+		return nil, toolerror.New(toolerror.CodePermissionDenied, "当前context %s 不在允许使用的范围内，请通过 context 参数指定", entry.context)
+	}
+	return entry.clientset, nil
+}
+
+// contextAllowed 判断context是否允许使用
+func (c *Client) contextAllowed(kubeContext string) bool {
+	return len(c.contexts) == 0 || !c.owned || c.contexts[kubeContext]
+}
+
+// NamespaceAllowed 判断命名空间是否允许操作
+func (c *Client) NamespaceAllowed(namespace string) bool {
+	return len(c.namespaces) == 0 || c.namespaces[namespace]
+}
+
+// Contexts 列出可用的context，Current 标记未指定 context 参数时使用的那一个
+func (c *Client) Contexts() ([]KubeContext, error) {
+	if !c.owned {
+		return nil, fmt.Errorf("当前服务器使用外部提供的Kubernetes客户端，没有可列出的context")
+	}
+
+	var contexts []KubeContext
+	inCluster, err := rest.InClusterConfig()
+	if err == nil && c.contextAllowed(InClusterContext) {
+		contexts = append(contexts, KubeContext{
+			Name:    InClusterContext,
+			Server:  inCluster.Host,
+			Current: true,
+		})
+	}
+
+	kubeconfig, err := c.kubeconfigPath()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(kubeconfig); err == nil {
+		raw, err := clientcmd.LoadFromFile(kubeconfig)
 		if err != nil {
-			return nil, fmt.Errorf("从集群内配置创建客户端失败: %v", err)
+			return nil, fmt.Errorf("读取kubeconfig失败: %v", err)
+		}
+		names := make([]string, 0, len(raw.Contexts))
+		for name := range raw.Contexts {
+			if c.contextAllowed(name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			kubeContext := raw.Contexts[name]
+			info := KubeContext{
+				Name:      name,
+				Cluster:   kubeContext.Cluster,
+				User:      kubeContext.AuthInfo,
+				Namespace: kubeContext.Namespace,
+				Current:   inCluster == nil && name == raw.CurrentContext,
+			}
+			if cluster, ok := raw.Clusters[kubeContext.Cluster]; ok {
+				info.Server = cluster.Server
+			}
+			contexts = append(contexts, info)
 		}
-		return clientset, nil
 	}
 
-	// 如果不在集群内，尝试使用kubeconfig
-	kubeconfig := os.Getenv("KUBECONFIG")
-	if kubeconfig == "" {
-		// 使用默认位置
-		home, err := os.UserHomeDir()
+	if len(contexts) == 0 {
+		if len(c.contexts) > 0 {
+			return nil, fmt.Errorf("kubeconfig文件 %s 中没有允许使用的context", kubeconfig)
+		}
+		return nil, fmt.Errorf("不在集群内运行，且kubeconfig文件 %s 不存在", kubeconfig)
+	}
+	return contexts, nil
+}
+
+// Close 关闭所有客户端的空闲连接并丢弃已加载的客户端，调用方提供的客户端不做处理
+func (c *Client) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.owned {
+		return
+	}
+	for name, entry := range c.entries {
+		entry.closeIdle()
+		delete(c.entries, name)
+	}
+}
+
+// ProbeContexts 就绪检查需要检查的context：限制了context时检查每个允许使用的context，
+// 否则只检查未指定 context 参数时使用的那一个（用空字符串表示）
+func (c *Client) ProbeContexts() []string {
+	if len(c.contexts) == 0 || !c.owned {
+		return []string{""}
+	}
+	names := make([]string, 0, len(c.contexts))
+	for name := range c.contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ServerVersion 访问指定context的API服务器并返回其版本，用于检查集群是否可用
+//
+// client-go 获取版本的接口不接受 context，超时或取消时直接返回，不等待请求结束。
+func (c *Client) ServerVersion(ctx context.Context, kubeContext string) (string, error) {
+	clientset, err := c.Get(kubeContext)
+	if err != nil {
+		return "", err
+	}
+
+	type versionResult struct {
+		version string
+		err     error
+	}
+	done := make(chan versionResult, 1)
+	go func() {
+		info, err := clientset.Discovery().ServerVersion()
 		if err != nil {
-			return nil, fmt.Errorf("获取用户home目录失败: %v", err)
+			done <- versionResult{err: fmt.Errorf("访问API服务器失败: %w", err)}
+			return
+		}
+		done <- versionResult{version: info.GitVersion}
+	}()
+
+	select {
+	case result := <-done:
+		return result.version, result.err
+	case <-ctx.Done():
+		return "", fmt.Errorf("访问API服务器失败: %w", ctx.Err())
+	}
+}
+
+// load 读取指定context的配置并创建客户端
+func (c *Client) load(kubeContext string) (*clientEntry, error) {
+	// 未指定context时优先使用集群内部配置
+	if kubeContext == "" || kubeContext == InClusterContext {
+		config, err := rest.InClusterConfig()
+		if err == nil {
+			clientset, transport, err := newClientset(config)
+			if err != nil {
+				return nil, fmt.Errorf("从集群内配置创建客户端失败: %v", err)
+			}
+			return &clientEntry{clientset: clientset, context: InClusterContext, transport: transport}, nil
+		}
+		if kubeContext == InClusterContext {
+			return nil, fmt.Errorf("服务器不在集群内运行: %v", err)
 		}
-		kubeconfig = filepath.Join(home, ".kube", "config")
+	}
+
+	// 如果不在集群内，尝试使用kubeconfig
+	kubeconfig, err := c.kubeconfigPath()
+	if err != nil {
+		return nil, err
 	}
 
 	// 检查kubeconfig文件是否存在
-	if _, err := os.Stat(kubeconfig); os.IsNotExist(err) {
+	info, err := os.Stat(kubeconfig)
+	if os.IsNotExist(err) {
 		return nil, fmt.Errorf("kubeconfig文件 %s 不存在", kubeconfig)
 	}
+	if err != nil {
+		return nil, fmt.Errorf("读取kubeconfig文件 %s 失败: %v", kubeconfig, err)
+	}
 
-	// 使用kubeconfig创建配置
-	config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	// 使用kubeconfig中指定的context创建配置
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig},
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext},
+	)
+	config, err := clientConfig.ClientConfig()
 	if err != nil {
 		return nil, fmt.Errorf("从kubeconfig构建配置失败: %v", err)
 	}
+	if kubeContext == "" {
+		raw, err := clientConfig.RawConfig()
+		if err != nil {
+			return nil, fmt.Errorf("读取kubeconfig失败: %v", err)
+		}
+		kubeContext = raw.CurrentContext
+	}
 
-	// 创建客户端
-	clientset, err := kubernetes.NewForConfig(config)
+	clientset, transport, err := newClientset(config)
 	if err != nil {
 		return nil, fmt.Errorf("创建客户端失败: %v", err)
 	}
 
-	return clientset, nil
+	return &clientEntry{
+		clientset:  clientset,
+		context:    kubeContext,
+		kubeconfig: kubeconfig,
+		modTime:    info.ModTime(),
+		transport:  transport,
+	}, nil
+}
+
+// newClientset 创建客户端并返回它底层的HTTP传输，每个API请求都会记录为工具调用span的子span
+func newClientset(config *rest.Config) (kubernetes.Interface, http.RoundTripper, error) {
+	var transport http.RoundTripper
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		transport = rt
+		return tracing.Transport(rt)
+	})
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return clientset, transport, nil
+}
+
+// kubeconfigPath 返回kubeconfig文件路径，依次使用 WithKubeconfig、KUBECONFIG 环境变量和 ~/.kube/config
+func (c *Client) kubeconfigPath() (string, error) {
+	if c.kubeconfig != "" {
+		return c.kubeconfig, nil
+	}
+	if kubeconfig := os.Getenv("KUBECONFIG"); kubeconfig != "" {
+		return kubeconfig, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("获取用户home目录失败: %v", err)
+	}
+	return filepath.Join(home, ".kube", "config"), nil
+}
+
+// toSet 把名称列表转换为集合，列表为空时返回 nil
+func toSet(names []string) map[string]bool {
+	if len(names) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
 }
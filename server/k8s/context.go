@@ -0,0 +1,76 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-docker/server/args"
+	"mcp-docker/server/output"
+	"mcp-docker/server/page"
+	"mcp-docker/server/toolerror"
+)
+
+// 列出kubeconfig context的工具函数
+func (t *Toolset) ListKubeContextsTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	paging, err := page.Of(request)
+	if err != nil {
+		return args.ErrorResult(request, err)
+	}
+
+	slog.Info("ai 正在调用mcp server的tool", "tool", "list_kube_contexts")
+
+	contexts, err := t.client.Contexts()
+	if err != nil {
+		return toolerror.Result(request, "获取context列表失败", err)
+	}
+
+	contexts, info := page.Slice(contexts, paging)
+
+	// 格式化输出
+	var result strings.Builder
+	result.WriteString("CURRENT\tNAME\tCLUSTER\tSERVER\tUSER\tNAMESPACE\n")
+	for _, kubeContext := range contexts {
+		current := ""
+		if kubeContext.Current {
+			current = "*"
+		}
+		result.WriteString(fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\n",
+			current,
+			kubeContext.Name,
+			kubeContext.Cluster,
+			kubeContext.Server,
+			kubeContext.User,
+			kubeContext.Namespace))
+	}
+
+	return page.Result(request, result.String(), contexts, info)
+}
+
+// 查看当前kubeconfig context的工具函数
+func (t *Toolset) CurrentKubeContextTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	slog.Info("ai 正在调用mcp server的tool", "tool", "current_kube_context")
+
+	contexts, err := t.client.Contexts()
+	if err != nil {
+		return toolerror.Result(request, "获取当前context失败", err)
+	}
+
+	for _, kubeContext := range contexts {
+		if !kubeContext.Current {
+			continue
+		}
+		text := fmt.Sprintf("当前context: %s\n集群: %s\n地址: %s\n用户: %s\nkubeconfig中设置的命名空间: %s\n",
+			kubeContext.Name,
+			kubeContext.Cluster,
+			kubeContext.Server,
+			kubeContext.User,
+			kubeContext.Namespace)
+		return output.Result(request, text, kubeContext)
+	}
+
+	return toolerror.Coded(request, toolerror.CodeInvalidArgument, "kubeconfig未设置 current-context，请在调用工具时通过 context 参数指定")
+}
@@ -3,6 +3,7 @@ package k8s
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"strings"
 	"time"
 
@@ -11,33 +12,51 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+
+	"mcp-docker/server/args"
+	"mcp-docker/server/output"
+	"mcp-docker/server/page"
+	"mcp-docker/server/toolerror"
 )
 
 // 列出Deployment的工具函数
-func ListDeploymentsTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	namespace, _ := request.Params.Arguments["namespace"].(string)
+func (t *Toolset) ListDeploymentsTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params namespaceArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	namespace := params.Namespace
 	if namespace == "" {
 		namespace = "default"
 	}
 
-	fmt.Println("ai 正在调用mcp server的tool: list_deployments, namespace=", namespace)
+	paging, err := page.Of(request)
+	if err != nil {
+		return args.ErrorResult(request, err)
+	}
+
+	slog.Info("ai 正在调用mcp server的tool", "tool", "list_deployments", "namespace", namespace)
 
-	// 创建K8s客户端
-	clientset, err := CreateK8sClient()
+	// 获取K8s客户端
+	clientset, err := t.client.Get(contextOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Kubernetes客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Kubernetes客户端失败", err)
 	}
 
 	// 获取Deployment列表
-	deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{
+		Limit:    int64(paging.Limit),
+		Continue: paging.Continue,
+	})
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("获取Deployment列表失败: %v", err)), err
+		return toolerror.Result(request, "获取Deployment列表失败", err)
 	}
 
 	// 格式化输出
 	var result strings.Builder
 	result.WriteString(fmt.Sprintf("命名空间: %s\n\n", namespace))
 	result.WriteString("NAME\tREADY\tUP-TO-DATE\tAVAILABLE\tAGE\tCONTAINERS\tIMAGES\n")
+	summaries := make([]DeploymentSummary, 0, len(deployments.Items))
 
 	for _, deployment := range deployments.Items {
 		// 计算运行时间
@@ -60,31 +79,52 @@ func ListDeploymentsTool(ctx context.Context, request mcp.CallToolRequest) (*mcp
 			age,
 			strings.Join(containers, ","),
 			strings.Join(images, ",")))
+
+		summaries = append(summaries, DeploymentSummary{
+			Name:              deployment.Name,
+			Namespace:         deployment.Namespace,
+			Replicas:          deployment.Status.Replicas,
+			ReadyReplicas:     deployment.Status.ReadyReplicas,
+			UpdatedReplicas:   deployment.Status.UpdatedReplicas,
+			AvailableReplicas: deployment.Status.AvailableReplicas,
+			Created:           deployment.CreationTimestamp.Time,
+			Containers:        containers,
+			Images:            images,
+		})
 	}
 
-	return mcp.NewToolResultText(result.String()), nil
+	return page.Result(request, result.String(), summaries, paging.Continued(len(deployments.Items), deployments.Continue, deployments.RemainingItemCount))
+}
+
+// deploymentArgs 只操作一个Deployment的工具的参数
+type deploymentArgs struct {
+	DeploymentName string `json:"deployment_name"`
+	Namespace      string `json:"namespace"`
 }
 
 // 获取Deployment详情的工具函数
-func DescribeDeploymentTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	deploymentName := request.Params.Arguments["deployment_name"].(string)
-	namespace, _ := request.Params.Arguments["namespace"].(string)
+func (t *Toolset) DescribeDeploymentTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params deploymentArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	deploymentName, namespace := params.DeploymentName, params.Namespace
 	if namespace == "" {
 		namespace = "default"
 	}
 
-	fmt.Println("ai 正在调用mcp server的tool: describe_deployment, deployment_name=", deploymentName, ", namespace=", namespace)
+	slog.Info("ai 正在调用mcp server的tool", "tool", "describe_deployment", "deployment_name", deploymentName, "namespace", namespace)
 
-	// 创建K8s客户端
-	clientset, err := CreateK8sClient()
+	// 获取K8s客户端
+	clientset, err := t.client.Get(contextOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Kubernetes客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Kubernetes客户端失败", err)
 	}
 
 	// 获取Deployment详情
 	deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("获取Deployment详情失败: %v", err)), err
+		return toolerror.Result(request, "获取Deployment详情失败", err)
 	}
 
 	// 格式化输出
@@ -201,36 +241,45 @@ func DescribeDeploymentTool(ctx context.Context, request mcp.CallToolRequest) (*
 		}
 	}
 
-	return mcp.NewToolResultText(result.String()), nil
+	deployment.ManagedFields = nil
+	return output.Result(request, result.String(), DeploymentDetail{
+		Deployment: deployment,
+		Events:     eventSummaries(events, err),
+	})
+}
+
+// scaleDeploymentArgs scale_deployment 的参数
+type scaleDeploymentArgs struct {
+	DeploymentName string `json:"deployment_name"`
+	Namespace      string `json:"namespace"`
+	Replicas       int32  `json:"replicas"`
 }
 
 // 扩缩Deployment的工具函数
-func ScaleDeploymentTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	deploymentName := request.Params.Arguments["deployment_name"].(string)
-	namespace, _ := request.Params.Arguments["namespace"].(string)
+func (t *Toolset) ScaleDeploymentTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params scaleDeploymentArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	deploymentName, namespace := params.DeploymentName, params.Namespace
 	if namespace == "" {
 		namespace = "default"
 	}
 
-	replicas, ok := request.Params.Arguments["replicas"].(float64)
-	if !ok {
-		return mcp.NewToolResultText("缺少必要的参数: replicas"), fmt.Errorf("缺少replicas参数")
-	}
-
-	replicasInt := int32(replicas)
+	replicasInt := params.Replicas
 
-	fmt.Println("ai 正在调用mcp server的tool: scale_deployment, deployment_name=", deploymentName, ", namespace=", namespace, ", replicas=", replicasInt)
+	slog.Info("ai 正在调用mcp server的tool", "tool", "scale_deployment", "deployment_name", deploymentName, "namespace", namespace, "replicas", replicasInt)
 
-	// 创建K8s客户端
-	clientset, err := CreateK8sClient()
+	// 获取K8s客户端
+	clientset, err := t.client.Get(contextOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Kubernetes客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Kubernetes客户端失败", err)
 	}
 
 	// 获取当前Deployment
 	deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("获取Deployment失败: %v", err)), err
+		return toolerror.Result(request, "获取Deployment失败", err)
 	}
 
 	// 记录原副本数
@@ -240,54 +289,80 @@ func ScaleDeploymentTool(ctx context.Context, request mcp.CallToolRequest) (*mcp
 	deployment.Spec.Replicas = &replicasInt
 
 	// 应用更新
-	_, err = clientset.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{})
+	dryRun := IsDryRun(request)
+	updated, err := clientset.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{DryRun: dryRunOption(dryRun)})
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("扩缩Deployment失败: %v", err)), err
+		return toolerror.Result(request, "扩缩Deployment失败", err)
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("已将Deployment %s 在命名空间 %s 中的副本数从 %d 扩缩到 %d",
-		deploymentName, namespace, oldReplicas, replicasInt)), nil
+	if dryRun {
+		var result strings.Builder
+		result.WriteString(DryRunHeader)
+		result.WriteString(fmt.Sprintf("Deployment: %s/%s\n", namespace, deploymentName))
+		result.WriteString(fmt.Sprintf("副本数: %d -> %d\n", oldReplicas, *updated.Spec.Replicas))
+		if oldReplicas == *updated.Spec.Replicas {
+			result.WriteString("副本数未变化，扩缩不会产生任何变化\n")
+		}
+		return output.DryRun(request, namespace+"/"+deploymentName, result.String(), nil)
+	}
+
+	return output.Action(request, namespace+"/"+deploymentName, fmt.Sprintf("已将Deployment %s 在命名空间 %s 中的副本数从 %d 扩缩到 %d",
+		deploymentName, namespace, oldReplicas, replicasInt))
 }
 
 // 重启Deployment的工具函数
-func RestartDeploymentTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	deploymentName := request.Params.Arguments["deployment_name"].(string)
-	namespace, _ := request.Params.Arguments["namespace"].(string)
+func (t *Toolset) RestartDeploymentTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params deploymentArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	deploymentName, namespace := params.DeploymentName, params.Namespace
 	if namespace == "" {
 		namespace = "default"
 	}
 
-	fmt.Println("ai 正在调用mcp server的tool: restart_deployment, deployment_name=", deploymentName, ", namespace=", namespace)
+	slog.Info("ai 正在调用mcp server的tool", "tool", "restart_deployment", "deployment_name", deploymentName, "namespace", namespace)
 
-	// 创建K8s客户端
-	clientset, err := CreateK8sClient()
+	// 获取K8s客户端
+	clientset, err := t.client.Get(contextOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Kubernetes客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Kubernetes客户端失败", err)
 	}
 
 	// 获取当前Deployment
 	deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("获取Deployment失败: %v", err)), err
+		return toolerror.Result(request, "获取Deployment失败", err)
 	}
 
 	// 添加或更新重启注解
 	if deployment.Spec.Template.Annotations == nil {
 		deployment.Spec.Template.Annotations = make(map[string]string)
 	}
-	deployment.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"] = time.Now().Format(time.RFC3339)
+	restartedAt := time.Now().Format(time.RFC3339)
+	deployment.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"] = restartedAt
 
 	// 应用更新
-	_, err = clientset.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{})
+	dryRun := IsDryRun(request)
+	updated, err := clientset.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{DryRun: dryRunOption(dryRun)})
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("重启Deployment失败: %v", err)), err
+		return toolerror.Result(request, "重启Deployment失败", err)
+	}
+
+	if dryRun {
+		var result strings.Builder
+		result.WriteString(DryRunHeader)
+		result.WriteString(fmt.Sprintf("Deployment: %s/%s\n", namespace, deploymentName))
+		result.WriteString(fmt.Sprintf("将设置Pod模板注解 kubectl.kubernetes.io/restartedAt=%s\n", restartedAt))
+		result.WriteString(fmt.Sprintf("将按 %s 策略滚动替换 %d 个副本\n", updated.Spec.Strategy.Type, *updated.Spec.Replicas))
+		return output.DryRun(request, namespace+"/"+deploymentName, result.String(), nil)
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("Deployment %s 在命名空间 %s 中已开始重启", deploymentName, namespace)), nil
+	return output.Action(request, namespace+"/"+deploymentName, fmt.Sprintf("Deployment %s 在命名空间 %s 中已开始重启", deploymentName, namespace))
 }
 
 // 辅助函数：获取Deployment相关事件
-func getEventsForDeployment(ctx context.Context, clientset *kubernetes.Clientset, deployment *appsv1.Deployment) (*corev1.EventList, error) {
+func getEventsForDeployment(ctx context.Context, clientset kubernetes.Interface, deployment *appsv1.Deployment) (*corev1.EventList, error) {
 	fieldSelector := fmt.Sprintf("involvedObject.name=%s,involvedObject.namespace=%s,involvedObject.kind=Deployment",
 		deployment.Name, deployment.Namespace)
 
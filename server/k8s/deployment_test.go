@@ -0,0 +1,105 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"mcp-docker/server/internal/tooltest"
+	"mcp-docker/server/output"
+	"mcp-docker/server/toolerror"
+)
+
+// newDeployment 构造一个指定副本数的Deployment
+func newDeployment(namespace, name string, replicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+}
+
+func TestScaleDeploymentTool(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newDeployment("default", "web", 1))
+	toolset := NewToolset(WrapClient(clientset))
+
+	result, err := toolset.ScaleDeploymentTool(context.Background(), tooltest.Request("scale_deployment", map[string]interface{}{
+		"deployment_name": "web",
+		"replicas":        3,
+		output.FormatArg:  output.FormatJSON,
+	}))
+	if err != nil {
+		t.Fatalf("不应返回Go错误: %v", err)
+	}
+
+	var action output.ActionResult
+	tooltest.JSON(t, result, &action)
+	if action.DryRun || action.Target != "default/web" {
+		t.Errorf("执行结果不正确: %+v", action)
+	}
+	deployment, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("获取Deployment失败: %v", err)
+	}
+	if *deployment.Spec.Replicas != 3 {
+		t.Errorf("副本数应为 3，实际为 %d", *deployment.Spec.Replicas)
+	}
+}
+
+func TestScaleDeploymentToolDryRun(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newDeployment("default", "web", 1))
+	// 假客户端不识别 DryRun，这里只记录更新选项并返回更新后的对象，不真正保存
+	var options *metav1.UpdateOptions
+	clientset.PrependReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		update := action.(k8stesting.UpdateActionImpl)
+		opts := update.GetUpdateOptions()
+		options = &opts
+		return true, update.GetObject(), nil
+	})
+	toolset := NewToolset(WrapClient(clientset))
+
+	result, err := toolset.ScaleDeploymentTool(context.Background(), tooltest.Request("scale_deployment", map[string]interface{}{
+		"deployment_name": "web",
+		"replicas":        3,
+		DryRunArg:         true,
+		output.FormatArg:  output.FormatJSON,
+	}))
+	if err != nil {
+		t.Fatalf("不应返回Go错误: %v", err)
+	}
+
+	var action output.ActionResult
+	tooltest.JSON(t, result, &action)
+	if !action.DryRun {
+		t.Errorf("应返回预演结果，实际: %+v", action)
+	}
+	if options == nil || len(options.DryRun) != 1 || options.DryRun[0] != metav1.DryRunAll {
+		t.Errorf("更新请求应带有 DryRun=All，实际: %+v", options)
+	}
+	deployment, err := clientset.AppsV1().Deployments("default").Get(context.Background(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("获取Deployment失败: %v", err)
+	}
+	if *deployment.Spec.Replicas != 1 {
+		t.Errorf("预演不应修改副本数，实际为 %d", *deployment.Spec.Replicas)
+	}
+}
+
+func TestScaleDeploymentToolNotFound(t *testing.T) {
+	toolset := NewToolset(WrapClient(fake.NewSimpleClientset()))
+
+	result, err := toolset.ScaleDeploymentTool(context.Background(), tooltest.Request("scale_deployment", map[string]interface{}{
+		"deployment_name": "missing",
+		"replicas":        3,
+	}))
+	if err != nil {
+		t.Fatalf("不应返回Go错误: %v", err)
+	}
+	if code := toolerror.CodeOfResult(result); code != toolerror.CodeNotFound {
+		t.Errorf("错误码应为 %s，实际为 %q: %s", toolerror.CodeNotFound, code, tooltest.Text(t, result))
+	}
+}
@@ -0,0 +1,34 @@
+package k8s
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DryRunHeader 预演结果的统一开头
+const DryRunHeader = "【预演】以下为将要进行的修改，请求已由API Server以 DryRun=All 校验，未做任何实际更改\n"
+
+// DryRunArg 修改类工具选择预演模式的参数名
+const DryRunArg = "dry_run"
+
+// WithDryRun 为修改类工具声明预演参数
+func WithDryRun() mcp.ToolOption {
+	return mcp.WithBoolean(DryRunArg,
+		mcp.Description("是否只预演，返回将要进行的修改而不实际执行"),
+		mcp.DefaultBool(false),
+	)
+}
+
+// IsDryRun 判断本次调用是否为预演
+func IsDryRun(request mcp.CallToolRequest) bool {
+	dryRun, _ := request.Params.Arguments[DryRunArg].(bool)
+	return dryRun
+}
+
+// 辅助函数：返回服务端预演选项，非预演时为空
+func dryRunOption(dryRun bool) []string {
+	if dryRun {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
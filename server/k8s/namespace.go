@@ -3,36 +3,58 @@ package k8s
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"strings"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+
+	"mcp-docker/server/args"
+	"mcp-docker/server/output"
+	"mcp-docker/server/page"
+	"mcp-docker/server/toolerror"
 )
 
 // 列出Namespace的工具函数
-func ListNamespacesTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	fmt.Println("ai 正在调用mcp server的tool: list_namespaces")
+func (t *Toolset) ListNamespacesTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	paging, err := page.Of(request)
+	if err != nil {
+		return args.ErrorResult(request, err)
+	}
 
-	// 创建K8s客户端
-	clientset, err := CreateK8sClient()
+	slog.Info("ai 正在调用mcp server的tool", "tool", "list_namespaces")
+
+	// 获取K8s客户端
+	clientset, err := t.client.Get(contextOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Kubernetes客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Kubernetes客户端失败", err)
 	}
 
 	// 获取Namespace列表
 	namespaces, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("获取Namespace列表失败: %v", err)), err
+		return toolerror.Result(request, "获取Namespace列表失败", err)
 	}
 
 	// 格式化输出
 	var result strings.Builder
 	result.WriteString("NAME\tSTATUS\tAGE\n")
-
+	// 只列出允许操作的命名空间；命名空间数量有限，过滤后再分页
+	allowed := make([]corev1.Namespace, 0, len(namespaces.Items))
 	for _, ns := range namespaces.Items {
+		if t.client.NamespaceAllowed(ns.Name) {
+			allowed = append(allowed, ns)
+		}
+	}
+	allowed, info := page.Slice(allowed, paging)
+	summaries := make([]NamespaceSummary, 0, len(allowed))
+
+	for _, ns := range allowed {
+
 		// 计算运行时间
 		age := formatAge(ns.CreationTimestamp.Time)
 
@@ -40,27 +62,42 @@ func ListNamespacesTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.
 			ns.Name,
 			string(ns.Status.Phase),
 			age))
+
+		summaries = append(summaries, NamespaceSummary{
+			Name:    ns.Name,
+			Status:  string(ns.Status.Phase),
+			Created: ns.CreationTimestamp.Time,
+		})
 	}
 
-	return mcp.NewToolResultText(result.String()), nil
+	return page.Result(request, result.String(), summaries, info)
+}
+
+// namespaceNameArgs 操作一个命名空间的工具的参数
+type namespaceNameArgs struct {
+	NamespaceName string `json:"namespace_name"`
 }
 
 // 获取Namespace详情的工具函数
-func DescribeNamespaceTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	namespaceName := request.Params.Arguments["namespace_name"].(string)
+func (t *Toolset) DescribeNamespaceTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params namespaceNameArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	namespaceName := params.NamespaceName
 
-	fmt.Println("ai 正在调用mcp server的tool: describe_namespace, namespace_name=", namespaceName)
+	slog.Info("ai 正在调用mcp server的tool", "tool", "describe_namespace", "namespace_name", namespaceName)
 
-	// 创建K8s客户端
-	clientset, err := CreateK8sClient()
+	// 获取K8s客户端
+	clientset, err := t.client.Get(contextOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Kubernetes客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Kubernetes客户端失败", err)
 	}
 
 	// 获取Namespace详情
 	namespace, err := clientset.CoreV1().Namespaces().Get(ctx, namespaceName, metav1.GetOptions{})
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("获取Namespace详情失败: %v", err)), err
+		return toolerror.Result(request, "获取Namespace详情失败", err)
 	}
 
 	// 格式化输出
@@ -71,8 +108,17 @@ func DescribeNamespaceTool(ctx context.Context, request mcp.CallToolRequest) (*m
 	result.WriteString(fmt.Sprintf("Status:            %s\n", string(namespace.Status.Phase)))
 	result.WriteString(fmt.Sprintf("CreationTimestamp: %s\n", namespace.CreationTimestamp.Format(time.RFC3339)))
 
+	detail := NamespaceDetail{
+		Namespace:      namespace,
+		ResourceQuotas: []corev1.ResourceQuota{},
+		LimitRanges:    []corev1.LimitRange{},
+	}
+
 	// 获取资源配额
 	quotas, err := clientset.CoreV1().ResourceQuotas(namespace.Name).List(ctx, metav1.ListOptions{})
+	if err == nil {
+		detail.ResourceQuotas = quotas.Items
+	}
 	if err == nil && len(quotas.Items) > 0 {
 		result.WriteString("\nResource Quotas:\n")
 		for _, quota := range quotas.Items {
@@ -94,6 +140,9 @@ func DescribeNamespaceTool(ctx context.Context, request mcp.CallToolRequest) (*m
 
 	// 获取资源限制范围
 	limits, err := clientset.CoreV1().LimitRanges(namespace.Name).List(ctx, metav1.ListOptions{})
+	if err == nil {
+		detail.LimitRanges = limits.Items
+	}
 	if err == nil && len(limits.Items) > 0 {
 		result.WriteString("\nLimit Ranges:\n")
 		for _, limit := range limits.Items {
@@ -146,6 +195,14 @@ func DescribeNamespaceTool(ctx context.Context, request mcp.CallToolRequest) (*m
 	result.WriteString(fmt.Sprintf("  ConfigMaps: %d\n", cmCount))
 	result.WriteString(fmt.Sprintf("  Secrets: %d\n", secretCount))
 
+	detail.Workloads = map[string]int{
+		"Deployments": deployCount,
+		"Services":    svcCount,
+		"Pods":        podCount,
+		"ConfigMaps":  cmCount,
+		"Secrets":     secretCount,
+	}
+
 	// 获取相关事件
 	events, err := getEventsForNamespace(ctx, clientset, namespace)
 	if err == nil && len(events.Items) > 0 {
@@ -162,19 +219,25 @@ func DescribeNamespaceTool(ctx context.Context, request mcp.CallToolRequest) (*m
 		}
 	}
 
-	return mcp.NewToolResultText(result.String()), nil
+	namespace.ManagedFields = nil
+	detail.Events = eventSummaries(events, err)
+	return output.Result(request, result.String(), detail)
 }
 
 // 创建Namespace的工具函数
-func CreateNamespaceTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	namespaceName := request.Params.Arguments["namespace_name"].(string)
+func (t *Toolset) CreateNamespaceTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params namespaceNameArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	namespaceName := params.NamespaceName
 
-	fmt.Println("ai 正在调用mcp server的tool: create_namespace, namespace_name=", namespaceName)
+	slog.Info("ai 正在调用mcp server的tool", "tool", "create_namespace", "namespace_name", namespaceName)
 
-	// 创建K8s客户端
-	clientset, err := CreateK8sClient()
+	// 获取K8s客户端
+	clientset, err := t.client.Get(contextOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Kubernetes客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Kubernetes客户端失败", err)
 	}
 
 	// 创建Namespace对象
@@ -185,37 +248,72 @@ func CreateNamespaceTool(ctx context.Context, request mcp.CallToolRequest) (*mcp
 	}
 
 	// 创建Namespace
-	_, err = clientset.CoreV1().Namespaces().Create(ctx, namespace, metav1.CreateOptions{})
+	dryRun := IsDryRun(request)
+	_, err = clientset.CoreV1().Namespaces().Create(ctx, namespace, metav1.CreateOptions{DryRun: dryRunOption(dryRun)})
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Namespace失败: %v", err)), err
+		return toolerror.Result(request, "创建Namespace失败", err)
+	}
+
+	if dryRun {
+		return output.DryRun(request, namespaceName, fmt.Sprintf("%s将创建Namespace: %s\n", DryRunHeader, namespaceName), nil)
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("Namespace %s 创建成功", namespaceName)), nil
+	return output.Action(request, namespaceName, fmt.Sprintf("Namespace %s 创建成功", namespaceName))
 }
 
 // 删除Namespace的工具函数
-func DeleteNamespaceTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	namespaceName := request.Params.Arguments["namespace_name"].(string)
+func (t *Toolset) DeleteNamespaceTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params namespaceNameArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	namespaceName := params.NamespaceName
 
-	fmt.Println("ai 正在调用mcp server的tool: delete_namespace, namespace_name=", namespaceName)
+	slog.Info("ai 正在调用mcp server的tool", "tool", "delete_namespace", "namespace_name", namespaceName)
 
-	// 创建K8s客户端
-	clientset, err := CreateK8sClient()
+	// 获取K8s客户端
+	clientset, err := t.client.Get(contextOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Kubernetes客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Kubernetes客户端失败", err)
 	}
 
 	// 删除Namespace
-	err = clientset.CoreV1().Namespaces().Delete(ctx, namespaceName, metav1.DeleteOptions{})
+	dryRun := IsDryRun(request)
+	err = clientset.CoreV1().Namespaces().Delete(ctx, namespaceName, metav1.DeleteOptions{DryRun: dryRunOption(dryRun)})
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("删除Namespace失败: %v", err)), err
+		return toolerror.Result(request, "删除Namespace失败", err)
+	}
+
+	if dryRun {
+		var result strings.Builder
+		result.WriteString(DryRunHeader)
+		result.WriteString(fmt.Sprintf("将删除Namespace: %s，其中的全部资源会随之删除，包括:\n", namespaceName))
+		counts := []struct {
+			kind  string
+			count func(context.Context, kubernetes.Interface, string) (int, error)
+		}{
+			{"Deployments", getDeploymentCount},
+			{"Services", getServiceCount},
+			{"Pods", getPodCount},
+			{"ConfigMaps", getConfigMapCount},
+			{"Secrets", getSecretCount},
+		}
+		for _, c := range counts {
+			n, err := c.count(ctx, clientset, namespaceName)
+			if err != nil {
+				result.WriteString(fmt.Sprintf("  %s: 无法获取 (%v)\n", c.kind, err))
+				continue
+			}
+			result.WriteString(fmt.Sprintf("  %s: %d\n", c.kind, n))
+		}
+		return output.DryRun(request, namespaceName, result.String(), nil)
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("Namespace %s 删除成功（删除过程可能需要一些时间才能完成）", namespaceName)), nil
+	return output.Action(request, namespaceName, fmt.Sprintf("Namespace %s 删除成功（删除过程可能需要一些时间才能完成）", namespaceName))
 }
 
 // 辅助函数：获取Namespace相关事件
-func getEventsForNamespace(ctx context.Context, clientset *kubernetes.Clientset, namespace *corev1.Namespace) (*corev1.EventList, error) {
+func getEventsForNamespace(ctx context.Context, clientset kubernetes.Interface, namespace *corev1.Namespace) (*corev1.EventList, error) {
 	fieldSelector := fmt.Sprintf("involvedObject.name=%s,involvedObject.kind=Namespace", namespace.Name)
 
 	return clientset.CoreV1().Events("").List(ctx, metav1.ListOptions{
@@ -224,7 +322,7 @@ func getEventsForNamespace(ctx context.Context, clientset *kubernetes.Clientset,
 }
 
 // 辅助函数：获取命名空间中的Deployment数量
-func getDeploymentCount(ctx context.Context, clientset *kubernetes.Clientset, namespace string) (int, error) {
+func getDeploymentCount(ctx context.Context, clientset kubernetes.Interface, namespace string) (int, error) {
 	deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return 0, err
@@ -233,7 +331,7 @@ func getDeploymentCount(ctx context.Context, clientset *kubernetes.Clientset, na
 }
 
 // 辅助函数：获取命名空间中的Service数量
-func getServiceCount(ctx context.Context, clientset *kubernetes.Clientset, namespace string) (int, error) {
+func getServiceCount(ctx context.Context, clientset kubernetes.Interface, namespace string) (int, error) {
 	services, err := clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return 0, err
@@ -242,7 +340,7 @@ func getServiceCount(ctx context.Context, clientset *kubernetes.Clientset, names
 }
 
 // 辅助函数：获取命名空间中的Pod数量
-func getPodCount(ctx context.Context, clientset *kubernetes.Clientset, namespace string) (int, error) {
+func getPodCount(ctx context.Context, clientset kubernetes.Interface, namespace string) (int, error) {
 	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return 0, err
@@ -251,7 +349,7 @@ func getPodCount(ctx context.Context, clientset *kubernetes.Clientset, namespace
 }
 
 // 辅助函数：获取命名空间中的ConfigMap数量
-func getConfigMapCount(ctx context.Context, clientset *kubernetes.Clientset, namespace string) (int, error) {
+func getConfigMapCount(ctx context.Context, clientset kubernetes.Interface, namespace string) (int, error) {
 	configmaps, err := clientset.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return 0, err
@@ -260,10 +358,34 @@ func getConfigMapCount(ctx context.Context, clientset *kubernetes.Clientset, nam
 }
 
 // 辅助函数：获取命名空间中的Secret数量
-func getSecretCount(ctx context.Context, clientset *kubernetes.Clientset, namespace string) (int, error) {
+func getSecretCount(ctx context.Context, clientset kubernetes.Interface, namespace string) (int, error) {
 	secrets, err := clientset.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return 0, err
 	}
 	return len(secrets.Items), nil
 }
+
+// restrictNamespace 拒绝操作不允许的命名空间，tool 没有命名空间参数时不做检查
+func (t *Toolset) restrictNamespace(tool mcp.Tool, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	var arg string
+	for _, name := range []string{"namespace", "namespace_name"} {
+		if _, ok := tool.InputSchema.Properties[name]; ok {
+			arg = name
+		}
+	}
+	if arg == "" {
+		return next
+	}
+
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		namespace, _ := request.Params.Arguments[arg].(string)
+		if namespace == "" {
+			namespace = "default"
+		}
+		if !t.client.NamespaceAllowed(namespace) {
+			return toolerror.Coded(request, toolerror.CodePermissionDenied, fmt.Sprintf("命名空间 %s 不在允许操作的范围内", namespace))
+		}
+		return next(ctx, request)
+	}
+}
@@ -3,7 +3,7 @@ package k8s
 import (
 	"context"
 	"fmt"
-	"io"
+	"log/slog"
 	"strings"
 	"time"
 
@@ -12,33 +12,62 @@ import (
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-docker/server/args"
+	"mcp-docker/server/output"
+	"mcp-docker/server/page"
+	"mcp-docker/server/toolerror"
 )
 
+// namespaceArgs 列出命名空间中资源的工具的参数
+type namespaceArgs struct {
+	Namespace string `json:"namespace"`
+}
+
+// podArgs 只操作一个Pod的工具的参数
+type podArgs struct {
+	PodName   string `json:"pod_name"`
+	Namespace string `json:"namespace"`
+}
+
 // 列出Pod的工具函数
-func ListPodsTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	namespace, _ := request.Params.Arguments["namespace"].(string)
+func (t *Toolset) ListPodsTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params namespaceArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	namespace := params.Namespace
 	if namespace == "" {
 		namespace = "default"
 	}
 
-	fmt.Println("ai 正在调用mcp server的tool: list_pods, namespace=", namespace)
+	paging, err := page.Of(request)
+	if err != nil {
+		return args.ErrorResult(request, err)
+	}
+
+	slog.Info("ai 正在调用mcp server的tool", "tool", "list_pods", "namespace", namespace)
 
-	// 创建K8s客户端
-	clientset, err := CreateK8sClient()
+	// 获取K8s客户端
+	clientset, err := t.client.Get(contextOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Kubernetes客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Kubernetes客户端失败", err)
 	}
 
 	// 获取Pod列表
-	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		Limit:    int64(paging.Limit),
+		Continue: paging.Continue,
+	})
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("获取Pod列表失败: %v", err)), err
+		return toolerror.Result(request, "获取Pod列表失败", err)
 	}
 
 	// 格式化输出
 	var result strings.Builder
 	result.WriteString(fmt.Sprintf("命名空间: %s\n\n", namespace))
 	result.WriteString("NAME\tREADY\tSTATUS\tRESTARTS\tAGE\tIP\tNODE\n")
+	summaries := make([]PodSummary, 0, len(pods.Items))
 
 	for _, pod := range pods.Items {
 		// 计算容器就绪数
@@ -67,31 +96,46 @@ func ListPodsTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallTo
 			age,
 			pod.Status.PodIP,
 			pod.Spec.NodeName))
+
+		summaries = append(summaries, PodSummary{
+			Name:       pod.Name,
+			Namespace:  pod.Namespace,
+			Ready:      readyContainers,
+			Containers: len(pod.Spec.Containers),
+			Status:     string(pod.Status.Phase),
+			Restarts:   restarts,
+			Created:    pod.CreationTimestamp.Time,
+			IP:         pod.Status.PodIP,
+			Node:       pod.Spec.NodeName,
+		})
 	}
 
-	return mcp.NewToolResultText(result.String()), nil
+	return page.Result(request, result.String(), summaries, paging.Continued(len(pods.Items), pods.Continue, pods.RemainingItemCount))
 }
 
 // 获取Pod详情的工具函数
-func DescribePodTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	podName := request.Params.Arguments["pod_name"].(string)
-	namespace, _ := request.Params.Arguments["namespace"].(string)
+func (t *Toolset) DescribePodTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params podArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	podName, namespace := params.PodName, params.Namespace
 	if namespace == "" {
 		namespace = "default"
 	}
 
-	fmt.Println("ai 正在调用mcp server的tool: describe_pod, pod_name=", podName, ", namespace=", namespace)
+	slog.Info("ai 正在调用mcp server的tool", "tool", "describe_pod", "pod_name", podName, "namespace", namespace)
 
-	// 创建K8s客户端
-	clientset, err := CreateK8sClient()
+	// 获取K8s客户端
+	clientset, err := t.client.Get(contextOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Kubernetes客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Kubernetes客户端失败", err)
 	}
 
 	// 获取Pod详情
 	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("获取Pod详情失败: %v", err)), err
+		return toolerror.Result(request, "获取Pod详情失败", err)
 	}
 
 	// 格式化输出
@@ -177,92 +221,166 @@ func DescribePodTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.Cal
 		}
 	}
 
-	return mcp.NewToolResultText(result.String()), nil
+	pod.ManagedFields = nil
+	return output.Result(request, result.String(), PodDetail{
+		Pod:    pod,
+		Events: eventSummaries(events, err),
+	})
+}
+
+// deletePodArgs delete_pod 的参数
+type deletePodArgs struct {
+	PodName   string `json:"pod_name"`
+	Namespace string `json:"namespace"`
+	Force     bool   `json:"force"`
 }
 
 // 删除Pod的工具函数
-func DeletePodTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	podName := request.Params.Arguments["pod_name"].(string)
-	namespace, _ := request.Params.Arguments["namespace"].(string)
+func (t *Toolset) DeletePodTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params deletePodArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	podName, namespace := params.PodName, params.Namespace
 	if namespace == "" {
 		namespace = "default"
 	}
-	force, _ := request.Params.Arguments["force"].(bool)
+	force := params.Force
 
-	fmt.Println("ai 正在调用mcp server的tool: delete_pod, pod_name=", podName, ", namespace=", namespace, ", force=", force)
+	slog.Info("ai 正在调用mcp server的tool", "tool", "delete_pod", "pod_name", podName, "namespace", namespace, "force", force)
 
-	// 创建K8s客户端
-	clientset, err := CreateK8sClient()
+	// 获取K8s客户端
+	clientset, err := t.client.Get(contextOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Kubernetes客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Kubernetes客户端失败", err)
 	}
 
+	dryRun := IsDryRun(request)
+
 	// 设置删除选项
-	deleteOptions := metav1.DeleteOptions{}
+	deleteOptions := metav1.DeleteOptions{DryRun: dryRunOption(dryRun)}
 	if force {
 		gracePeriod := int64(0)
 		deleteOptions.GracePeriodSeconds = &gracePeriod
 	}
 
+	// 预演时先获取Pod，用于展示将被删除的对象
+	var pod *corev1.Pod
+	if dryRun {
+		pod, err = clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return toolerror.Result(request, "获取Pod失败", err)
+		}
+	}
+
 	// 删除Pod
 	err = clientset.CoreV1().Pods(namespace).Delete(ctx, podName, deleteOptions)
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("删除Pod失败: %v", err)), err
+		return toolerror.Result(request, "删除Pod失败", err)
+	}
+
+	if dryRun {
+		var result strings.Builder
+		result.WriteString(DryRunHeader)
+		result.WriteString(fmt.Sprintf("将删除Pod: %s/%s\n", namespace, podName))
+		result.WriteString(fmt.Sprintf("当前状态: %s\n", pod.Status.Phase))
+		result.WriteString(fmt.Sprintf("所在节点: %s\n", pod.Spec.NodeName))
+		if force {
+			result.WriteString("将立即删除，不等待优雅终止\n")
+		} else if pod.Spec.TerminationGracePeriodSeconds != nil {
+			result.WriteString(fmt.Sprintf("将等待最多 %d 秒优雅终止\n", *pod.Spec.TerminationGracePeriodSeconds))
+		}
+		if owners := pod.GetOwnerReferences(); len(owners) > 0 {
+			result.WriteString(fmt.Sprintf("该Pod由 %s %s 管理，删除后会被重新创建\n", owners[0].Kind, owners[0].Name))
+		} else {
+			result.WriteString("该Pod没有控制器管理，删除后不会被重新创建\n")
+		}
+		return output.DryRun(request, namespace+"/"+podName, result.String(), nil)
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("Pod %s 在命名空间 %s 中已成功删除", podName, namespace)), nil
+	return output.Action(request, namespace+"/"+podName, fmt.Sprintf("Pod %s 在命名空间 %s 中已成功删除", podName, namespace))
+}
+
+// podLogsArgs pod_logs 的参数
+type podLogsArgs struct {
+	PodName    string `json:"pod_name"`
+	Namespace  string `json:"namespace"`
+	Container  string `json:"container"`
+	Tail       int64  `json:"tail"`
+	Timestamps bool   `json:"timestamps"`
 }
 
 // 获取Pod日志的工具函数
-func PodLogsTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	podName := request.Params.Arguments["pod_name"].(string)
-	namespace, _ := request.Params.Arguments["namespace"].(string)
+func (t *Toolset) PodLogsTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params podLogsArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	podName, namespace := params.PodName, params.Namespace
 	if namespace == "" {
 		namespace = "default"
 	}
-	container, _ := request.Params.Arguments["container"].(string)
-	tail, _ := request.Params.Arguments["tail"].(float64)
-	if tail == 0 {
-		tail = 100 // 默认值
-	}
+	// tail 的默认值由参数中间件填充，0 表示不限制行数
+	container, tail := params.Container, params.Tail
 
-	fmt.Println("ai 正在调用mcp server的tool: pod_logs, pod_name=", podName, ", namespace=", namespace, ", container=", container)
+	slog.Info("ai 正在调用mcp server的tool", "tool", "pod_logs", "pod_name", podName, "namespace", namespace, "container", container)
+
+	// 获取K8s客户端
+	clientset, err := t.client.Get(contextOf(request))
+	if err != nil {
+		return toolerror.Result(request, "获取Kubernetes客户端失败", err)
+	}
 
-	// 创建K8s客户端
-	clientset, err := CreateK8sClient()
+	budget, err := page.LogsOf(request, int(tail))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Kubernetes客户端失败: %v", err)), err
+		return args.ErrorResult(request, err)
 	}
 
-	// 设置日志选项
-	tailLines := int64(tail)
+	// 始终请求时间戳，用于按游标继续读取；API 不支持 until，游标之前的日志由 budget 过滤
 	podLogOptions := corev1.PodLogOptions{
-		TailLines: &tailLines,
+		Container:  container,
+		Timestamps: true,
 	}
-	if container != "" {
-		podLogOptions.Container = container
+	tailed := false
+	switch {
+	case !budget.Since.IsZero():
+		podLogOptions.SinceTime = &metav1.Time{Time: budget.Since}
+	case budget.Continued():
+		// 查看更早的日志时需要读取全部日志，由 budget 只保留游标之前的部分
+	case tail > 0 && budget.Truncate == page.TruncateHead:
+		podLogOptions.TailLines = &tail
+		tailed = true
 	}
 
 	// 获取Pod日志
 	req := clientset.CoreV1().Pods(namespace).GetLogs(podName, &podLogOptions)
 	podLogs, err := req.Stream(ctx)
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("获取Pod日志失败: %v", err)), err
+		return toolerror.Result(request, "获取Pod日志失败", err)
 	}
 	defer podLogs.Close()
 
-	// 读取日志
-	buf := new(strings.Builder)
-	_, err = io.Copy(buf, podLogs)
+	// 按预算读取日志
+	read, err := budget.Read(podLogs, params.Timestamps, tailed)
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("读取Pod日志失败: %v", err)), err
-	}
-
-	return mcp.NewToolResultText(buf.String()), nil
+		return toolerror.Result(request, "读取Pod日志失败", err)
+	}
+
+	return output.Result(request, read.String(), PodLogs{
+		Pod:        podName,
+		Namespace:  namespace,
+		Container:  container,
+		Tail:       tail,
+		Logs:       read.Text,
+		Lines:      read.Lines,
+		Truncated:  read.Truncated,
+		NextCursor: read.Next,
+		Message:    read.Message,
+	})
 }
 
 // 辅助函数：获取Pod相关事件
-func getEventsForPod(ctx context.Context, clientset *kubernetes.Clientset, pod *corev1.Pod) (*corev1.EventList, error) {
+func getEventsForPod(ctx context.Context, clientset kubernetes.Interface, pod *corev1.Pod) (*corev1.EventList, error) {
 	fieldSelector := fmt.Sprintf("involvedObject.name=%s,involvedObject.namespace=%s,involvedObject.kind=Pod",
 		pod.Name, pod.Namespace)
 
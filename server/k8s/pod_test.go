@@ -0,0 +1,170 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"mcp-docker/server/internal/tooltest"
+	"mcp-docker/server/output"
+	"mcp-docker/server/toolerror"
+)
+
+// newPod 构造一个运行中的单容器Pod
+func newPod(namespace, name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx"}}},
+		Status: corev1.PodStatus{
+			Phase:             corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "app", Ready: true, RestartCount: 2}},
+		},
+	}
+}
+
+func TestListPodsTool(t *testing.T) {
+	toolset := NewToolset(WrapClient(fake.NewSimpleClientset(
+		newPod("default", "web"),
+		newPod("default", "db"),
+		newPod("kube-system", "coredns"),
+	)))
+
+	result, err := toolset.ListPodsTool(context.Background(), tooltest.Request("list_pods", map[string]interface{}{
+		output.FormatArg: output.FormatJSON,
+	}))
+	if err != nil {
+		t.Fatalf("调用失败: %v", err)
+	}
+
+	var list struct {
+		Items []PodSummary `json:"items"`
+	}
+	tooltest.JSON(t, result, &list)
+	if len(list.Items) != 2 {
+		t.Fatalf("未指定命名空间时应只列出 default 中的 2 个Pod，实际: %+v", list.Items)
+	}
+	names := map[string]bool{}
+	for _, pod := range list.Items {
+		names[pod.Name] = true
+		if pod.Namespace != "default" || pod.Ready != 1 || pod.Containers != 1 || pod.Restarts != 2 {
+			t.Errorf("Pod 摘要不正确: %+v", pod)
+		}
+	}
+	if !names["web"] || !names["db"] {
+		t.Errorf("应列出 web 和 db，实际: %+v", list.Items)
+	}
+}
+
+func TestDescribePodToolNotFound(t *testing.T) {
+	toolset := NewToolset(WrapClient(fake.NewSimpleClientset(newPod("default", "web"))))
+
+	result, err := toolset.DescribePodTool(context.Background(), tooltest.Request("describe_pod", map[string]interface{}{
+		"pod_name":  "missing",
+		"namespace": "default",
+	}))
+	if err != nil {
+		t.Fatalf("不应返回Go错误: %v", err)
+	}
+	if code := toolerror.CodeOfResult(result); code != toolerror.CodeNotFound {
+		t.Errorf("错误码应为 %s，实际为 %q: %s", toolerror.CodeNotFound, code, tooltest.Text(t, result))
+	}
+}
+
+func TestDeletePodTool(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newPod("default", "web"))
+	toolset := NewToolset(WrapClient(clientset))
+
+	result, err := toolset.DeletePodTool(context.Background(), tooltest.Request("delete_pod", map[string]interface{}{
+		"pod_name":       "web",
+		"namespace":      "default",
+		"force":          true,
+		output.FormatArg: output.FormatJSON,
+	}))
+	if err != nil {
+		t.Fatalf("不应返回Go错误: %v", err)
+	}
+
+	var action output.ActionResult
+	tooltest.JSON(t, result, &action)
+	if action.DryRun || action.Target != "default/web" {
+		t.Errorf("执行结果不正确: %+v", action)
+	}
+	if _, err := clientset.CoreV1().Pods("default").Get(context.Background(), "web", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("Pod web 应已被删除，实际: %v", err)
+	}
+}
+
+func TestDeletePodToolDryRun(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newPod("default", "web"))
+	// 假客户端不识别 DryRun，这里只记录删除选项，不真正删除
+	var options *metav1.DeleteOptions
+	clientset.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		opts := action.(k8stesting.DeleteActionImpl).GetDeleteOptions()
+		options = &opts
+		return true, nil, nil
+	})
+	toolset := NewToolset(WrapClient(clientset))
+
+	result, err := toolset.DeletePodTool(context.Background(), tooltest.Request("delete_pod", map[string]interface{}{
+		"pod_name":       "web",
+		"namespace":      "default",
+		DryRunArg:        true,
+		output.FormatArg: output.FormatJSON,
+	}))
+	if err != nil {
+		t.Fatalf("不应返回Go错误: %v", err)
+	}
+
+	var action output.ActionResult
+	tooltest.JSON(t, result, &action)
+	if !action.DryRun {
+		t.Errorf("应返回预演结果，实际: %+v", action)
+	}
+	if options == nil || len(options.DryRun) != 1 || options.DryRun[0] != metav1.DryRunAll {
+		t.Errorf("删除请求应带有 DryRun=All，实际: %+v", options)
+	}
+}
+
+func TestPodErrorCodes(t *testing.T) {
+	pods := schema.GroupResource{Resource: "pods"}
+	tests := []struct {
+		name string
+		err  error
+		code string
+	}{
+		{name: "Forbidden", err: apierrors.NewForbidden(pods, "web", nil), code: toolerror.CodePermissionDenied},
+		{name: "Conflict", err: apierrors.NewConflict(pods, "web", nil), code: toolerror.CodeConflict},
+		{name: "BadRequest", err: apierrors.NewBadRequest("invalid"), code: toolerror.CodeInvalidArgument},
+		{name: "ServiceUnavailable", err: apierrors.NewServiceUnavailable("unavailable"), code: toolerror.CodeBackendUnavailable},
+		{name: "Timeout", err: apierrors.NewTimeoutError("timeout", 1), code: toolerror.CodeTimeout},
+		{name: "InternalError", err: apierrors.NewInternalError(errors.New("etcd unavailable")), code: toolerror.CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(newPod("default", "web"))
+			clientset.PrependReactor("delete", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, tt.err
+			})
+			toolset := NewToolset(WrapClient(clientset))
+
+			result, err := toolset.DeletePodTool(context.Background(), tooltest.Request("delete_pod", map[string]interface{}{
+				"pod_name":  "web",
+				"namespace": "default",
+			}))
+			if err != nil {
+				t.Fatalf("不应返回Go错误: %v", err)
+			}
+			if code := toolerror.CodeOfResult(result); code != tt.code {
+				t.Errorf("错误码应为 %s，实际为 %q: %s", tt.code, code, tooltest.Text(t, result))
+			}
+		})
+	}
+}
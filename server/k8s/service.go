@@ -3,39 +3,58 @@ package k8s
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+
+	"mcp-docker/server/args"
+	"mcp-docker/server/output"
+	"mcp-docker/server/page"
+	"mcp-docker/server/toolerror"
 )
 
 // 列出Service的工具函数
-func ListServicesTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	namespace, _ := request.Params.Arguments["namespace"].(string)
+func (t *Toolset) ListServicesTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params namespaceArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	namespace := params.Namespace
 	if namespace == "" {
 		namespace = "default"
 	}
 
-	fmt.Println("ai 正在调用mcp server的tool: list_services, namespace=", namespace)
+	paging, err := page.Of(request)
+	if err != nil {
+		return args.ErrorResult(request, err)
+	}
+
+	slog.Info("ai 正在调用mcp server的tool", "tool", "list_services", "namespace", namespace)
 
-	// 创建K8s客户端
-	clientset, err := CreateK8sClient()
+	// 获取K8s客户端
+	clientset, err := t.client.Get(contextOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Kubernetes客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Kubernetes客户端失败", err)
 	}
 
 	// 获取Service列表
-	services, err := clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	services, err := clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{
+		Limit:    int64(paging.Limit),
+		Continue: paging.Continue,
+	})
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("获取Service列表失败: %v", err)), err
+		return toolerror.Result(request, "获取Service列表失败", err)
 	}
 
 	// 格式化输出
 	var result strings.Builder
 	result.WriteString(fmt.Sprintf("命名空间: %s\n\n", namespace))
 	result.WriteString("NAME\tTYPE\tCLUSTER-IP\tEXTERNAL-IP\tPORT(S)\tAGE\tSELECTOR\n")
+	summaries := make([]ServiceSummary, 0, len(services.Items))
 
 	for _, service := range services.Items {
 		// 计算运行时间
@@ -90,31 +109,58 @@ func ListServicesTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.Ca
 			portStr,
 			age,
 			selectorStr))
+
+		summary := ServiceSummary{
+			Name:        service.Name,
+			Namespace:   service.Namespace,
+			Type:        string(service.Spec.Type),
+			ClusterIP:   service.Spec.ClusterIP,
+			ExternalIPs: []string{},
+			Ports:       ports,
+			Selector:    service.Spec.Selector,
+			Created:     service.CreationTimestamp.Time,
+		}
+		if externalIPs != "<none>" {
+			summary.ExternalIPs = strings.Split(externalIPs, ", ")
+		}
+		if summary.Ports == nil {
+			summary.Ports = []string{}
+		}
+		summaries = append(summaries, summary)
 	}
 
-	return mcp.NewToolResultText(result.String()), nil
+	return page.Result(request, result.String(), summaries, paging.Continued(len(services.Items), services.Continue, services.RemainingItemCount))
+}
+
+// serviceArgs describe_service 的参数
+type serviceArgs struct {
+	ServiceName string `json:"service_name"`
+	Namespace   string `json:"namespace"`
 }
 
 // 获取Service详情的工具函数
-func DescribeServiceTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	serviceName := request.Params.Arguments["service_name"].(string)
-	namespace, _ := request.Params.Arguments["namespace"].(string)
+func (t *Toolset) DescribeServiceTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var params serviceArgs
+	if err := args.Decode(request, &params); err != nil {
+		return args.ErrorResult(request, err)
+	}
+	serviceName, namespace := params.ServiceName, params.Namespace
 	if namespace == "" {
 		namespace = "default"
 	}
 
-	fmt.Println("ai 正在调用mcp server的tool: describe_service, service_name=", serviceName, ", namespace=", namespace)
+	slog.Info("ai 正在调用mcp server的tool", "tool", "describe_service", "service_name", serviceName, "namespace", namespace)
 
-	// 创建K8s客户端
-	clientset, err := CreateK8sClient()
+	// 获取K8s客户端
+	clientset, err := t.client.Get(contextOf(request))
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("创建Kubernetes客户端失败: %v", err)), err
+		return toolerror.Result(request, "获取Kubernetes客户端失败", err)
 	}
 
 	// 获取Service详情
 	service, err := clientset.CoreV1().Services(namespace).Get(ctx, serviceName, metav1.GetOptions{})
 	if err != nil {
-		return mcp.NewToolResultText(fmt.Sprintf("获取Service详情失败: %v", err)), err
+		return toolerror.Result(request, "获取Service详情失败", err)
 	}
 
 	// 格式化输出
@@ -180,9 +226,14 @@ func DescribeServiceTool(ctx context.Context, request mcp.CallToolRequest) (*mcp
 		result.WriteString(fmt.Sprintf("External Name:    %s\n", service.Spec.ExternalName))
 	}
 
+	detail := ServiceDetail{Service: service, Events: []EventSummary{}}
+
 	// 获取和此服务匹配的端点
 	endpoints, err := clientset.CoreV1().Endpoints(namespace).Get(ctx, serviceName, metav1.GetOptions{})
 	if err == nil {
+		endpoints.ManagedFields = nil
+		detail.Endpoints = endpoints
+
 		result.WriteString("\nEndpoints:\n")
 		if len(endpoints.Subsets) == 0 {
 			result.WriteString("  <none>\n")
@@ -225,6 +276,7 @@ func DescribeServiceTool(ctx context.Context, request mcp.CallToolRequest) (*mcp
 	// 如果是LoadBalancer类型，尝试获取相关的事件
 	if service.Spec.Type == corev1.ServiceTypeLoadBalancer {
 		events, err := getEventsForService(ctx, clientset, service)
+		detail.Events = eventSummaries(events, err)
 		if err == nil && len(events.Items) > 0 {
 			result.WriteString("\nEvents:\n")
 			result.WriteString("LAST SEEN\tTYPE\tREASON\tOBJECT\tMESSAGE\n")
@@ -240,11 +292,12 @@ func DescribeServiceTool(ctx context.Context, request mcp.CallToolRequest) (*mcp
 		}
 	}
 
-	return mcp.NewToolResultText(result.String()), nil
+	service.ManagedFields = nil
+	return output.Result(request, result.String(), detail)
 }
 
 // 辅助函数：获取Service相关事件
-func getEventsForService(ctx context.Context, clientset *kubernetes.Clientset, service *corev1.Service) (*corev1.EventList, error) {
+func getEventsForService(ctx context.Context, clientset kubernetes.Interface, service *corev1.Service) (*corev1.EventList, error) {
 	fieldSelector := fmt.Sprintf("involvedObject.name=%s,involvedObject.namespace=%s,involvedObject.kind=Service",
 		service.Name, service.Namespace)
 
@@ -0,0 +1,300 @@
+package k8s
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-docker/server/page"
+	"mcp-docker/server/registry"
+)
+
+// Kubernetes对象名称的格式：命名空间、Service和容器名称为 DNS-1123 label，Pod和Deployment名称为 DNS-1123 subdomain
+const (
+	dnsLabelPattern     = `^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	dnsSubdomainPattern = `^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+)
+
+// Tools 返回Kubernetes相关的全部工具
+func (t *Toolset) Tools() []registry.Spec {
+	specs := []registry.Spec{
+		// Kubernetes Pod相关工具
+		{
+			Tool: mcp.NewTool("list_pods",
+				mcp.WithDescription("列出指定命名空间中的所有Pod"),
+				mcp.WithString("namespace",
+					mcp.Description("要查询的命名空间, 默认为default"),
+					mcp.DefaultString("default"),
+					mcp.Pattern(dnsLabelPattern),
+					mcp.MaxLength(63),
+				),
+				page.WithPagination(),
+			),
+			Handler: t.ListPodsTool,
+		},
+		{
+			Tool: mcp.NewTool("describe_pod",
+				mcp.WithDescription("查看Pod的详细信息"),
+				mcp.WithString("pod_name",
+					mcp.Required(),
+					mcp.Description("要查看的Pod名称"),
+					mcp.Pattern(dnsSubdomainPattern),
+					mcp.MaxLength(253),
+				),
+				mcp.WithString("namespace",
+					mcp.Description("Pod所在的命名空间, 默认为default"),
+					mcp.DefaultString("default"),
+					mcp.Pattern(dnsLabelPattern),
+					mcp.MaxLength(63),
+				),
+			),
+			Handler: t.DescribePodTool,
+		},
+		{
+			Tool: mcp.NewTool("delete_pod",
+				mcp.WithDescription("删除指定的Pod"),
+				mcp.WithString("pod_name",
+					mcp.Required(),
+					mcp.Description("要删除的Pod名称"),
+					mcp.Pattern(dnsSubdomainPattern),
+					mcp.MaxLength(253),
+				),
+				mcp.WithString("namespace",
+					mcp.Description("Pod所在的命名空间, 默认为default"),
+					mcp.DefaultString("default"),
+					mcp.Pattern(dnsLabelPattern),
+					mcp.MaxLength(63),
+				),
+				mcp.WithBoolean("force",
+					mcp.Description("是否强制删除"),
+					mcp.DefaultBool(false),
+				),
+				WithDryRun(),
+			),
+			Handler:     t.DeletePodTool,
+			Mutating:    true,
+			Destructive: true,
+		},
+		{
+			Tool: mcp.NewTool("pod_logs",
+				mcp.WithDescription("获取Pod的日志"),
+				mcp.WithString("pod_name",
+					mcp.Required(),
+					mcp.Description("要查看日志的Pod名称"),
+					mcp.Pattern(dnsSubdomainPattern),
+					mcp.MaxLength(253),
+				),
+				mcp.WithString("namespace",
+					mcp.Description("Pod所在的命名空间, 默认为default"),
+					mcp.DefaultString("default"),
+					mcp.Pattern(dnsLabelPattern),
+					mcp.MaxLength(63),
+				),
+				mcp.WithString("container",
+					mcp.Description("要查看日志的容器名称, 如果Pod中只有一个容器则可以省略"),
+					mcp.Pattern(dnsLabelPattern),
+				),
+				mcp.WithNumber("tail",
+					mcp.Description("最多返回的日志行数，0 表示不限制行数，只受 max_bytes 限制"),
+					mcp.DefaultNumber(100.0),
+					mcp.Min(0),
+					mcp.MultipleOf(1),
+				),
+				mcp.WithBoolean("timestamps",
+					mcp.Description("是否显示时间戳"),
+					mcp.DefaultBool(false),
+				),
+				page.WithLogBudget(),
+			),
+			Handler: t.PodLogsTool,
+		},
+
+		// Kubernetes Deployment相关工具
+		{
+			Tool: mcp.NewTool("list_deployments",
+				mcp.WithDescription("列出指定命名空间中的所有Deployment"),
+				mcp.WithString("namespace",
+					mcp.Description("要查询的命名空间, 默认为default"),
+					mcp.DefaultString("default"),
+					mcp.Pattern(dnsLabelPattern),
+					mcp.MaxLength(63),
+				),
+				page.WithPagination(),
+			),
+			Handler: t.ListDeploymentsTool,
+		},
+		{
+			Tool: mcp.NewTool("describe_deployment",
+				mcp.WithDescription("查看Deployment的详细信息"),
+				mcp.WithString("deployment_name",
+					mcp.Required(),
+					mcp.Description("要查看的Deployment名称"),
+					mcp.Pattern(dnsSubdomainPattern),
+					mcp.MaxLength(253),
+				),
+				mcp.WithString("namespace",
+					mcp.Description("Deployment所在的命名空间, 默认为default"),
+					mcp.DefaultString("default"),
+					mcp.Pattern(dnsLabelPattern),
+					mcp.MaxLength(63),
+				),
+			),
+			Handler: t.DescribeDeploymentTool,
+		},
+		{
+			Tool: mcp.NewTool("scale_deployment",
+				mcp.WithDescription("调整Deployment的副本数"),
+				mcp.WithString("deployment_name",
+					mcp.Required(),
+					mcp.Description("要调整的Deployment名称"),
+					mcp.Pattern(dnsSubdomainPattern),
+					mcp.MaxLength(253),
+				),
+				mcp.WithString("namespace",
+					mcp.Description("Deployment所在的命名空间, 默认为default"),
+					mcp.DefaultString("default"),
+					mcp.Pattern(dnsLabelPattern),
+					mcp.MaxLength(63),
+				),
+				mcp.WithNumber("replicas",
+					mcp.Required(),
+					mcp.Description("要设置的副本数"),
+					mcp.Min(0),
+					mcp.MultipleOf(1),
+				),
+				WithDryRun(),
+			),
+			Handler:  t.ScaleDeploymentTool,
+			Mutating: true,
+		},
+		{
+			Tool: mcp.NewTool("restart_deployment",
+				mcp.WithDescription("重启Deployment的所有Pod"),
+				mcp.WithString("deployment_name",
+					mcp.Required(),
+					mcp.Description("要重启的Deployment名称"),
+					mcp.Pattern(dnsSubdomainPattern),
+					mcp.MaxLength(253),
+				),
+				mcp.WithString("namespace",
+					mcp.Description("Deployment所在的命名空间, 默认为default"),
+					mcp.DefaultString("default"),
+					mcp.Pattern(dnsLabelPattern),
+					mcp.MaxLength(63),
+				),
+				WithDryRun(),
+			),
+			Handler:  t.RestartDeploymentTool,
+			Mutating: true,
+		},
+
+		// Kubernetes Service相关工具
+		{
+			Tool: mcp.NewTool("list_services",
+				mcp.WithDescription("列出指定命名空间中的所有Service"),
+				mcp.WithString("namespace",
+					mcp.Description("要查询的命名空间, 默认为default"),
+					mcp.DefaultString("default"),
+					mcp.Pattern(dnsLabelPattern),
+					mcp.MaxLength(63),
+				),
+				page.WithPagination(),
+			),
+			Handler: t.ListServicesTool,
+		},
+		{
+			Tool: mcp.NewTool("describe_service",
+				mcp.WithDescription("查看Service的详细信息"),
+				mcp.WithString("service_name",
+					mcp.Required(),
+					mcp.Description("要查看的Service名称"),
+					mcp.Pattern(dnsLabelPattern),
+					mcp.MaxLength(63),
+				),
+				mcp.WithString("namespace",
+					mcp.Description("Service所在的命名空间, 默认为default"),
+					mcp.DefaultString("default"),
+					mcp.Pattern(dnsLabelPattern),
+					mcp.MaxLength(63),
+				),
+			),
+			Handler: t.DescribeServiceTool,
+		},
+
+		// Kubernetes Namespace相关工具
+		{
+			Tool: mcp.NewTool("list_namespaces",
+				mcp.WithDescription("列出所有命名空间"),
+				page.WithPagination(),
+			),
+			Handler: t.ListNamespacesTool,
+		},
+		{
+			Tool: mcp.NewTool("describe_namespace",
+				mcp.WithDescription("查看命名空间的详细信息"),
+				mcp.WithString("namespace_name",
+					mcp.Required(),
+					mcp.Description("要查看的命名空间名称"),
+					mcp.Pattern(dnsLabelPattern),
+					mcp.MaxLength(63),
+				),
+			),
+			Handler: t.DescribeNamespaceTool,
+		},
+		{
+			Tool: mcp.NewTool("create_namespace",
+				mcp.WithDescription("创建新的命名空间"),
+				mcp.WithString("namespace_name",
+					mcp.Required(),
+					mcp.Description("要创建的命名空间名称"),
+					mcp.Pattern(dnsLabelPattern),
+					mcp.MaxLength(63),
+				),
+				WithDryRun(),
+			),
+			Handler:  t.CreateNamespaceTool,
+			Mutating: true,
+		},
+		{
+			Tool: mcp.NewTool("delete_namespace",
+				mcp.WithDescription("删除指定的命名空间"),
+				mcp.WithString("namespace_name",
+					mcp.Required(),
+					mcp.Description("要删除的命名空间名称"),
+					mcp.Pattern(dnsLabelPattern),
+					mcp.MaxLength(63),
+				),
+				WithDryRun(),
+			),
+			Handler:     t.DeleteNamespaceTool,
+			Mutating:    true,
+			Destructive: true,
+		},
+	}
+
+	// 所有资源相关的工具都可以通过 context 参数选择集群，并受命名空间限制
+	for i := range specs {
+		WithContext()(&specs[i].Tool)
+		specs[i].Handler = t.restrictNamespace(specs[i].Tool, specs[i].Handler)
+	}
+
+	specs = append(specs,
+		// kubeconfig context相关工具
+		registry.Spec{
+			Tool: mcp.NewTool("list_kube_contexts",
+				mcp.WithDescription("列出服务器可以访问的全部kubeconfig context（集群）"),
+				page.WithPagination(),
+			),
+			Handler: t.ListKubeContextsTool,
+		},
+		registry.Spec{
+			Tool: mcp.NewTool("current_kube_context",
+				mcp.WithDescription("查看未指定 context 参数时使用的kubeconfig context"),
+			),
+			Handler: t.CurrentKubeContextTool,
+		},
+	)
+
+	for i := range specs {
+		specs[i].Group = registry.GroupK8s
+	}
+	return specs
+}
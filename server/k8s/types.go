@@ -0,0 +1,138 @@
+package k8s
+
+import (
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// 以下结构体是 output_format 为 json/yaml 时各工具返回的数据结构，字段名保持稳定；
+// describe_* 工具在摘要之外直接返回Kubernetes原始对象（已去掉 managedFields）
+
+// PodSummary list_pods 返回的Pod摘要
+type PodSummary struct {
+	Name       string    `json:"name"`
+	Namespace  string    `json:"namespace"`
+	Ready      int       `json:"ready"`
+	Containers int       `json:"containers"`
+	Status     string    `json:"status"`
+	Restarts   int32     `json:"restarts"`
+	Created    time.Time `json:"created"`
+	IP         string    `json:"ip,omitempty"`
+	Node       string    `json:"node,omitempty"`
+}
+
+// DeploymentSummary list_deployments 返回的Deployment摘要
+type DeploymentSummary struct {
+	Name              string    `json:"name"`
+	Namespace         string    `json:"namespace"`
+	Replicas          int32     `json:"replicas"`
+	ReadyReplicas     int32     `json:"ready_replicas"`
+	UpdatedReplicas   int32     `json:"updated_replicas"`
+	AvailableReplicas int32     `json:"available_replicas"`
+	Created           time.Time `json:"created"`
+	Containers        []string  `json:"containers"`
+	Images            []string  `json:"images"`
+}
+
+// ServiceSummary list_services 返回的Service摘要
+type ServiceSummary struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Type        string            `json:"type"`
+	ClusterIP   string            `json:"cluster_ip"`
+	ExternalIPs []string          `json:"external_ips"`
+	Ports       []string          `json:"ports"`
+	Selector    map[string]string `json:"selector,omitempty"`
+	Created     time.Time         `json:"created"`
+}
+
+// NamespaceSummary list_namespaces 返回的命名空间摘要
+type NamespaceSummary struct {
+	Name    string    `json:"name"`
+	Status  string    `json:"status"`
+	Created time.Time `json:"created"`
+}
+
+// EventSummary 资源相关的事件
+type EventSummary struct {
+	Type     string    `json:"type"`
+	Reason   string    `json:"reason"`
+	Object   string    `json:"object"`
+	Message  string    `json:"message"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// KubeContext list_kube_contexts 和 current_kube_context 返回的context信息
+type KubeContext struct {
+	Name      string `json:"name"`
+	Cluster   string `json:"cluster,omitempty"`
+	Server    string `json:"server,omitempty"`
+	User      string `json:"user,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	// Current 未指定 context 参数时使用的context
+	Current bool `json:"current"`
+}
+
+// PodDetail describe_pod 返回的Pod详情
+type PodDetail struct {
+	Pod    *corev1.Pod    `json:"pod"`
+	Events []EventSummary `json:"events"`
+}
+
+// DeploymentDetail describe_deployment 返回的Deployment详情
+type DeploymentDetail struct {
+	Deployment *appsv1.Deployment `json:"deployment"`
+	Events     []EventSummary     `json:"events"`
+}
+
+// ServiceDetail describe_service 返回的Service详情
+type ServiceDetail struct {
+	Service   *corev1.Service   `json:"service"`
+	Endpoints *corev1.Endpoints `json:"endpoints,omitempty"`
+	Events    []EventSummary    `json:"events"`
+}
+
+// NamespaceDetail describe_namespace 返回的命名空间详情
+type NamespaceDetail struct {
+	Namespace      *corev1.Namespace      `json:"namespace"`
+	ResourceQuotas []corev1.ResourceQuota `json:"resource_quotas"`
+	LimitRanges    []corev1.LimitRange    `json:"limit_ranges"`
+	// Workloads 各类资源的数量，键为 Deployments、Services、Pods、ConfigMaps、Secrets
+	Workloads map[string]int `json:"workloads"`
+	Events    []EventSummary `json:"events"`
+}
+
+// PodLogs pod_logs 返回的日志
+type PodLogs struct {
+	Pod       string `json:"pod"`
+	Namespace string `json:"namespace"`
+	Container string `json:"container,omitempty"`
+	Tail      int64  `json:"tail"`
+	Logs      string `json:"logs"`
+	Lines     int    `json:"lines"`
+	// Truncated 日志超出预算时被截断的位置（head 或 tail），没有截断时省略
+	Truncated string `json:"truncated,omitempty"`
+	// NextCursor 继续查看被截断部分的游标
+	NextCursor string `json:"next_cursor,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+// 辅助函数：转换事件列表，列表获取失败时返回空列表
+func eventSummaries(events *corev1.EventList, err error) []EventSummary {
+	summaries := []EventSummary{}
+	if err != nil || events == nil {
+		return summaries
+	}
+	for _, event := range events.Items {
+		summaries = append(summaries, EventSummary{
+			Type:     event.Type,
+			Reason:   event.Reason,
+			Object:   event.InvolvedObject.Kind + "/" + event.InvolvedObject.Name,
+			Message:  event.Message,
+			LastSeen: event.LastTimestamp.Time,
+		})
+	}
+	return summaries
+}
@@ -0,0 +1,205 @@
+// Package mcpserver 提供可嵌入其它Go服务的Docker/Kubernetes MCP服务器
+//
+// 使用示例：
+//
+//	svr := mcpserver.NewServer(
+//		mcpserver.WithGroups(registry.GroupK8s),
+//		mcpserver.WithKubernetesClient(clientset),
+//		mcpserver.WithMiddleware(myAuditMiddleware),
+//	)
+//	server.NewSSEServer(svr).Start(":8080")
+package mcpserver
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"k8s.io/client-go/kubernetes"
+
+	"mcp-docker/server/args"
+	"mcp-docker/server/cancellation"
+	"mcp-docker/server/docker"
+	"mcp-docker/server/k8s"
+	"mcp-docker/server/output"
+	"mcp-docker/server/registry"
+)
+
+// 默认的服务器名称和版本
+const (
+	DefaultName    = "docker-k8s mcp server"
+	DefaultVersion = mcp.LATEST_PROTOCOL_VERSION
+)
+
+// ReadOnlyInstructions 只读模式下在 initialize 响应的 instructions 中告知客户端的说明
+const ReadOnlyInstructions = "该MCP服务器运行在只读模式：只提供列表、查看、日志、状态等查询类工具，" +
+	"不提供创建、启动、停止、重启、扩缩容、删除、清理等修改资源的工具，调用这些工具会被拒绝。" +
+	"需要修改资源时，请把排查结论和建议的操作交给有权限的人员执行。"
+
+// Option 配置 NewServer 创建的服务器
+type Option func(*options)
+
+type options struct {
+	name          string
+	version       string
+	groups        []string
+	readOnly      bool
+	dockerHosts   *docker.Hosts
+	k8sClient     *k8s.Client
+	timeout       time.Duration
+	timeouts      map[string]time.Duration
+	tools         []registry.Spec
+	middlewares   []registry.Middleware
+	serverOptions []server.ServerOption
+}
+
+// WithName 设置服务器名称和版本
+func WithName(name, version string) Option {
+	return func(o *options) {
+		o.name = name
+		o.version = version
+	}
+}
+
+// WithGroups 设置启用的工具分组，默认启用 docker、k8s 和 audit 全部分组
+//
+// audit 分组没有内置工具，只用于筛选通过 WithTools 注册的审计工具。
+func WithGroups(groups ...string) Option {
+	return func(o *options) {
+		o.groups = groups
+	}
+}
+
+// WithReadOnly 设置只读模式，只注册不修改资源的工具，包括通过 WithTools 注册的工具
+//
+// 修改类工具（Spec.Mutating）不会出现在工具列表中，客户端直接按名称调用时返回工具不存在的错误；
+// initialize 响应的 instructions 和 system_info 工具的结果中会说明服务器运行在只读模式。
+func WithReadOnly(readOnly bool) Option {
+	return func(o *options) {
+		o.readOnly = readOnly
+	}
+}
+
+// WithDockerClient 使用调用方提供的Docker客户端作为唯一的Docker主机，客户端由调用方负责关闭
+func WithDockerClient(cli *client.Client) Option {
+	return func(o *options) {
+		o.dockerHosts = docker.SingleHost(docker.DefaultHostName, cli.DaemonHost(), docker.WrapClient(cli))
+	}
+}
+
+// WithDockerHosts 使用多个命名的Docker主机，工具通过 host 参数选择主机
+func WithDockerHosts(hosts *docker.Hosts) Option {
+	return func(o *options) {
+		o.dockerHosts = hosts
+	}
+}
+
+// WithKubernetesClient 使用调用方提供的Kubernetes客户端
+func WithKubernetesClient(clientset kubernetes.Interface) Option {
+	return func(o *options) {
+		o.k8sClient = k8s.WrapClient(clientset)
+	}
+}
+
+// WithKubernetes 使用配置好的Kubernetes客户端池，例如限制了context或命名空间的客户端池
+func WithKubernetes(cli *k8s.Client) Option {
+	return func(o *options) {
+		o.k8sClient = cli
+	}
+}
+
+// WithTimeouts 设置工具调用的超时时间，规则见 registry.Timeouts
+func WithTimeouts(defaultTimeout time.Duration, overrides map[string]time.Duration) Option {
+	return func(o *options) {
+		o.timeout = defaultTimeout
+		o.timeouts = overrides
+	}
+}
+
+// WithTools 注册额外的工具，例如审计日志查询工具，额外的工具同样会经过中间件
+//
+// 设置了 Group 的工具只有在所属分组启用时才会注册。
+func WithTools(specs ...registry.Spec) Option {
+	return func(o *options) {
+		o.tools = append(o.tools, specs...)
+	}
+}
+
+// WithMiddleware 添加工具中间件，多次调用时按添加顺序由外到内执行
+func WithMiddleware(middlewares ...registry.Middleware) Option {
+	return func(o *options) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+// WithServerOptions 透传 mcp-go 的服务器选项
+func WithServerOptions(opts ...server.ServerOption) Option {
+	return func(o *options) {
+		o.serverOptions = append(o.serverOptions, opts...)
+	}
+}
+
+// NewServer 创建注册好工具的MCP服务器
+//
+// 所有工具都会声明 output_format 参数，调用前按工具声明的输入模式检查参数并填充默认值，
+// 并按 WithTimeouts 和工具的 Timeout 设置超时，超时或被取消的调用返回说明原因的错误结果；
+// 调用方通过 WithMiddleware 添加的中间件位于这些中间件的外层。
+// 工具重名或分组未知属于编程错误，会直接 panic。
+func NewServer(opts ...Option) *server.MCPServer {
+	o := &options{
+		name:    DefaultName,
+		version: DefaultVersion,
+		groups:  []string{registry.GroupDocker, registry.GroupK8s, registry.GroupAudit},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	specs, err := o.specs()
+	if err != nil {
+		panic(err)
+	}
+
+	// 调用方通过 WithServerOptions 设置的 instructions 优先
+	serverOptions := o.serverOptions
+	if o.readOnly {
+		serverOptions = append([]server.ServerOption{server.WithInstructions(ReadOnlyInstructions)}, serverOptions...)
+	}
+	svr := server.NewMCPServer(o.name, o.version, serverOptions...)
+	middlewares := append(o.middlewares, output.Middleware, args.Middleware, registry.Timeouts(o.timeout, o.timeouts), cancellation.Middleware)
+	registry.RegisterAll(svr, specs, middlewares...)
+	return svr
+}
+
+// specs 返回启用的分组中的全部工具，只读模式下不包括修改类工具
+func (o *options) specs() ([]registry.Spec, error) {
+	var specs []registry.Spec
+	enabled := make(map[string]bool, len(o.groups))
+	for _, group := range o.groups {
+		enabled[group] = true
+		switch group {
+		case registry.GroupDocker:
+			specs = append(specs, docker.NewToolset(o.dockerHosts, docker.WithReadOnly(o.readOnly)).Tools()...)
+		case registry.GroupK8s:
+			specs = append(specs, k8s.NewToolset(o.k8sClient).Tools()...)
+		case registry.GroupAudit:
+		default:
+			return nil, fmt.Errorf("未知的工具分组: %s", group)
+		}
+	}
+	for _, spec := range o.tools {
+		if spec.Group == "" || enabled[spec.Group] {
+			specs = append(specs, spec)
+		}
+	}
+	if o.readOnly {
+		specs = slices.DeleteFunc(specs, func(spec registry.Spec) bool { return spec.Mutating })
+	}
+	if err := registry.Validate(specs); err != nil {
+		return nil, err
+	}
+	return specs, nil
+}
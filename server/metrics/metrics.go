@@ -0,0 +1,179 @@
+// Package metrics 以Prometheus格式导出工具调用的指标
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"mcp-docker/server/registry"
+	"mcp-docker/server/toolerror"
+)
+
+// DefaultPath 默认的指标路径
+const DefaultPath = "/metrics"
+
+// namespace 所有指标名称的前缀
+const namespace = "mcp"
+
+// Metrics 服务器的全部指标，使用独立的注册表，不影响嵌入方的默认注册表
+type Metrics struct {
+	registry *prometheus.Registry
+
+	toolCalls     *prometheus.CounterVec
+	toolErrors    *prometheus.CounterVec
+	toolDuration  *prometheus.HistogramVec
+	responseBytes *prometheus.HistogramVec
+
+	backendRequests *prometheus.CounterVec
+	backendErrors   *prometheus.CounterVec
+
+	sseSessions prometheus.Gauge
+}
+
+// New 创建并注册全部指标，同时导出Go运行时和进程指标
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		toolCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tool_calls_total",
+			Help:      "工具调用次数",
+		}, []string{"tool"}),
+		toolErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tool_errors_total",
+			Help:      "失败的工具调用次数，包括参数错误、权限拒绝和后端API错误",
+		}, []string{"tool"}),
+		toolDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "tool_duration_seconds",
+			Help:      "工具调用耗时",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+		}, []string{"tool"}),
+		responseBytes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "tool_response_bytes",
+			Help:      "工具返回结果序列化后的字节数",
+			Buckets:   prometheus.ExponentialBuckets(256, 4, 9),
+		}, []string{"tool"}),
+		backendRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "backend_requests_total",
+			Help:      "访问Docker或Kubernetes API的工具调用次数",
+		}, []string{"backend"}),
+		backendErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "backend_errors_total",
+			Help:      "Docker或Kubernetes API返回错误或无法连接的工具调用次数",
+		}, []string{"backend"}),
+		sseSessions: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "sse_sessions_active",
+			Help:      "当前打开的SSE连接数",
+		}),
+	}
+
+	m.registry.MustRegister(
+		m.toolCalls,
+		m.toolErrors,
+		m.toolDuration,
+		m.responseBytes,
+		m.backendRequests,
+		m.backendErrors,
+		m.sseSessions,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler 返回导出指标的HTTP处理函数
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// TrackHTTPSessions 导出 streamable HTTP 的会话数，count 在每次抓取指标时调用
+func (m *Metrics) TrackHTTPSessions(count func() int) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_sessions_active",
+		Help:      "当前的streamable HTTP会话数",
+	}, func() float64 {
+		return float64(count())
+	}))
+}
+
+// Middleware 以工具中间件的形式记录调用次数、错误、耗时和返回的字节数
+//
+// 应作为最外层的中间件，这样被权限检查拒绝的调用也会被统计。
+// docker 和 k8s 分组的工具返回 error 表示获取客户端或调用API失败，计入后端错误。
+func (m *Metrics) Middleware(spec *registry.Spec, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	tool := spec.Tool.Name
+	calls := m.toolCalls.WithLabelValues(tool)
+	errors := m.toolErrors.WithLabelValues(tool)
+	duration := m.toolDuration.WithLabelValues(tool)
+	responseBytes := m.responseBytes.WithLabelValues(tool)
+
+	var backendRequests, backendErrors prometheus.Counter
+	if backend := backendOf(spec.Group); backend != "" {
+		backendRequests = m.backendRequests.WithLabelValues(backend)
+		backendErrors = m.backendErrors.WithLabelValues(backend)
+	}
+
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		result, err := next(ctx, request)
+
+		calls.Inc()
+		duration.Observe(time.Since(start).Seconds())
+		if err != nil || (result != nil && result.IsError) {
+			errors.Inc()
+		}
+		if result != nil {
+			if data, marshalErr := json.Marshal(result); marshalErr == nil {
+				responseBytes.Observe(float64(len(data)))
+			}
+		}
+		if backendRequests != nil {
+			backendRequests.Inc()
+			if err != nil || toolerror.IsBackendError(toolerror.CodeOfResult(result)) {
+				backendErrors.Inc()
+			}
+		}
+		return result, err
+	}
+}
+
+// TrackSSE 统计打开的SSE连接，sseEndpoint 为SSE服务器建立事件流的路径
+func (m *Metrics) TrackSSE(sseEndpoint string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != sseEndpoint {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// 事件流在处理函数返回前一直保持打开
+		m.sseSessions.Inc()
+		defer m.sseSessions.Dec()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// backendOf 返回工具分组访问的后端，其他分组返回空字符串
+func backendOf(group string) string {
+	switch group {
+	case registry.GroupDocker:
+		return "docker"
+	case registry.GroupK8s:
+		return "kubernetes"
+	default:
+		return ""
+	}
+}
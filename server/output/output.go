@@ -0,0 +1,115 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"sigs.k8s.io/yaml"
+
+	"mcp-docker/server/registry"
+)
+
+// FormatArg 选择输出格式的参数名
+const FormatArg = "output_format"
+
+// 支持的输出格式
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// ErrUnsupportedFormat 调用方要求了不支持的输出格式
+var ErrUnsupportedFormat = errors.New("不支持的输出格式")
+
+// ActionResult 修改类工具（启动、删除、扩缩等）的结构化结果
+type ActionResult struct {
+	// Action 工具名称，例如 start_container
+	Action string `json:"action"`
+	// Target 操作对象，Kubernetes资源为 "命名空间/名称"
+	Target string `json:"target"`
+	// DryRun 为 true 时表示只是预演，没有做任何修改
+	DryRun bool `json:"dry_run"`
+	// Message 与文本输出相同的说明信息
+	Message string `json:"message"`
+	// Details 预演时展示的附加信息，例如 create_container 的最终配置
+	Details interface{} `json:"details,omitempty"`
+}
+
+// WithFormat 为工具声明输出格式参数
+func WithFormat() mcp.ToolOption {
+	return mcp.WithString(FormatArg,
+		mcp.Description("输出格式: text 为适合阅读的文本，json/yaml 为结构稳定的机器可读格式"),
+		mcp.Enum(FormatText, FormatJSON, FormatYAML),
+		mcp.DefaultString(FormatText),
+	)
+}
+
+// Middleware 为每个工具声明输出格式参数
+func Middleware(spec *registry.Spec, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	WithFormat()(&spec.Tool)
+	return next
+}
+
+// FormatOf 返回调用方要求的输出格式
+func FormatOf(request mcp.CallToolRequest) (string, error) {
+	format, _ := request.Params.Arguments[FormatArg].(string)
+	switch format {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON, FormatYAML:
+		return format, nil
+	default:
+		return "", fmt.Errorf("%w %q，可选值: text、json、yaml", ErrUnsupportedFormat, format)
+	}
+}
+
+// Result 按调用方要求的格式返回结果，text 格式返回 text，其余格式序列化 data
+//
+// 输出格式不支持或序列化失败时返回错误，工具处理函数直接返回即可，由参数中间件转换为带错误码的错误结果。
+func Result(request mcp.CallToolRequest, text string, data interface{}) (*mcp.CallToolResult, error) {
+	format, err := FormatOf(request)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatJSON:
+		encoded, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("序列化JSON输出失败: %w", err)
+		}
+		return mcp.NewToolResultText(string(encoded)), nil
+	case FormatYAML:
+		encoded, err := yaml.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("序列化YAML输出失败: %w", err)
+		}
+		return mcp.NewToolResultText(string(encoded)), nil
+	default:
+		return mcp.NewToolResultText(text), nil
+	}
+}
+
+// Action 返回修改类工具的执行结果
+func Action(request mcp.CallToolRequest, target, message string) (*mcp.CallToolResult, error) {
+	return Result(request, message, ActionResult{
+		Action:  request.Params.Name,
+		Target:  target,
+		Message: message,
+	})
+}
+
+// DryRun 返回修改类工具的预演结果，details 可以为空
+func DryRun(request mcp.CallToolRequest, target, preview string, details interface{}) (*mcp.CallToolResult, error) {
+	return Result(request, preview, ActionResult{
+		Action:  request.Params.Name,
+		Target:  target,
+		DryRun:  true,
+		Message: preview,
+		Details: details,
+	})
+}
@@ -0,0 +1,363 @@
+package page
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-docker/server/args"
+)
+
+// 日志预算参数
+const (
+	MaxBytesArg = "max_bytes"
+	TruncateArg = "truncate"
+)
+
+// 日志默认和最多返回的字节数
+const (
+	DefaultMaxBytes = 64 * 1024
+	MaxMaxBytes     = 1024 * 1024
+)
+
+// 超出预算时截断的位置
+const (
+	// TruncateHead 省略较早的日志，保留最新的部分
+	TruncateHead = "head"
+	// TruncateTail 省略较新的日志，保留最早的部分
+	TruncateTail = "tail"
+)
+
+// truncatedLineSuffix 单行超过字节预算时附加在截断处的说明
+const truncatedLineSuffix = "…（该行过长，已截断）"
+
+// LogRequest 一次日志调用的预算和范围
+type LogRequest struct {
+	// MaxLines 最多返回的行数，0 表示不限制
+	MaxLines int
+	// MaxBytes 最多返回的字节数
+	MaxBytes int
+	// Truncate 超出预算时截断的位置，为 TruncateHead 或 TruncateTail
+	Truncate string
+	// Since 只返回不早于该时间的日志，来自 TruncateTail 的游标
+	Since time.Time
+	// Until 只返回不晚于该时间的日志，来自 TruncateHead 的游标
+	Until time.Time
+
+	// skip 时间戳与游标边界相同、上一页已经返回过的行数，读取时跳过这些行
+	skip int
+	tool string
+}
+
+// Logs 按预算读取的日志
+type Logs struct {
+	Text  string
+	Lines int
+	// Truncated 日志被截断的位置，没有截断时为空
+	Truncated string
+	// Next 获取被截断部分的游标
+	Next string
+	// Message 日志被截断时的说明
+	Message string
+}
+
+// logLine 一行日志，Time 为日志行开头的时间戳，无法解析时为零值
+type logLine struct {
+	Time time.Time
+	Text string
+}
+
+// WithLogBudget 为日志类工具声明 max_bytes、truncate 和 cursor 参数，行数预算由各工具的 tail 参数给出
+func WithLogBudget() mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		mcp.WithNumber(MaxBytesArg,
+			mcp.Description(fmt.Sprintf("最多返回的日志字节数，默认 %d，最大 %d", DefaultMaxBytes, MaxMaxBytes)),
+			mcp.DefaultNumber(DefaultMaxBytes),
+			mcp.Min(1024),
+			mcp.Max(MaxMaxBytes),
+			mcp.MultipleOf(1),
+		)(t)
+		mcp.WithString(TruncateArg,
+			mcp.Description("日志超出 tail 或 max_bytes 时截断的位置: head 省略较早的日志、保留最新的部分，tail 省略较新的日志、保留最早的部分"),
+			mcp.Enum(TruncateHead, TruncateTail),
+			mcp.DefaultString(TruncateHead),
+		)(t)
+		withCursor("上一次调用结果中的 next_cursor，用于继续查看被截断的日志：head 截断时返回更早的日志，tail 截断时返回之后的日志")(t)
+	}
+}
+
+// LogsOf 返回调用方请求的日志预算，maxLines 为工具 tail 参数给出的行数
+//
+// 传入游标时按游标继续读取，截断位置与产生游标的那次调用相同。
+func LogsOf(request mcp.CallToolRequest, maxLines int) (LogRequest, error) {
+	var params struct {
+		MaxBytes int    `json:"max_bytes"`
+		Truncate string `json:"truncate"`
+		Cursor   string `json:"cursor"`
+	}
+	if err := args.Decode(request, &params); err != nil {
+		return LogRequest{}, err
+	}
+
+	r := LogRequest{
+		MaxLines: max(maxLines, 0),
+		MaxBytes: params.MaxBytes,
+		Truncate: params.Truncate,
+		tool:     request.Params.Name,
+	}
+	if r.MaxBytes <= 0 {
+		r.MaxBytes = DefaultMaxBytes
+	}
+	if r.MaxBytes > MaxMaxBytes {
+		r.MaxBytes = MaxMaxBytes
+	}
+	if r.Truncate != TruncateTail {
+		r.Truncate = TruncateHead
+	}
+	if params.Cursor == "" {
+		return r, nil
+	}
+
+	c, err := decode(request.Params.Name, params.Cursor)
+	if err != nil {
+		return LogRequest{}, err
+	}
+	switch {
+	case c.Until != "":
+		r.Truncate = TruncateHead
+		r.Until, err = time.Parse(time.RFC3339Nano, c.Until)
+	case c.Since != "":
+		r.Truncate = TruncateTail
+		r.Since, err = time.Parse(time.RFC3339Nano, c.Since)
+	default:
+		err = errors.New("游标中没有日志的位置")
+	}
+	if err != nil {
+		return LogRequest{}, invalidCursor(request.Params.Name, "游标 %q 无效: %v", params.Cursor, err)
+	}
+	r.skip = c.Skip
+	return r, nil
+}
+
+// Continued 判断本次调用是否来自游标
+func (r LogRequest) Continued() bool {
+	return !r.Since.IsZero() || !r.Until.IsZero()
+}
+
+// Read 按预算读取日志，每行日志都应以 RFC3339Nano 格式的时间戳开头，timestamps 为 false 时返回的日志去掉时间戳
+//
+// tailed 表示后端已经按 MaxLines 只返回了最后几行，读满 MaxLines 行时说明可能还有更早的日志。
+// 截断位置为 tail 时读满预算即停止读取，调用方关闭日志流即可。
+//
+// 游标的边界包含与边界时间戳相同的日志行，多行日志共用一个时间戳时不会在两页之间遗漏；
+// 其中上一页已经返回过的行由游标记录的行数跳过，不会重复返回。
+func (r LogRequest) Read(reader io.Reader, timestamps, tailed bool) (Logs, error) {
+	var (
+		lines     []logLine
+		size      int
+		truncated bool
+		read      int
+		// skipped 已跳过的、时间戳等于 Since 的行数
+		skipped int
+		// boundary 时间戳等于 Until 的行，位于范围的末尾，读完后去掉上一页返回过的最后 skip 行
+		boundary []logLine
+	)
+
+	// accept 把一行日志加入结果，截断位置为 tail 且超出预算时返回 false
+	accept := func(line logLine) bool {
+		read++
+		line.Text = r.fit(line.Text, timestamps)
+		cost := r.cost(line, timestamps)
+
+		if r.Truncate == TruncateTail {
+			if (r.MaxLines > 0 && len(lines) >= r.MaxLines) || size+cost > r.MaxBytes {
+				truncated = true
+				return false
+			}
+			lines = append(lines, line)
+			size += cost
+			return true
+		}
+
+		lines = append(lines, line)
+		size += cost
+		for len(lines) > 0 && ((r.MaxLines > 0 && len(lines) > r.MaxLines) || size > r.MaxBytes) {
+			size -= r.cost(lines[0], timestamps)
+			lines = lines[1:]
+			truncated = true
+		}
+		return true
+	}
+
+	buffered := bufio.NewReader(reader)
+	for {
+		text, err := buffered.ReadString('\n')
+		if text != "" {
+			line := parseLine(strings.TrimSuffix(text, "\n"))
+			switch {
+			case !r.matches(line):
+			case !r.Since.IsZero() && line.Time.Equal(r.Since) && skipped < r.skip:
+				skipped++
+			case !r.Until.IsZero() && line.Time.Equal(r.Until):
+				boundary = append(boundary, line)
+			default:
+				if !accept(line) {
+					err = io.EOF
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Logs{}, err
+		}
+	}
+	for _, line := range boundary[:max(len(boundary)-r.skip, 0)] {
+		accept(line)
+	}
+
+	// 后端只返回了最后 MaxLines 行，读满时更早的日志可能被省略了
+	if r.Truncate == TruncateHead && tailed && r.MaxLines > 0 && read >= r.MaxLines {
+		truncated = true
+	}
+
+	logs := Logs{Lines: len(lines)}
+	var text strings.Builder
+	for _, line := range lines {
+		if timestamps && !line.Time.IsZero() {
+			text.WriteString(line.Time.Format(time.RFC3339Nano))
+			text.WriteString(" ")
+		}
+		text.WriteString(line.Text)
+		text.WriteString("\n")
+	}
+	logs.Text = text.String()
+
+	if truncated && len(lines) > 0 {
+		logs.Truncated = r.Truncate
+		c := cursor{Tool: r.tool}
+		if r.Truncate == TruncateHead {
+			edge := lines[0].Time
+			c.Until = edge.Format(time.RFC3339Nano)
+			c.Skip = sameTime(lines, edge)
+			if edge.Equal(r.Until) {
+				c.Skip += r.skip
+			}
+		} else {
+			edge := lines[len(lines)-1].Time
+			c.Since = edge.Format(time.RFC3339Nano)
+			c.Skip = sameTime(lines, edge)
+			if edge.Equal(r.Since) {
+				c.Skip += r.skip
+			}
+		}
+		// 没有时间戳的日志无法继续读取
+		if !lines[0].Time.IsZero() && !lines[len(lines)-1].Time.IsZero() {
+			logs.Next = encode(c)
+		}
+		logs.Message = r.message(logs)
+	}
+	return logs, nil
+}
+
+// String 返回 text 格式的日志，截断说明放在被截断的一端
+func (l Logs) String() string {
+	switch l.Truncated {
+	case TruncateHead:
+		return "[" + l.Message + "]\n" + l.Text
+	case TruncateTail:
+		return l.Text + "[" + l.Message + "]\n"
+	}
+	return l.Text
+}
+
+// message 返回日志被截断时的说明
+func (r LogRequest) message(logs Logs) string {
+	budget := fmt.Sprintf("%d 字节", r.MaxBytes)
+	if r.MaxLines > 0 {
+		budget = fmt.Sprintf("%d 行或 %s", r.MaxLines, budget)
+	}
+
+	var message string
+	if logs.Truncated == TruncateHead {
+		message = fmt.Sprintf("日志已截断: 只显示了最新的 %d 行（每次最多 %s），更早的日志已省略", logs.Lines, budget)
+		if logs.Next != "" {
+			message += fmt.Sprintf("，使用 cursor=%q 查看更早的日志", logs.Next)
+		}
+	} else {
+		message = fmt.Sprintf("日志已截断: 只显示了最早的 %d 行（每次最多 %s），之后的日志已省略", logs.Lines, budget)
+		if logs.Next != "" {
+			message += fmt.Sprintf("，使用 cursor=%q 查看之后的日志", logs.Next)
+		}
+	}
+	return message
+}
+
+// matches 判断日志行是否在游标给出的范围内，范围包含边界
+func (r LogRequest) matches(line logLine) bool {
+	if line.Time.IsZero() {
+		return true
+	}
+	if !r.Since.IsZero() && line.Time.Before(r.Since) {
+		return false
+	}
+	if !r.Until.IsZero() && line.Time.After(r.Until) {
+		return false
+	}
+	return true
+}
+
+// sameTime 返回结果中时间戳为 ts 的行数
+func sameTime(lines []logLine, ts time.Time) int {
+	count := 0
+	for _, line := range lines {
+		if line.Time.Equal(ts) {
+			count++
+		}
+	}
+	return count
+}
+
+// fit 截断超过字节预算的单行日志，保证至少能返回一行
+func (r LogRequest) fit(text string, timestamps bool) string {
+	limit := r.MaxBytes - len(truncatedLineSuffix) - 1
+	if timestamps {
+		limit -= len(time.RFC3339Nano) + 1
+	}
+	if len(text) <= limit || limit <= 0 {
+		return text
+	}
+	for limit > 0 && !utf8.RuneStart(text[limit]) {
+		limit--
+	}
+	return text[:limit] + truncatedLineSuffix
+}
+
+// cost 返回日志行在结果中占用的字节数
+func (r LogRequest) cost(line logLine, timestamps bool) int {
+	cost := len(line.Text) + 1
+	if timestamps && !line.Time.IsZero() {
+		cost += len(line.Time.Format(time.RFC3339Nano)) + 1
+	}
+	return cost
+}
+
+// parseLine 解析日志行开头的时间戳
+func parseLine(text string) logLine {
+	stamp, rest, ok := strings.Cut(text, " ")
+	if !ok {
+		stamp, rest = text, ""
+	}
+	ts, err := time.Parse(time.RFC3339Nano, stamp)
+	if err != nil {
+		return logLine{Text: text}
+	}
+	return logLine{Time: ts, Text: rest}
+}
@@ -0,0 +1,183 @@
+package page
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// logReader 把日志行拼接为日志流
+func logReader(lines ...string) io.Reader {
+	return strings.NewReader(strings.Join(lines, "\n") + "\n")
+}
+
+// sharedTimestampLogs 多行共用时间戳的日志，第 2-4 行和第 5-6 行分别共用一个时间戳
+var sharedTimestampLogs = []string{
+	"2025-01-01T00:00:01Z a",
+	"2025-01-01T00:00:02Z b",
+	"2025-01-01T00:00:02Z c",
+	"2025-01-01T00:00:02Z d",
+	"2025-01-01T00:00:03.5Z e",
+	"2025-01-01T00:00:03.5Z f",
+	"2025-01-01T00:00:04Z g",
+}
+
+func TestReadTruncation(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments map[string]interface{}
+		maxLines  int
+		tailed    bool
+		lines     []string
+		want      string
+		truncated string
+	}{
+		{
+			name:      "未超出预算",
+			maxLines:  10,
+			lines:     []string{"2025-01-01T00:00:01Z a", "2025-01-01T00:00:02Z b"},
+			want:      "a\nb\n",
+			truncated: "",
+		},
+		{
+			name:      "head截断保留最新的行",
+			maxLines:  2,
+			lines:     []string{"2025-01-01T00:00:01Z a", "2025-01-01T00:00:02Z b", "2025-01-01T00:00:03Z c"},
+			want:      "b\nc\n",
+			truncated: TruncateHead,
+		},
+		{
+			name:      "tail截断保留最早的行",
+			arguments: map[string]interface{}{TruncateArg: TruncateTail},
+			maxLines:  2,
+			lines:     []string{"2025-01-01T00:00:01Z a", "2025-01-01T00:00:02Z b", "2025-01-01T00:00:03Z c"},
+			want:      "a\nb\n",
+			truncated: TruncateTail,
+		},
+		{
+			name:      "后端按行数返回且读满时视为截断",
+			maxLines:  2,
+			tailed:    true,
+			lines:     []string{"2025-01-01T00:00:02Z b", "2025-01-01T00:00:03Z c"},
+			want:      "b\nc\n",
+			truncated: TruncateHead,
+		},
+		{
+			name:      "后端按行数返回但未读满",
+			maxLines:  3,
+			tailed:    true,
+			lines:     []string{"2025-01-01T00:00:02Z b", "2025-01-01T00:00:03Z c"},
+			want:      "b\nc\n",
+			truncated: "",
+		},
+		{
+			name:      "超出字节预算",
+			arguments: map[string]interface{}{MaxBytesArg: 1024},
+			lines: func() []string {
+				var lines []string
+				for i := 0; i < 20; i++ {
+					lines = append(lines, "2025-01-01T00:00:01Z "+strings.Repeat("x", 99))
+				}
+				return lines
+			}(),
+			want:      strings.Repeat(strings.Repeat("x", 99)+"\n", 10),
+			truncated: TruncateHead,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := LogsOf(newRequest("pod_logs", tt.arguments), tt.maxLines)
+			if err != nil {
+				t.Fatalf("解析日志预算失败: %v", err)
+			}
+			logs, err := r.Read(logReader(tt.lines...), false, tt.tailed)
+			if err != nil {
+				t.Fatalf("读取日志失败: %v", err)
+			}
+			if logs.Text != tt.want {
+				t.Errorf("日志应为 %q，实际为 %q", tt.want, logs.Text)
+			}
+			if logs.Truncated != tt.truncated {
+				t.Errorf("截断位置应为 %q，实际为 %q", tt.truncated, logs.Truncated)
+			}
+			if (logs.Truncated != "") != (logs.Next != "") {
+				t.Errorf("只有截断时才应返回游标，实际 truncated=%q next=%q", logs.Truncated, logs.Next)
+			}
+			if logs.Truncated != "" && !strings.Contains(logs.String(), logs.Message) {
+				t.Error("text 输出应包含截断说明")
+			}
+		})
+	}
+}
+
+func TestReadKeepsTimestamps(t *testing.T) {
+	r, err := LogsOf(newRequest("pod_logs", nil), 0)
+	if err != nil {
+		t.Fatalf("解析日志预算失败: %v", err)
+	}
+	logs, err := r.Read(logReader("2025-01-01T00:00:01.5Z a", "no timestamp"), true, false)
+	if err != nil {
+		t.Fatalf("读取日志失败: %v", err)
+	}
+	if want := "2025-01-01T00:00:01.5Z a\nno timestamp\n"; logs.Text != want {
+		t.Errorf("日志应为 %q，实际为 %q", want, logs.Text)
+	}
+}
+
+// readAllPages 按游标逐页读取全部日志，返回按时间顺序排列的日志内容
+func readAllPages(t *testing.T, truncate string, maxLines int, lines []string) []string {
+	t.Helper()
+
+	var (
+		pages  [][]string
+		cursor string
+	)
+	for i := 0; ; i++ {
+		if i > len(lines) {
+			t.Fatal("游标没有前进")
+		}
+		arguments := map[string]interface{}{TruncateArg: truncate}
+		if cursor != "" {
+			arguments[CursorArg] = cursor
+		}
+		r, err := LogsOf(newRequest("container_logs", arguments), maxLines)
+		if err != nil {
+			t.Fatalf("解析游标失败: %v", err)
+		}
+		// 后端按游标的时间过滤，这里总是返回全部日志，由 Read 按边界过滤
+		logs, err := r.Read(logReader(lines...), false, false)
+		if err != nil {
+			t.Fatalf("读取日志失败: %v", err)
+		}
+		pages = append(pages, strings.Fields(logs.Text))
+		if logs.Next == "" {
+			break
+		}
+		cursor = logs.Next
+	}
+
+	var all []string
+	if truncate == TruncateHead {
+		// head 截断的游标返回更早的日志
+		for i := len(pages) - 1; i >= 0; i-- {
+			all = append(all, pages[i]...)
+		}
+	} else {
+		for _, page := range pages {
+			all = append(all, page...)
+		}
+	}
+	return all
+}
+
+func TestReadCursorBoundary(t *testing.T) {
+	want := "a b c d e f g"
+	for _, truncate := range []string{TruncateHead, TruncateTail} {
+		for _, maxLines := range []int{1, 2, 3} {
+			got := strings.Join(readAllPages(t, truncate, maxLines, sharedTimestampLogs), " ")
+			if got != want {
+				t.Errorf("truncate=%s tail=%d 逐页读取应不重复不遗漏地得到 %q，实际为 %q", truncate, maxLines, want, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,214 @@
+// Package page 为列表类工具提供 limit/cursor 分页，为日志类工具提供字节数和行数预算
+//
+// 返回给模型的内容越大，占用的上下文越多。列表类工具每次最多返回 limit 项，
+// 日志类工具最多返回 max_bytes 字节和 tail 行，超出的部分会被截断，
+// 结果中会明确说明截断的位置，并给出获取后续内容的游标。
+package page
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-docker/server/args"
+	"mcp-docker/server/output"
+)
+
+// 分页参数
+const (
+	LimitArg  = "limit"
+	CursorArg = "cursor"
+)
+
+// 每页默认和最多返回的项数
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// Page 一次列表调用请求的分页范围
+type Page struct {
+	// Limit 本页最多返回的项数
+	Limit int
+	// Offset 本页第一项在完整列表中的序号，从0开始
+	Offset int
+	// Continue Kubernetes API 返回的 continue 令牌，第一页为空
+	Continue string
+
+	tool string
+}
+
+// Info 本页的分页信息
+type Info struct {
+	// Total 结果总数，未知时为 -1
+	Total int
+	// Start 本页第一项的序号，从0开始
+	Start int
+	// Count 本页的项数
+	Count int
+	// Next 下一页的游标，没有更多结果时为空
+	Next string
+}
+
+// List 列表类工具在 json/yaml 格式下返回的结构
+type List struct {
+	Items interface{} `json:"items"`
+	// Total 结果总数，Kubernetes资源无法得知总数时省略
+	Total *int `json:"total,omitempty"`
+	// NextCursor 下一页的游标，作为 cursor 参数传入即可获取下一页，没有更多结果时省略
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Message 结果被分页时的说明
+	Message string `json:"message,omitempty"`
+}
+
+// cursor 游标的内容，编码为 base64 的JSON，调用方应把游标当作不透明的字符串
+type cursor struct {
+	Tool     string `json:"tool"`
+	Offset   int    `json:"offset,omitempty"`
+	Continue string `json:"continue,omitempty"`
+	Since    string `json:"since,omitempty"`
+	Until    string `json:"until,omitempty"`
+	// Skip 时间戳等于 Since 或 Until 的日志中已经返回过的行数
+	Skip int `json:"skip,omitempty"`
+}
+
+// WithPagination 为列表类工具声明 limit 和 cursor 参数
+func WithPagination() mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		mcp.WithNumber(LimitArg,
+			mcp.Description(fmt.Sprintf("每页最多返回的项数，默认 %d，最大 %d", DefaultLimit, MaxLimit)),
+			mcp.DefaultNumber(DefaultLimit),
+			mcp.Min(1),
+			mcp.Max(MaxLimit),
+			mcp.MultipleOf(1),
+		)(t)
+		withCursor("上一页结果中的 next_cursor，用于获取下一页；不传时返回第一页")(t)
+	}
+}
+
+// Of 返回调用方请求的分页范围，游标无效时返回 *args.Error
+func Of(request mcp.CallToolRequest) (Page, error) {
+	var params struct {
+		Limit  int    `json:"limit"`
+		Cursor string `json:"cursor"`
+	}
+	if err := args.Decode(request, &params); err != nil {
+		return Page{}, err
+	}
+
+	p := Page{Limit: params.Limit, tool: request.Params.Name}
+	if p.Limit <= 0 {
+		p.Limit = DefaultLimit
+	}
+	if p.Limit > MaxLimit {
+		p.Limit = MaxLimit
+	}
+	if params.Cursor == "" {
+		return p, nil
+	}
+
+	c, err := decode(request.Params.Name, params.Cursor)
+	if err != nil {
+		return Page{}, err
+	}
+	p.Offset, p.Continue = c.Offset, c.Continue
+	return p, nil
+}
+
+// Slice 返回完整列表中属于本页的部分，用于无法在服务端分页的Docker列表
+//
+// 两次调用之间列表发生变化时，后续页可能重复或遗漏个别项，调用方应先按稳定的顺序排序。
+func Slice[T any](items []T, p Page) ([]T, Info) {
+	start := min(p.Offset, len(items))
+	end := start + min(p.Limit, len(items)-start)
+	info := Info{Total: len(items), Start: start, Count: end - start}
+	if end < len(items) {
+		info.Next = encode(cursor{Tool: p.tool, Offset: end})
+	}
+	return items[start:end], info
+}
+
+// Continued 返回Kubernetes API分页结果的分页信息
+//
+// next 为列表的 continue 令牌，remaining 为列表的 remainingItemCount，API服务器没有返回时为 nil。
+func (p Page) Continued(count int, next string, remaining *int64) Info {
+	info := Info{Total: -1, Start: p.Offset, Count: count}
+	if next != "" {
+		info.Next = encode(cursor{Tool: p.tool, Offset: p.Offset + count, Continue: next})
+		if remaining != nil {
+			info.Total = p.Offset + count + int(*remaining)
+		}
+	} else {
+		info.Total = p.Offset + count
+	}
+	return info
+}
+
+// Message 返回结果被分页时的说明，只有一页时返回空字符串
+func (i Info) Message() string {
+	if i.Next == "" && i.Start == 0 {
+		return ""
+	}
+	if i.Count == 0 {
+		return "本页没有结果，游标之后已经没有更多的项"
+	}
+
+	span := fmt.Sprintf("第 %d-%d 项", i.Start+1, i.Start+i.Count)
+	if i.Total >= 0 {
+		span += fmt.Sprintf("（共 %d 项）", i.Total)
+	}
+	if i.Next == "" {
+		return fmt.Sprintf("已显示%s，这是最后一页", span)
+	}
+	return fmt.Sprintf("只显示了%s，还有更多结果，使用 cursor=%q 获取下一页", span, i.Next)
+}
+
+// Result 按调用方要求的格式返回一页结果，text 格式在末尾附上分页说明，json/yaml 格式返回 List
+func Result(request mcp.CallToolRequest, text string, items interface{}, info Info) (*mcp.CallToolResult, error) {
+	list := List{Items: items, NextCursor: info.Next, Message: info.Message()}
+	if info.Total >= 0 {
+		total := info.Total
+		list.Total = &total
+	}
+	if list.Message != "" {
+		text += "\n[" + list.Message + "]\n"
+	}
+	return output.Result(request, text, list)
+}
+
+// withCursor 声明 cursor 参数
+func withCursor(description string) mcp.ToolOption {
+	return mcp.WithString(CursorArg, mcp.Description(description))
+}
+
+// encode 把游标编码为字符串
+func encode(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decode 解析游标并检查它是否属于当前工具
+func decode(tool, value string) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil || c.Tool == "" {
+		return cursor{}, invalidCursor(tool, "游标 %q 无效，请使用上一次调用结果中返回的游标", value)
+	}
+	if c.Tool != tool {
+		return cursor{}, invalidCursor(tool, "游标属于工具 %s，不能用于 %s", c.Tool, tool)
+	}
+	if c.Offset < 0 || c.Skip < 0 {
+		return cursor{}, invalidCursor(tool, "游标 %q 无效，请使用上一次调用结果中返回的游标", value)
+	}
+	return c, nil
+}
+
+// invalidCursor 构造 cursor 参数错误
+func invalidCursor(tool, format string, a ...interface{}) error {
+	return &args.Error{Tool: tool, Problems: []args.Problem{{Argument: CursorArg, Message: fmt.Sprintf(format, a...)}}}
+}
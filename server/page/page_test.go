@@ -0,0 +1,166 @@
+package page
+
+import (
+	"encoding/base64"
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"mcp-docker/server/args"
+)
+
+// newRequest 构造工具调用请求
+func newRequest(tool string, arguments map[string]interface{}) mcp.CallToolRequest {
+	var request mcp.CallToolRequest
+	request.Params.Name = tool
+	request.Params.Arguments = arguments
+	return request
+}
+
+func TestSliceRoundTrip(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e"}
+
+	var (
+		got    []string
+		cursor string
+		pages  int
+	)
+	for {
+		arguments := map[string]interface{}{LimitArg: 2}
+		if cursor != "" {
+			arguments[CursorArg] = cursor
+		}
+		p, err := Of(newRequest("list_containers", arguments))
+		if err != nil {
+			t.Fatalf("解析分页参数失败: %v", err)
+		}
+		page, info := Slice(items, p)
+		got = append(got, page...)
+		pages++
+		if info.Total != len(items) || info.Start != p.Offset || info.Count != len(page) {
+			t.Errorf("第 %d 页的分页信息不正确: %+v", pages, info)
+		}
+		if info.Next == "" {
+			break
+		}
+		if pages > len(items) {
+			t.Fatal("游标没有前进")
+		}
+		cursor = info.Next
+	}
+
+	if pages != 3 {
+		t.Errorf("5 项每页 2 项应分为 3 页，实际为 %d 页", pages)
+	}
+	if len(got) != len(items) {
+		t.Fatalf("逐页读取应得到全部 %d 项，实际: %v", len(items), got)
+	}
+	for i := range items {
+		if got[i] != items[i] {
+			t.Errorf("第 %d 项应为 %s，实际为 %s", i, items[i], got[i])
+		}
+	}
+}
+
+func TestContinuedRoundTrip(t *testing.T) {
+	first, err := Of(newRequest("list_pods", map[string]interface{}{LimitArg: 10}))
+	if err != nil {
+		t.Fatalf("解析分页参数失败: %v", err)
+	}
+	remaining := int64(5)
+	info := first.Continued(10, "continue-token", &remaining)
+	if info.Total != 15 || info.Next == "" {
+		t.Fatalf("第一页应给出总数 15 和下一页的游标，实际: %+v", info)
+	}
+
+	second, err := Of(newRequest("list_pods", map[string]interface{}{LimitArg: 10, CursorArg: info.Next}))
+	if err != nil {
+		t.Fatalf("解析游标失败: %v", err)
+	}
+	if second.Continue != "continue-token" || second.Offset != 10 {
+		t.Errorf("游标应还原 continue 令牌和偏移，实际 continue=%q offset=%d", second.Continue, second.Offset)
+	}
+
+	last := second.Continued(5, "", nil)
+	if last.Next != "" || last.Total != 15 || last.Start != 10 {
+		t.Errorf("最后一页不应有游标，总数应为 15，实际: %+v", last)
+	}
+}
+
+func TestCursorRejected(t *testing.T) {
+	p, err := Of(newRequest("list_containers", map[string]interface{}{LimitArg: 1}))
+	if err != nil {
+		t.Fatalf("解析分页参数失败: %v", err)
+	}
+	_, info := Slice([]int{1, 2}, p)
+
+	tests := []struct {
+		name   string
+		tool   string
+		cursor string
+	}{
+		{name: "其他工具的游标", tool: "list_images", cursor: info.Next},
+		{name: "不是base64", tool: "list_containers", cursor: "%%%"},
+		{name: "不是JSON", tool: "list_containers", cursor: "bm90LWpzb24"},
+		{name: "负数偏移", tool: "list_images", cursor: rawCursor(`{"tool":"list_images","offset":-5}`)},
+		{name: "负数跳过行数", tool: "pod_logs", cursor: rawCursor(`{"tool":"pod_logs","since":"2025-01-01T00:00:01Z","skip":-1}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := newRequest(tt.tool, map[string]interface{}{CursorArg: tt.cursor})
+			_, err := Of(request)
+			if tt.tool == "pod_logs" {
+				_, err = LogsOf(request, 0)
+			}
+			var argsErr *args.Error
+			if !errors.As(err, &argsErr) {
+				t.Fatalf("应返回 *args.Error，实际为 %v", err)
+			}
+			if argsErr.Problems[0].Argument != CursorArg {
+				t.Errorf("应报告 cursor 参数的问题，实际: %+v", argsErr.Problems)
+			}
+		})
+	}
+}
+
+func TestSliceOffsetBeyondEnd(t *testing.T) {
+	items := []string{"a", "b", "c"}
+	for _, offset := range []int{3, 10, math.MaxInt} {
+		t.Run(strconv.Itoa(offset), func(t *testing.T) {
+			p, err := Of(newRequest("list_images", map[string]interface{}{
+				LimitArg:  MaxLimit,
+				CursorArg: rawCursor(`{"tool":"list_images","offset":` + strconv.Itoa(offset) + `}`),
+			}))
+			if err != nil {
+				t.Fatalf("解析游标失败: %v", err)
+			}
+			page, info := Slice(items, p)
+			if len(page) != 0 || info.Count != 0 || info.Next != "" {
+				t.Errorf("偏移超出列表时应返回空页且没有游标，实际 %v %+v", page, info)
+			}
+		})
+	}
+}
+
+func TestLogCursorRejectedByListTool(t *testing.T) {
+	r, err := LogsOf(newRequest("pod_logs", nil), 1)
+	if err != nil {
+		t.Fatalf("解析日志预算失败: %v", err)
+	}
+	logs, err := r.Read(logReader("2025-01-01T00:00:01Z a", "2025-01-01T00:00:02Z b"), false, false)
+	if err != nil || logs.Next == "" {
+		t.Fatalf("应返回下一页的游标，实际 %+v, %v", logs, err)
+	}
+
+	if _, err := LogsOf(newRequest("container_logs", map[string]interface{}{CursorArg: logs.Next}), 1); err == nil {
+		t.Error("pod_logs 的游标不应能用于 container_logs")
+	}
+}
+
+// rawCursor 把游标的JSON内容编码为游标，用于构造调用方伪造的游标
+func rawCursor(data string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(data))
+}
@@ -0,0 +1,95 @@
+// Package progress 把长时间操作的进度以MCP进度通知发送给发起调用的客户端
+package progress
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Method MCP进度通知的方法名
+const Method = "notifications/progress"
+
+// minInterval 两次进度通知的最小间隔，避免频繁的进度更新占满通知通道
+const minInterval = 250 * time.Millisecond
+
+// Reporter 向发起调用的客户端发送 notifications/progress，通知通过 progressToken 与调用关联
+//
+// 客户端调用工具时没有提供 progressToken 时，Reporter 不发送任何通知。
+type Reporter struct {
+	ctx    context.Context
+	server *server.MCPServer
+	token  mcp.ProgressToken
+
+	mu       sync.Mutex
+	progress float64
+	lastSent time.Time
+}
+
+// FromRequest 返回工具调用对应的进度报告器
+func FromRequest(ctx context.Context, request mcp.CallToolRequest) *Reporter {
+	r := &Reporter{ctx: ctx, server: server.ServerFromContext(ctx)}
+	if request.Params.Meta != nil {
+		r.token = request.Params.Meta.ProgressToken
+	}
+	return r
+}
+
+// Enabled 客户端是否要求接收进度通知
+func (r *Reporter) Enabled() bool {
+	return r != nil && r.token != nil && r.server != nil
+}
+
+// Report 发送一次进度通知
+//
+// progress 为当前进度，total 为总量，总量未知时传0；fields 为附加的结构化信息，例如各层的字节数。
+// MCP要求进度单调递增，比上次小的进度会按上次的进度发送；除最终进度外，间隔过短的通知会被丢弃。
+func (r *Reporter) Report(progress, total float64, message string, fields map[string]any) {
+	r.send(progress, total, message, fields, false)
+}
+
+// Step 按步骤报告进度，适合由若干固定步骤组成的操作，例如创建容器；每个步骤都会发送通知
+func (r *Reporter) Step(step, steps int, message string) {
+	r.send(float64(step), float64(steps), message, nil, true)
+}
+
+// send 发送进度通知，force 为 true 时不受最小间隔限制
+func (r *Reporter) send(progress, total float64, message string, fields map[string]any, force bool) {
+	if !r.Enabled() {
+		return
+	}
+
+	r.mu.Lock()
+	final := total > 0 && progress >= total
+	if !force && !final && time.Since(r.lastSent) < minInterval {
+		r.mu.Unlock()
+		return
+	}
+	if progress < r.progress {
+		progress = r.progress
+	}
+	r.progress = progress
+	r.lastSent = time.Now()
+	r.mu.Unlock()
+
+	params := map[string]any{
+		"progressToken": r.token,
+		"progress":      progress,
+	}
+	if total > 0 {
+		params["total"] = total
+	}
+	if message != "" {
+		params["message"] = message
+	}
+	for key, value := range fields {
+		params[key] = value
+	}
+	if err := r.server.SendNotificationToClient(r.ctx, Method, params); err != nil {
+		slog.Debug("发送进度通知失败", "error", err)
+	}
+}
@@ -0,0 +1,213 @@
+// Package ratelimit 按API密钥和工具限制调用频率，并限制同时执行的耗时操作数量
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"golang.org/x/time/rate"
+
+	"mcp-docker/server/auth"
+	"mcp-docker/server/confirm"
+	"mcp-docker/server/output"
+	"mcp-docker/server/registry"
+	"mcp-docker/server/toolerror"
+)
+
+// 默认限制
+const (
+	// DefaultRate 每个密钥每秒允许的工具调用次数
+	DefaultRate = 5
+	// DefaultBurst 每个密钥允许的突发调用次数
+	DefaultBurst = 20
+	// DefaultMaxHeavy 同时执行的耗时操作数量上限
+	DefaultMaxHeavy = 3
+)
+
+// heavyRetryAfter 耗时操作达到并发上限时建议的重试间隔，耗时操作通常持续数秒到数分钟
+const heavyRetryAfter = 10 * time.Second
+
+// anonymous 上下文中没有调用方身份时使用的名称
+const anonymous = "anonymous"
+
+// 限制的范围
+const (
+	ScopeKey   = "key"
+	ScopeTool  = "tool"
+	ScopeHeavy = "heavy"
+)
+
+// Limit 令牌桶限制，Rate 为0表示不限制
+type Limit struct {
+	// Rate 每秒补充的令牌数，即长期平均每秒允许的调用次数，可以是小数，例如 0.1 表示每10秒一次
+	Rate float64 `yaml:"rate"`
+	// Burst 令牌桶容量，即短时间内最多允许的连续调用次数，为0时取 Rate 向上取整
+	Burst int `yaml:"burst"`
+}
+
+// Config 限流配置
+type Config struct {
+	// PerKey 每个密钥所有工具调用合计的限制
+	PerKey Limit
+	// Tools 按工具名称配置的限制，每个密钥单独计算
+	Tools map[string]Limit
+	// MaxHeavy 所有调用方同时执行的耗时操作数量上限，0表示不限制
+	MaxHeavy int
+}
+
+// Throttled 被限流的调用返回的结构化结果
+type Throttled struct {
+	Error string `json:"error"`
+	// Scope 触发的限制：key 为密钥的总调用频率，tool 为单个工具的调用频率，heavy 为耗时操作的并发数
+	Scope             string  `json:"scope"`
+	Key               string  `json:"key"`
+	Tool              string  `json:"tool"`
+	RetryAfterSeconds float64 `json:"retry_after_seconds"`
+	Message           string  `json:"message"`
+	Hint              string  `json:"hint"`
+}
+
+// Limiter 按密钥和工具保存令牌桶
+type Limiter struct {
+	config Config
+
+	mu       sync.Mutex
+	keys     map[string]*rate.Limiter
+	tools    map[string]*rate.Limiter
+	heavy    chan struct{}
+	heavyMax int
+}
+
+// New 创建限流器
+func New(config Config) *Limiter {
+	l := &Limiter{
+		config: config,
+		keys:   make(map[string]*rate.Limiter),
+		tools:  make(map[string]*rate.Limiter),
+	}
+	if config.MaxHeavy > 0 {
+		l.heavy = make(chan struct{}, config.MaxHeavy)
+		l.heavyMax = config.MaxHeavy
+	}
+	return l
+}
+
+// Middleware 以工具中间件的形式检查密钥和工具的调用频率，超过限制时返回错误结果，不执行工具
+//
+// 应位于权限检查之后，这样未授权的调用不会消耗令牌。
+func (l *Limiter) Middleware(spec *registry.Spec, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	tool := spec.Tool.Name
+	toolLimit, limitTool := l.config.Tools[tool]
+	limitTool = limitTool && toolLimit.Rate > 0
+	if l.config.PerKey.Rate <= 0 && !limitTool {
+		return next
+	}
+
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		key := keyOf(ctx)
+		now := time.Now()
+
+		var keyReservation *rate.Reservation
+		if l.config.PerKey.Rate > 0 {
+			keyReservation = l.bucket(l.keys, key, l.config.PerKey).ReserveN(now, 1)
+			if delay := keyReservation.DelayFrom(now); delay > 0 {
+				keyReservation.CancelAt(now)
+				return throttled(request, ScopeKey, key, delay,
+					fmt.Sprintf("调用过于频繁: 密钥 %s 每秒最多调用 %s 次工具", key, formatRate(l.config.PerKey.Rate))), nil
+			}
+		}
+		if limitTool {
+			toolReservation := l.bucket(l.tools, key+"\x00"+tool, toolLimit).ReserveN(now, 1)
+			if delay := toolReservation.DelayFrom(now); delay > 0 {
+				toolReservation.CancelAt(now)
+				if keyReservation != nil {
+					keyReservation.CancelAt(now)
+				}
+				return throttled(request, ScopeTool, key, delay,
+					fmt.Sprintf("调用过于频繁: 密钥 %s 每秒最多调用 %s 次工具 %s", key, formatRate(toolLimit.Rate), tool)), nil
+			}
+		}
+
+		return next(ctx, request)
+	}
+}
+
+// HeavyMiddleware 以工具中间件的形式限制同时执行的耗时操作（spec.Heavy）数量，预演调用不受限制
+//
+// 应作为最内层的中间件，只在真正执行操作时占用名额，返回确认预览时不占用。
+func (l *Limiter) HeavyMiddleware(spec *registry.Spec, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if !spec.Heavy || l.heavy == nil {
+		return next
+	}
+
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if dryRun, _ := request.Params.Arguments[confirm.DryRunArg].(bool); dryRun {
+			return next(ctx, request)
+		}
+
+		select {
+		case l.heavy <- struct{}{}:
+			defer func() { <-l.heavy }()
+		default:
+			return throttled(request, ScopeHeavy, keyOf(ctx), heavyRetryAfter,
+				fmt.Sprintf("服务器正在执行 %d 个耗时操作（拉取镜像、创建容器、系统清理等），已达到并发上限", l.heavyMax)), nil
+		}
+		return next(ctx, request)
+	}
+}
+
+// bucket 返回名称对应的令牌桶，不存在时创建
+func (l *Limiter) bucket(buckets map[string]*rate.Limiter, name string, limit Limit) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	bucket, ok := buckets[name]
+	if !ok {
+		burst := limit.Burst
+		if burst <= 0 {
+			burst = int(math.Ceil(limit.Rate))
+		}
+		bucket = rate.NewLimiter(rate.Limit(limit.Rate), burst)
+		buckets[name] = bucket
+	}
+	return bucket
+}
+
+// keyOf 返回调用方的密钥名称
+func keyOf(ctx context.Context) string {
+	if id, ok := auth.IdentityFromContext(ctx); ok && id.Name != "" {
+		return id.Name
+	}
+	return anonymous
+}
+
+// throttled 构造被限流的错误结果，text 格式返回说明文字，json/yaml 格式返回 Throttled
+func throttled(request mcp.CallToolRequest, scope, key string, retryAfter time.Duration, reason string) *mcp.CallToolResult {
+	// 向上取整到0.1秒，避免调用方按建议的时间重试时仍然差几毫秒
+	seconds := math.Ceil(retryAfter.Seconds()*10) / 10
+	message := fmt.Sprintf("%s，请在 %.1f 秒后重试", reason, seconds)
+	hint := toolerror.Hint(toolerror.CodeRateLimited)
+	result, err := output.Result(request, toolerror.Text(message, hint), Throttled{
+		Error:             toolerror.CodeRateLimited,
+		Scope:             scope,
+		Key:               key,
+		Tool:              request.Params.Name,
+		RetryAfterSeconds: seconds,
+		Message:           message,
+		Hint:              hint,
+	})
+	if err != nil || result == nil {
+		result = mcp.NewToolResultText(toolerror.Text(message, hint))
+	}
+	return toolerror.Mark(result, toolerror.CodeRateLimited)
+}
+
+// formatRate 格式化每秒的调用次数，去掉多余的小数位
+func formatRate(r float64) string {
+	return fmt.Sprintf("%g", r)
+}
@@ -0,0 +1,192 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"mcp-docker/server/auth"
+	"mcp-docker/server/confirm"
+	"mcp-docker/server/output"
+	"mcp-docker/server/registry"
+	"mcp-docker/server/toolerror"
+)
+
+// okHandler 总是成功的处理函数
+func okHandler(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return mcp.NewToolResultText("ok"), nil
+}
+
+// callAs 以指定密钥和json格式调用工具
+func callAs(t *testing.T, handler server.ToolHandlerFunc, key, tool string, arguments map[string]interface{}) *mcp.CallToolResult {
+	t.Helper()
+
+	copied := map[string]interface{}{output.FormatArg: output.FormatJSON}
+	for name, value := range arguments {
+		copied[name] = value
+	}
+	var request mcp.CallToolRequest
+	request.Params.Name = tool
+	request.Params.Arguments = copied
+	result, err := handler(auth.WithIdentity(context.Background(), auth.Identity{Name: key}), request)
+	if err != nil {
+		t.Fatalf("不应返回Go错误: %v", err)
+	}
+	return result
+}
+
+// throttledOf 解析被限流的结果，结果不是限流错误时测试失败
+func throttledOf(t *testing.T, result *mcp.CallToolResult) Throttled {
+	t.Helper()
+
+	if code := toolerror.CodeOfResult(result); code != toolerror.CodeRateLimited {
+		t.Fatalf("错误码应为 %s，实际为 %q", toolerror.CodeRateLimited, code)
+	}
+	var throttled Throttled
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &throttled); err != nil {
+		t.Fatalf("解析结果失败: %v", err)
+	}
+	return throttled
+}
+
+// wrap 为工具加上频率限制
+func wrap(l *Limiter, tool string, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return l.Middleware(&registry.Spec{Tool: mcp.NewTool(tool)}, next)
+}
+
+func TestPerKeyLimit(t *testing.T) {
+	l := New(Config{PerKey: Limit{Rate: 1, Burst: 2}})
+	list := wrap(l, "list_pods", okHandler)
+	inspect := wrap(l, "inspect_container", okHandler)
+
+	// 同一密钥的所有工具共用突发额度
+	if callAs(t, list, "alice", "list_pods", nil).IsError || callAs(t, inspect, "alice", "inspect_container", nil).IsError {
+		t.Fatal("突发额度内的调用不应被限流")
+	}
+
+	throttled := throttledOf(t, callAs(t, list, "alice", "list_pods", nil))
+	if throttled.Scope != ScopeKey || throttled.Key != "alice" || throttled.Tool != "list_pods" {
+		t.Errorf("限流结果不正确: %+v", throttled)
+	}
+	if throttled.RetryAfterSeconds <= 0 || throttled.RetryAfterSeconds > 1 {
+		t.Errorf("每秒 1 次时建议的重试间隔应在 (0, 1] 秒内，实际为 %g", throttled.RetryAfterSeconds)
+	}
+
+	// 其他密钥不受影响
+	if result := callAs(t, list, "bob", "list_pods", nil); result.IsError {
+		t.Error("不同密钥的额度应分别计算")
+	}
+}
+
+func TestPerToolLimit(t *testing.T) {
+	l := New(Config{Tools: map[string]Limit{"pull_image": {Rate: 0.1}}})
+	pull := wrap(l, "pull_image", okHandler)
+	list := wrap(l, "list_images", okHandler)
+
+	if result := callAs(t, pull, "alice", "pull_image", nil); result.IsError {
+		t.Fatal("第一次调用不应被限流")
+	}
+	throttled := throttledOf(t, callAs(t, pull, "alice", "pull_image", nil))
+	if throttled.Scope != ScopeTool || throttled.Tool != "pull_image" {
+		t.Errorf("限流结果不正确: %+v", throttled)
+	}
+	if throttled.RetryAfterSeconds <= 9 || throttled.RetryAfterSeconds > 10 {
+		t.Errorf("每 10 秒 1 次时建议的重试间隔应接近 10 秒，实际为 %g", throttled.RetryAfterSeconds)
+	}
+
+	// 未配置限制的工具和其他密钥不受影响
+	for i := 0; i < 5; i++ {
+		if result := callAs(t, list, "alice", "list_images", nil); result.IsError {
+			t.Fatal("未配置限制的工具不应被限流")
+		}
+	}
+	if result := callAs(t, pull, "bob", "pull_image", nil); result.IsError {
+		t.Error("工具的额度应按密钥分别计算")
+	}
+}
+
+func TestToolLimitRefundsKeyToken(t *testing.T) {
+	l := New(Config{PerKey: Limit{Rate: 1, Burst: 2}, Tools: map[string]Limit{"pull_image": {Rate: 0.1}}})
+	pull := wrap(l, "pull_image", okHandler)
+	list := wrap(l, "list_images", okHandler)
+
+	callAs(t, pull, "alice", "pull_image", nil)
+	throttledOf(t, callAs(t, pull, "alice", "pull_image", nil))
+
+	// 被工具限制拒绝的调用不应消耗密钥的额度
+	if result := callAs(t, list, "alice", "list_images", nil); result.IsError {
+		t.Error("被工具限制拒绝的调用不应占用密钥的突发额度")
+	}
+}
+
+func TestUnlimited(t *testing.T) {
+	l := New(Config{})
+	spec := &registry.Spec{Tool: mcp.NewTool("list_pods"), Heavy: true}
+	if l.Middleware(spec, okHandler) == nil || l.HeavyMiddleware(spec, okHandler) == nil {
+		t.Fatal("未配置限制时应直接返回处理函数")
+	}
+	handler := l.HeavyMiddleware(spec, l.Middleware(spec, okHandler))
+	for i := 0; i < 100; i++ {
+		if result := callAs(t, handler, "alice", "list_pods", nil); result.IsError {
+			t.Fatal("未配置限制时不应被限流")
+		}
+	}
+}
+
+func TestHeavyLimit(t *testing.T) {
+	l := New(Config{MaxHeavy: 1})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	spec := &registry.Spec{Tool: mcp.NewTool("pull_image"), Heavy: true}
+	handler := l.HeavyMiddleware(spec, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if dryRun, _ := request.Params.Arguments[confirm.DryRunArg].(bool); !dryRun {
+			started <- struct{}{}
+			<-release
+		}
+		return mcp.NewToolResultText("ok"), nil
+	})
+
+	done := make(chan *mcp.CallToolResult)
+	go func() {
+		var request mcp.CallToolRequest
+		request.Params.Name = "pull_image"
+		result, _ := handler(auth.WithIdentity(context.Background(), auth.Identity{Name: "alice"}), request)
+		done <- result
+	}()
+	<-started
+
+	throttled := throttledOf(t, callAs(t, handler, "bob", "pull_image", nil))
+	if throttled.Scope != ScopeHeavy || throttled.RetryAfterSeconds != heavyRetryAfter.Seconds() {
+		t.Errorf("限流结果不正确: %+v", throttled)
+	}
+
+	// 预演调用不占用名额
+	if result := callAs(t, handler, "bob", "pull_image", map[string]interface{}{confirm.DryRunArg: true}); result.IsError {
+		t.Error("预演调用不应受耗时操作并发数的限制")
+	}
+
+	close(release)
+	if result := <-done; result.IsError {
+		t.Error("第一次调用不应被限流")
+	}
+
+	// 操作结束后释放名额
+	go func() { <-started }()
+	if result := callAs(t, handler, "bob", "pull_image", nil); result.IsError {
+		t.Error("耗时操作结束后应释放名额")
+	}
+}
+
+func TestHeavyLimitIgnoresLightTools(t *testing.T) {
+	l := New(Config{MaxHeavy: 1})
+	l.heavy <- struct{}{}
+
+	handler := l.HeavyMiddleware(&registry.Spec{Tool: mcp.NewTool("list_images")}, okHandler)
+	if result := callAs(t, handler, "alice", "list_images", nil); result.IsError {
+		t.Error("非耗时操作不应受并发数的限制")
+	}
+}
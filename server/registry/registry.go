@@ -0,0 +1,106 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// 工具分组
+const (
+	GroupDocker = "docker"
+	GroupK8s    = "k8s"
+	GroupAudit  = "audit"
+)
+
+// DefaultTimeout 未单独设置超时的工具使用的超时时间
+const DefaultTimeout = 2 * time.Minute
+
+// ErrTimeout 工具调用超过超时时间的原因，可以通过 context.Cause 从调用的上下文中取得
+var ErrTimeout = errors.New("工具调用超时")
+
+// Spec 描述一个MCP工具：工具声明、处理函数以及供中间件使用的属性
+type Spec struct {
+	Tool    mcp.Tool
+	Handler server.ToolHandlerFunc
+	// Group 工具所属的分组，例如 docker、k8s
+	Group string
+	// Mutating 工具会修改资源，支持 dry_run 参数
+	Mutating bool
+	// Destructive 工具会删除资源且不可撤销，需要两阶段确认
+	Destructive bool
+	// Heavy 工具会长时间占用守护进程，例如拉取镜像、系统清理
+	Heavy bool
+	// Timeout 工具调用的超时时间，为0时使用 DefaultTimeout
+	Timeout time.Duration
+	// TimeoutHint 调用超时时的处理建议，为空时使用超时错误码的通用建议
+	TimeoutHint string
+}
+
+// Middleware 包装工具处理函数
+//
+// 中间件可以根据 Spec 中的属性决定是否生效，也可以修改 spec.Tool 为工具增加参数，
+// 例如为危险操作增加确认令牌参数。
+type Middleware func(spec *Spec, next server.ToolHandlerFunc) server.ToolHandlerFunc
+
+// RegisterAll 将工具注册到MCP服务器，middlewares 中靠前的中间件位于外层，最先执行
+func RegisterAll(svr *server.MCPServer, specs []Spec, middlewares ...Middleware) {
+	for _, spec := range specs {
+		handler := spec.Handler
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			handler = middlewares[i](&spec, handler)
+		}
+		svr.AddTool(spec.Tool, handler)
+	}
+}
+
+// Timeout 为工具调用设置超时时间
+func Timeout(spec *Spec, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return Timeouts(0, nil)(spec, next)
+}
+
+// Timeouts 返回按配置设置超时时间的中间件
+//
+// 优先使用 overrides 中按工具名称配置的超时，其次是工具自身的 Timeout，
+// 最后是 defaultTimeout；defaultTimeout 为0时使用 DefaultTimeout。
+func Timeouts(defaultTimeout time.Duration, overrides map[string]time.Duration) Middleware {
+	if defaultTimeout <= 0 {
+		defaultTimeout = DefaultTimeout
+	}
+	return func(spec *Spec, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		timeout := overrides[spec.Tool.Name]
+		if timeout <= 0 {
+			timeout = spec.Timeout
+		}
+		if timeout <= 0 {
+			timeout = defaultTimeout
+		}
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			timeoutCtx, cancel := context.WithTimeoutCause(ctx, timeout, fmt.Errorf("%w（超过 %s）", ErrTimeout, timeout))
+			defer cancel()
+			return next(timeoutCtx, request)
+		}
+	}
+}
+
+// Validate 检查工具列表中是否有重名或缺少处理函数的工具
+func Validate(specs []Spec) error {
+	seen := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		if spec.Tool.Name == "" {
+			return fmt.Errorf("存在未命名的工具")
+		}
+		if spec.Handler == nil {
+			return fmt.Errorf("工具 %s 缺少处理函数", spec.Tool.Name)
+		}
+		if seen[spec.Tool.Name] {
+			return fmt.Errorf("工具 %s 重复注册", spec.Tool.Name)
+		}
+		seen[spec.Tool.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+// Package toolerror 把工具执行失败的原因归类为固定的错误码，并以 isError 结果返回给调用方
+//
+// 工具处理函数直接返回 Go error 时，mcp-go 会把它转换为 JSON-RPC 错误，Agent 看不到说明文字。
+// 处理函数应通过 Result 返回错误结果：结果中带有错误码和处理建议，
+// json/yaml 格式返回 Failure，text 格式返回说明文字和建议；错误码同时写入结果的 _meta。
+package toolerror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/mark3labs/mcp-go/mcp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"mcp-docker/server/output"
+	"mcp-docker/server/registry"
+)
+
+// 错误码
+const (
+	// CodeNotFound 容器、镜像、Pod等资源不存在
+	CodeNotFound = "not_found"
+	// CodeConflict 资源已存在或当前状态不允许该操作，例如名称已被占用、删除正在运行的容器
+	CodeConflict = "conflict"
+	// CodePermissionDenied Docker守护进程或Kubernetes拒绝了请求，或者超出了允许使用的context、命名空间
+	CodePermissionDenied = "permission_denied"
+	// CodeTimeout 工具调用或后端请求超时
+	CodeTimeout = "timeout"
+	// CodeBackendUnavailable 无法连接Docker守护进程或Kubernetes API服务器
+	CodeBackendUnavailable = "backend_unavailable"
+	// CodeInvalidArgument 参数不正确，或者后端认为请求无效
+	CodeInvalidArgument = "invalid_argument"
+	// CodeCancelled 客户端取消了调用或断开了连接
+	CodeCancelled = "cancelled"
+	// CodeRateLimited 调用超过了限流配置，没有执行
+	CodeRateLimited = "rate_limited"
+	// CodeUnavailable 服务器正在关闭，不再接受新的调用
+	CodeUnavailable = "unavailable"
+	// CodeInvalidConfirmation 危险操作的确认令牌无效、已过期或与调用参数不符，操作没有执行
+	CodeInvalidConfirmation = "invalid_confirmation"
+	// CodeInternal 无法归类的错误
+	CodeInternal = "internal"
+)
+
+// MetaKey 错误结果的 _meta 中保存错误码的键，text 格式的结果同样可以据此取得错误码
+const MetaKey = "mcp-docker/error"
+
+// hints 各错误码的处理建议
+var hints = map[string]string{
+	CodeNotFound:            "确认名称或ID是否正确，可以先用 list_containers、list_images、list_pods 等工具查看现有资源；Kubernetes资源还需要确认命名空间和context",
+	CodeConflict:            "资源已存在或当前状态不允许该操作，请先查看资源的当前状态，必要时更换名称，或者先停止、删除冲突的资源后重试",
+	CodePermissionDenied:    "当前凭据没有执行该操作的权限，请检查Docker守护进程的访问权限、Kubernetes RBAC授权以及服务器允许使用的context和命名空间",
+	CodeTimeout:             "操作未在规定时间内完成，可以稍后重试，或者通过配置文件的 timeouts 调大该工具的超时时间；修改类操作请先确认资源的当前状态",
+	CodeBackendUnavailable:  "无法连接Docker守护进程或Kubernetes API服务器，请确认服务正在运行且网络可达，可以通过 /readyz 查看各后端的状态",
+	CodeInvalidArgument:     "请按工具的参数说明修正参数后重试",
+	CodeCancelled:           "调用已被取消，需要时可以重新发起",
+	CodeRateLimited:         "调用过于频繁，请按结果中给出的时间等待后再重试，不要立即重复调用",
+	CodeUnavailable:         "服务器正在关闭，请稍后重新连接后重试",
+	CodeInvalidConfirmation: "请不带 confirm_token 使用相同参数重新调用，获取新的预览和令牌，用户确认后再携带新令牌调用",
+	CodeInternal:            "服务器或后端发生了内部错误，请查看服务端日志以及Docker守护进程或API服务器的日志；问题持续出现时请反馈",
+}
+
+// Failure 工具执行失败时返回的结构化结果
+type Failure struct {
+	// Error 错误码，例如 not_found
+	Error   string `json:"error"`
+	Tool    string `json:"tool"`
+	Message string `json:"message"`
+	// Hint 处理建议
+	Hint string `json:"hint"`
+}
+
+// Error 带有错误码的错误，用于后端错误无法说明原因的情况，例如未知的Docker主机、不允许使用的context
+type Error struct {
+	Code string
+	Err  error
+}
+
+// Error 实现 error，返回原始错误的说明
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap 返回原始错误
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New 创建带有错误码的错误
+func New(code string, format string, a ...interface{}) error {
+	return &Error{Code: code, Err: fmt.Errorf(format, a...)}
+}
+
+// Wrap 为错误指定错误码，err 为 nil 时返回 nil
+func Wrap(code string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// CodeOf 返回错误的错误码
+//
+// 依次识别 Wrap 指定的错误码、上下文的取消和超时、Docker SDK 的 errdefs、
+// Kubernetes 的 apierrors 以及网络错误，无法识别时返回 CodeInternal。
+func CodeOf(err error) string {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, registry.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return CodeTimeout
+	case errors.Is(err, context.Canceled):
+		return CodeCancelled
+	case errors.Is(err, output.ErrUnsupportedFormat):
+		return CodeInvalidArgument
+	}
+
+	if code := dockerCode(err); code != "" {
+		return code
+	}
+	if code := kubernetesCode(err); code != "" {
+		return code
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return CodeTimeout
+		}
+		return CodeBackendUnavailable
+	}
+	return CodeInternal
+}
+
+// Hint 返回错误码对应的处理建议
+func Hint(code string) string {
+	if hint, ok := hints[code]; ok {
+		return hint
+	}
+	return hints[CodeInternal]
+}
+
+// Result 返回工具执行失败的结果，说明文字为 "message: err"
+func Result(request mcp.CallToolRequest, message string, err error) (*mcp.CallToolResult, error) {
+	if err != nil {
+		message = fmt.Sprintf("%s: %v", message, err)
+	}
+	return Coded(request, CodeOf(err), message)
+}
+
+// Coded 以指定的错误码返回工具执行失败的结果
+func Coded(request mcp.CallToolRequest, code, message string) (*mcp.CallToolResult, error) {
+	return CodedWithHint(request, code, message, Hint(code))
+}
+
+// CodedWithHint 以指定的错误码和处理建议返回工具执行失败的结果，用于比错误码的通用建议更具体的情况
+func CodedWithHint(request mcp.CallToolRequest, code, message, hint string) (*mcp.CallToolResult, error) {
+	result, err := output.Result(request, Text(message, hint), Failure{
+		Error:   code,
+		Tool:    request.Params.Name,
+		Message: message,
+		Hint:    hint,
+	})
+	if err != nil || result == nil {
+		result = mcp.NewToolResultText(Text(message, hint))
+	}
+	return Mark(result, code), nil
+}
+
+// Text 返回 text 格式的错误说明，附带处理建议
+func Text(message, hint string) string {
+	if hint == "" {
+		return message
+	}
+	return fmt.Sprintf("%s\n建议: %s", message, hint)
+}
+
+// Mark 把结果标记为错误结果并在 _meta 中记录错误码，供其他中间件返回自己的结构化错误时使用
+func Mark(result *mcp.CallToolResult, code string) *mcp.CallToolResult {
+	result.IsError = true
+	if result.Meta == nil {
+		result.Meta = make(map[string]interface{})
+	}
+	result.Meta[MetaKey] = code
+	return result
+}
+
+// CodeOfResult 返回错误结果的错误码，不是错误结果或没有错误码时返回空字符串
+func CodeOfResult(result *mcp.CallToolResult) string {
+	if result == nil || !result.IsError {
+		return ""
+	}
+	code, _ := result.Meta[MetaKey].(string)
+	return code
+}
+
+// IsBackendError 判断错误码是否表示后端返回了错误，参数错误、客户端取消以及服务器自身拒绝的调用不属于后端错误
+func IsBackendError(code string) bool {
+	switch code {
+	case "", CodeInvalidArgument, CodeCancelled, CodeRateLimited, CodeUnavailable, CodeInvalidConfirmation:
+		return false
+	}
+	return true
+}
+
+// dockerCode 识别Docker SDK返回的错误
+func dockerCode(err error) string {
+	switch {
+	case client.IsErrConnectionFailed(err), errdefs.IsUnavailable(err):
+		return CodeBackendUnavailable
+	case errdefs.IsNotFound(err):
+		return CodeNotFound
+	case errdefs.IsConflict(err), errdefs.IsNotModified(err):
+		return CodeConflict
+	case errdefs.IsUnauthorized(err), errdefs.IsForbidden(err):
+		return CodePermissionDenied
+	case errdefs.IsInvalidParameter(err), errdefs.IsNotImplemented(err):
+		return CodeInvalidArgument
+	case errdefs.IsDeadline(err):
+		return CodeTimeout
+	case errdefs.IsCancelled(err):
+		return CodeCancelled
+	}
+	return ""
+}
+
+// kubernetesCode 识别client-go返回的API错误
+func kubernetesCode(err error) string {
+	switch {
+	case apierrors.IsNotFound(err):
+		return CodeNotFound
+	case apierrors.IsAlreadyExists(err), apierrors.IsConflict(err):
+		return CodeConflict
+	case apierrors.IsUnauthorized(err), apierrors.IsForbidden(err):
+		return CodePermissionDenied
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err), apierrors.IsMethodNotSupported(err), apierrors.IsResourceExpired(err):
+		return CodeInvalidArgument
+	case apierrors.IsTimeout(err), apierrors.IsServerTimeout(err):
+		return CodeTimeout
+	case apierrors.IsServiceUnavailable(err), apierrors.IsTooManyRequests(err):
+		return CodeBackendUnavailable
+	}
+	return ""
+}
@@ -0,0 +1,142 @@
+// Package tracing 使用OpenTelemetry记录工具调用及其访问Docker、Kubernetes API的链路
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"mcp-docker/server/registry"
+)
+
+// instrumentationName 本服务创建的span所属的instrumentation名称
+const instrumentationName = "mcp-docker/server"
+
+// DefaultServiceName 默认的服务名称
+const DefaultServiceName = "mcp-docker-server"
+
+// Config 链路追踪配置
+type Config struct {
+	// Endpoint OTLP/HTTP 接收地址，例如 http://otel-collector:4318，不含路径时使用 /v1/traces
+	Endpoint    string
+	ServiceName string
+	// SampleRatio 没有上游链路时的采样比例，上游已采样的请求总是会被记录
+	SampleRatio float64
+}
+
+// Setup 创建OTLP导出器并设置全局的TracerProvider和传播格式（W3C Trace Context 和 Baggage）
+//
+// 返回的 shutdown 函数会导出尚未发送的span，应在服务器退出前调用。
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	endpoint, err := EndpointURL(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("创建OTLP导出器失败: %v", err)
+	}
+
+	serviceName := config.ServiceName
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("创建链路追踪资源失败: %v", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	return provider.Shutdown, nil
+}
+
+// EndpointURL 检查OTLP接收地址，地址不含路径时补全为 /v1/traces
+func EndpointURL(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("OTLP接收地址无效: %v", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("OTLP接收地址应以 http:// 或 https:// 开头: %q", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+	return u.String(), nil
+}
+
+// Extract 从HTTP请求头中提取上游的链路上下文，之后的工具调用span会成为上游span的子span
+//
+// SSE传输中每次工具调用对应一个 /message 请求，因此客户端需要在这些请求上携带 traceparent 请求头。
+func Extract(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Middleware 以工具中间件的形式为每次工具调用创建一个span
+//
+// 应位于审计和权限检查的外层，这样被拒绝的调用也有对应的span。
+func Middleware(spec *registry.Spec, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	tracer := otel.Tracer(instrumentationName)
+	name := "tools/call " + spec.Tool.Name
+	attrs := []attribute.KeyValue{
+		attribute.String("mcp.method.name", string(mcp.MethodToolsCall)),
+		attribute.String("gen_ai.tool.name", spec.Tool.Name),
+		attribute.String("mcp.tool.group", spec.Group),
+	}
+
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+		defer span.End()
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			span.SetAttributes(attribute.String("mcp.session.id", session.SessionID()))
+		}
+
+		result, err := next(ctx, request)
+		switch {
+		case err != nil:
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		case result != nil && result.IsError:
+			span.SetStatus(codes.Error, "工具返回错误结果")
+		}
+		return result, err
+	}
+}
+
+// Transport 为HTTP客户端记录每个请求的span并向下游传播链路上下文，用于包装client-go的请求
+//
+// Docker SDK 已经内置了同样的处理，会使用全局的TracerProvider，不需要额外包装。
+func Transport(rt http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(rt, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method + " " + r.URL.Path
+	}))
+}
@@ -0,0 +1,264 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// flushQuiet 关闭事件流前，所有消息请求处理完毕且事件流持续这么长时间没有写入，才认为排队的结果都已发出
+const flushQuiet = 200 * time.Millisecond
+
+// SSEStreams 记录打开的SSE事件流，关闭服务器时向所有客户端推送通知并结束事件流
+//
+// mcp-go 的 SSEServer 只有在请求的上下文结束时才会结束事件流，也不提供向所有会话推送通知的方法，
+// 因此在它外层包装一次：事件流的写入通过互斥锁与推送的通知串行化，结束时取消请求的上下文。
+type SSEStreams struct {
+	endpoint string
+	next     http.Handler
+
+	mu      sync.Mutex
+	closed  bool
+	streams map[*sseStream]struct{}
+	wg      sync.WaitGroup
+	onClose func(sessionID string)
+
+	// pending 正在处理的消息请求数量，SSE服务器在处理函数返回前把结果放入事件流的发送队列
+	pending atomic.Int64
+	// lastActivity 最后一次写入事件流或处理完消息请求的时间，UnixNano
+	lastActivity atomic.Int64
+}
+
+// sseStream 一个打开的事件流
+type sseStream struct {
+	mu      sync.Mutex
+	streams *SSEStreams
+	writer  http.ResponseWriter
+	flusher http.Flusher
+	cancel  context.CancelFunc
+	// started 表示SSE服务器已经写入了响应头和 endpoint 事件，之后才能推送通知
+	started bool
+	// sessionID 从 endpoint 事件中的消息地址解析出的会话ID
+	sessionID string
+}
+
+// NewSSEStreams 包装SSE服务器，sseEndpoint 为建立事件流的路径
+func NewSSEStreams(sseEndpoint string, next http.Handler) *SSEStreams {
+	return &SSEStreams{
+		endpoint: sseEndpoint,
+		next:     next,
+		streams:  make(map[*sseStream]struct{}),
+	}
+}
+
+// ServeHTTP 处理MCP请求，开始关闭后拒绝建立新的事件流
+func (s *SSEStreams) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != s.endpoint || r.Method != http.MethodGet {
+		s.pending.Add(1)
+		defer func() {
+			s.touch()
+			s.pending.Add(-1)
+		}()
+		s.next.ServeHTTP(w, r)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.next.ServeHTTP(w, r)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	stream := &sseStream{streams: s, writer: w, flusher: flusher, cancel: cancel}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		http.Error(w, "服务器正在关闭", http.StatusServiceUnavailable)
+		return
+	}
+	s.streams[stream] = struct{}{}
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.streams, stream)
+		onClose := s.onClose
+		s.mu.Unlock()
+		if onClose != nil && stream.sessionID != "" {
+			onClose(stream.sessionID)
+		}
+		s.wg.Done()
+	}()
+	s.next.ServeHTTP(stream, r.WithContext(ctx))
+}
+
+// OnSessionClosed 设置事件流结束时的回调，参数为该事件流对应的会话ID
+func (s *SSEStreams) OnSessionClosed(fn func(sessionID string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onClose = fn
+}
+
+// Count 返回打开的事件流数量
+func (s *SSEStreams) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.streams)
+}
+
+// Notify 向所有打开的事件流推送一条通知
+func (s *SSEStreams) Notify(method string, params map[string]any) error {
+	notification := mcp.JSONRPCNotification{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		Notification: mcp.Notification{
+			Method: method,
+			Params: mcp.NotificationParams{
+				AdditionalFields: params,
+			},
+		},
+	}
+	data, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("序列化通知失败: %v", err)
+	}
+	event := fmt.Sprintf("event: message\ndata: %s\n\n", data)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for stream := range s.streams {
+		stream.writeEvent(event)
+	}
+	return nil
+}
+
+// Close 拒绝新的事件流，结束所有打开的事件流，并等待SSE服务器的处理函数返回或 ctx 结束
+//
+// 结束事件流前先等待正在处理的消息请求返回、发送队列中的结果写出，
+// 否则刚刚结束的工具调用的结果会随事件流一起被丢弃。
+func (s *SSEStreams) Close(ctx context.Context) error {
+	flushErr := s.flush(ctx)
+
+	s.mu.Lock()
+	s.closed = true
+	for stream := range s.streams {
+		stream.cancel()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		if flushErr != nil {
+			return fmt.Errorf("等待排队的消息发出超时: %w", flushErr)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flush 等待消息请求全部处理完毕，且事件流持续 flushQuiet 没有写入
+//
+// SSE服务器的发送队列无法从外部观察，只能以一段时间内没有写入作为队列已经清空的依据。
+func (s *SSEStreams) flush(ctx context.Context) error {
+	ticker := time.NewTicker(flushQuiet / 10)
+	defer ticker.Stop()
+
+	for {
+		idle := time.Since(time.Unix(0, s.lastActivity.Load()))
+		if s.pending.Load() == 0 && idle >= flushQuiet {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// touch 记录事件流或消息请求的活动时间
+func (s *SSEStreams) touch() {
+	s.lastActivity.Store(time.Now().UnixNano())
+}
+
+// Header 实现 http.ResponseWriter
+func (s *sseStream) Header() http.Header {
+	return s.writer.Header()
+}
+
+// Write 实现 http.ResponseWriter，与推送的通知互斥
+func (s *sseStream) Write(data []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.started {
+		s.sessionID = parseSessionID(string(data))
+	}
+	s.started = true
+	s.streams.touch()
+	return s.writer.Write(data)
+}
+
+// WriteHeader 实现 http.ResponseWriter
+func (s *sseStream) WriteHeader(statusCode int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.writer.WriteHeader(statusCode)
+}
+
+// Flush 实现 http.Flusher
+func (s *sseStream) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.flusher.Flush()
+}
+
+// writeEvent 写入一个完整的事件并立即发送
+func (s *sseStream) writeEvent(event string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.started {
+		return
+	}
+	fmt.Fprint(s.writer, event)
+	s.flusher.Flush()
+}
+
+// parseSessionID 从SSE服务器写入的第一个事件（endpoint 事件）中解析会话ID
+//
+// 事件的数据为客户端发送消息的地址，例如 /message?sessionId=xxx。
+func parseSessionID(event string) string {
+	for _, line := range strings.Split(event, "\n") {
+		data, ok := strings.CutPrefix(strings.TrimSpace(line), "data:")
+		if !ok {
+			continue
+		}
+		endpoint, err := url.Parse(strings.TrimSpace(data))
+		if err != nil {
+			return ""
+		}
+		return endpoint.Query().Get("sessionId")
+	}
+	return ""
+}
@@ -0,0 +1,173 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// openStream 建立事件流，返回发送消息的地址和按顺序收到的事件，事件流结束时关闭通道
+func openStream(t *testing.T, ts *httptest.Server) (string, <-chan string) {
+	t.Helper()
+
+	resp, err := http.Get(ts.URL + "/sse")
+	if err != nil {
+		t.Fatalf("建立事件流失败: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		t.Fatalf("建立事件流应返回 200，实际为 %d", resp.StatusCode)
+	}
+
+	events := make(chan string, 10)
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			// SSE服务器的 endpoint 事件以 \r\n 结尾
+			if data, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "data: "); ok {
+				events <- data
+			}
+		}
+	}()
+
+	endpoint := <-events
+	return ts.URL + endpoint, events
+}
+
+// nextEvent 等待下一个事件，事件流已经结束时测试失败
+func nextEvent(t *testing.T, events <-chan string) string {
+	t.Helper()
+
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("事件流提前结束")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("没有收到事件")
+		return ""
+	}
+}
+
+func TestSSEStreamsClose(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	svr := server.NewMCPServer("test", "1.0", server.WithToolCapabilities(false))
+	svr.AddTool(mcp.NewTool("pull_image"), func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		close(started)
+		<-release
+		return mcp.NewToolResultText("pulled"), nil
+	})
+
+	streams := NewSSEStreams("/sse", server.NewSSEServer(svr))
+	var (
+		mu     sync.Mutex
+		closed []string
+	)
+	streams.OnSessionClosed(func(sessionID string) {
+		mu.Lock()
+		defer mu.Unlock()
+		closed = append(closed, sessionID)
+	})
+	ts := httptest.NewServer(streams)
+	defer ts.Close()
+
+	endpoint, events := openStream(t, ts)
+	if streams.Count() != 1 {
+		t.Errorf("应有 1 个事件流，实际为 %d", streams.Count())
+	}
+
+	// 发起一次执行中的调用，SSE服务器在调用返回后才把结果放入发送队列
+	go func() {
+		resp, err := http.Post(endpoint, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"pull_image"}}`))
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	if err := streams.Notify("notifications/message", map[string]any{"data": "服务器即将关闭"}); err != nil {
+		t.Fatalf("推送通知失败: %v", err)
+	}
+	if event := nextEvent(t, events); !strings.Contains(event, "notifications/message") || !strings.Contains(event, "服务器即将关闭") {
+		t.Errorf("应先收到关闭通知，实际为 %s", event)
+	}
+
+	closeErr := make(chan error, 1)
+	go func() { closeErr <- streams.Close(context.Background()) }()
+
+	// 关闭时等待正在处理的调用返回并写出结果后才结束事件流
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	if event := nextEvent(t, events); !strings.Contains(event, "pulled") {
+		t.Errorf("结束事件流前应发出调用的结果，实际为 %s", event)
+	}
+	if err := <-closeErr; err != nil {
+		t.Errorf("关闭事件流失败: %v", err)
+	}
+	if _, ok := <-events; ok {
+		t.Error("关闭后事件流应结束")
+	}
+
+	mu.Lock()
+	if len(closed) != 1 || !strings.HasSuffix(endpoint, "sessionId="+closed[0]) {
+		t.Errorf("事件流结束时应回调一次对应的会话ID，实际: %v", closed)
+	}
+	mu.Unlock()
+
+	resp, err := http.Get(ts.URL + "/sse")
+	if err != nil {
+		t.Fatalf("发送请求失败: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("关闭后建立事件流应返回 503，实际为 %d", resp.StatusCode)
+	}
+}
+
+func TestSSEStreamsCloseTimeout(t *testing.T) {
+	svr := server.NewMCPServer("test", "1.0")
+	streams := NewSSEStreams("/sse", server.NewSSEServer(svr))
+	ts := httptest.NewServer(streams)
+	defer ts.Close()
+
+	_, events := openStream(t, ts)
+
+	// 一直有消息请求在处理时，超过期限不再等待发送队列
+	streams.pending.Add(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := streams.Close(ctx); err == nil {
+		t.Error("等待发送队列超过期限时应返回错误")
+	}
+	streams.pending.Add(-1)
+	for range events {
+	}
+}
+
+func TestParseSessionID(t *testing.T) {
+	tests := []struct {
+		event string
+		want  string
+	}{
+		{event: "event: endpoint\ndata: /message?sessionId=abc\n\n", want: "abc"},
+		{event: "event: endpoint\ndata: http://localhost:8080/message?sessionId=abc\n\n", want: "abc"},
+		{event: "event: endpoint\n\n", want: ""},
+	}
+	for _, tt := range tests {
+		if got := parseSessionID(tt.event); got != tt.want {
+			t.Errorf("%q 的会话ID应为 %q，实际为 %q", tt.event, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,393 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// SessionHeader streamable HTTP 传输中携带会话ID的请求头
+const SessionHeader = "Mcp-Session-Id"
+
+// 默认配置
+const (
+	DefaultEndpoint = "/mcp"
+	// SessionIdleTimeout 会话超过这个时间没有请求时会被清理
+	SessionIdleTimeout = 30 * time.Minute
+	// MaxSessions 同时存在的会话数上限，达到上限时新的 initialize 请求返回 503
+	MaxSessions = 1000
+	// reapInterval 两次清理过期会话之间的最短间隔，清理在处理请求时进行
+	reapInterval = time.Minute
+	// maxBodySize 单个请求体的大小上限
+	maxBodySize = 4 * 1024 * 1024
+	// notificationBuffer 单个请求可以缓存的通知数量
+	notificationBuffer = 100
+)
+
+// StreamableHTTPServer 实现MCP的 streamable HTTP 传输
+//
+// 客户端通过 POST 发送JSON-RPC消息，服务器根据 Accept 请求头返回JSON，或以SSE流的形式
+// 先推送调用过程中的通知（例如进度）再返回结果；DELETE 用于结束会话。
+// 服务器不支持通过 GET 打开独立的通知流，GET 请求返回 405。
+type StreamableHTTPServer struct {
+	server   *server.MCPServer
+	endpoint string
+	sessions sync.Map
+	onClose  func(sessionID string)
+
+	// count 当前的会话数量
+	count atomic.Int64
+	// lastReap 上一次清理过期会话的时间
+	lastReap    atomic.Int64
+	idleTimeout time.Duration
+	maxSessions int64
+}
+
+// NewStreamableHTTPServer 创建 streamable HTTP 服务器，endpoint 为空时使用 /mcp
+func NewStreamableHTTPServer(svr *server.MCPServer, endpoint string) *StreamableHTTPServer {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	return &StreamableHTTPServer{server: svr, endpoint: endpoint, idleTimeout: SessionIdleTimeout, maxSessions: MaxSessions}
+}
+
+// SessionCount 返回当前的会话数量
+func (s *StreamableHTTPServer) SessionCount() int {
+	return int(s.count.Load())
+}
+
+// OnSessionClosed 设置会话结束或因长时间没有请求被清理时的回调，需要在开始处理请求之前设置
+func (s *StreamableHTTPServer) OnSessionClosed(fn func(sessionID string)) {
+	s.onClose = fn
+}
+
+// ServeHTTP 处理MCP请求
+func (s *StreamableHTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != s.endpoint {
+		http.NotFound(w, r)
+		return
+	}
+	s.reapIfDue()
+
+	switch r.Method {
+	case http.MethodPost:
+		s.handlePost(w, r)
+	case http.MethodDelete:
+		s.handleDelete(w, r)
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "不支持的请求方法", http.StatusMethodNotAllowed)
+	}
+}
+
+// handlePost 处理客户端发送的JSON-RPC消息，支持单条消息和批量消息
+func (s *StreamableHTTPServer) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("读取请求失败: %v", err), http.StatusBadRequest)
+		return
+	}
+	messages, batch, err := splitMessages(body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, parseError(err))
+		return
+	}
+
+	// initialize 请求创建新会话，其余请求必须携带已有的会话ID
+	var session *httpSession
+	if containsMethod(messages, string(mcp.MethodInitialize)) {
+		session, err = s.newSession()
+		if errors.Is(err, errTooManySessions) {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(SessionHeader, session.id)
+	} else {
+		id := r.Header.Get(SessionHeader)
+		if id == "" {
+			http.Error(w, "缺少 "+SessionHeader+" 请求头", http.StatusBadRequest)
+			return
+		}
+		value, ok := s.sessions.Load(id)
+		if !ok {
+			http.Error(w, "会话不存在或已过期", http.StatusNotFound)
+			return
+		}
+		session = value.(*httpSession)
+	}
+	// 请求结束时再次更新，执行时间较长的调用不会让会话在调用结束后立即过期
+	session.touch()
+	defer session.touch()
+
+	// 每个请求使用独立的通知通道，调用过程中的通知只会推送给发起调用的请求
+	requestSession := &requestSession{
+		httpSession:   session,
+		notifications: make(chan mcp.JSONRPCNotification, notificationBuffer),
+	}
+	ctx := s.server.WithContext(r.Context(), requestSession)
+
+	// 只有通知或响应时不需要返回内容
+	if !containsRequest(messages) {
+		for _, message := range messages {
+			s.server.HandleMessage(ctx, message)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if acceptsEventStream(r) {
+		s.stream(ctx, w, requestSession, messages, batch)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.handleMessages(ctx, messages, batch))
+}
+
+// stream 以SSE流的形式返回结果，结果返回前先推送调用过程中产生的通知
+func (s *StreamableHTTPServer) stream(ctx context.Context, w http.ResponseWriter, session *requestSession, messages []json.RawMessage, batch bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusOK, s.handleMessages(ctx, messages, batch))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	done := make(chan interface{}, 1)
+	go func() {
+		done <- s.handleMessages(ctx, messages, batch)
+	}()
+
+	for {
+		select {
+		case notification := <-session.notifications:
+			writeEvent(w, notification)
+			flusher.Flush()
+		case result := <-done:
+			// 先发送结果返回前已经产生的通知
+		drain:
+			for {
+				select {
+				case notification := <-session.notifications:
+					writeEvent(w, notification)
+				default:
+					break drain
+				}
+			}
+			writeEvent(w, result)
+			flusher.Flush()
+			return
+		}
+	}
+}
+
+// handleMessages 依次处理消息，批量请求返回响应数组
+func (s *StreamableHTTPServer) handleMessages(ctx context.Context, messages []json.RawMessage, batch bool) interface{} {
+	responses := make([]mcp.JSONRPCMessage, 0, len(messages))
+	for _, message := range messages {
+		if response := s.server.HandleMessage(ctx, message); response != nil {
+			responses = append(responses, response)
+		}
+	}
+	if !batch && len(responses) == 1 {
+		return responses[0]
+	}
+	return responses
+}
+
+// handleDelete 结束会话
+func (s *StreamableHTTPServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(SessionHeader)
+	if !s.removeSession(id) {
+		http.Error(w, "会话不存在或已过期", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// removeSession 从会话表中移除并注销会话，会话已经被移除时返回 false
+func (s *StreamableHTTPServer) removeSession(id string) bool {
+	if _, ok := s.sessions.LoadAndDelete(id); !ok {
+		return false
+	}
+	s.count.Add(-1)
+	s.server.UnregisterSession(id)
+	if s.onClose != nil {
+		s.onClose(id)
+	}
+	return true
+}
+
+// reapIfDue 距离上一次清理超过 reapInterval 时清理长时间没有请求的会话
+func (s *StreamableHTTPServer) reapIfDue() {
+	now := time.Now().UnixNano()
+	last := s.lastReap.Load()
+	if now-last < int64(reapInterval) || !s.lastReap.CompareAndSwap(last, now) {
+		return
+	}
+	s.reap()
+}
+
+// reap 清理长时间没有请求的会话
+func (s *StreamableHTTPServer) reap() {
+	s.sessions.Range(func(key, value interface{}) bool {
+		if value.(*httpSession).idle() > s.idleTimeout {
+			s.removeSession(key.(string))
+		}
+		return true
+	})
+}
+
+// errTooManySessions 会话数达到上限
+var errTooManySessions = errors.New("会话数已达到上限，请稍后重试或结束不再使用的会话")
+
+// newSession 创建并注册新会话，会话数达到上限时先清理过期会话，仍然没有空位时返回 errTooManySessions
+func (s *StreamableHTTPServer) newSession() (*httpSession, error) {
+	if s.count.Add(1) > s.maxSessions {
+		s.count.Add(-1)
+		s.reap()
+		if s.count.Add(1) > s.maxSessions {
+			s.count.Add(-1)
+			return nil, errTooManySessions
+		}
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		s.count.Add(-1)
+		return nil, fmt.Errorf("生成会话ID失败: %v", err)
+	}
+	session := &httpSession{id: hex.EncodeToString(buf)}
+	session.touch()
+	if err := s.server.RegisterSession(session); err != nil {
+		s.count.Add(-1)
+		return nil, fmt.Errorf("注册会话失败: %v", err)
+	}
+	s.sessions.Store(session.id, session)
+	return session, nil
+}
+
+// httpSession 一个 streamable HTTP 会话
+type httpSession struct {
+	id          string
+	initialized atomic.Bool
+	lastSeen    atomic.Int64
+}
+
+func (s *httpSession) Initialize()       { s.initialized.Store(true) }
+func (s *httpSession) Initialized() bool { return s.initialized.Load() }
+func (s *httpSession) SessionID() string { return s.id }
+
+// NotificationChannel 没有独立的通知流，会话级别的通知直接丢弃
+func (s *httpSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return nil }
+
+func (s *httpSession) touch() { s.lastSeen.Store(time.Now().UnixNano()) }
+
+func (s *httpSession) idle() time.Duration {
+	return time.Since(time.Unix(0, s.lastSeen.Load()))
+}
+
+// requestSession 单个请求范围内的会话，通知会推送到该请求的SSE流
+type requestSession struct {
+	*httpSession
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s *requestSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+// splitMessages 拆分单条或批量的JSON-RPC消息
+func splitMessages(body []byte) ([]json.RawMessage, bool, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var messages []json.RawMessage
+		if err := json.Unmarshal(body, &messages); err != nil {
+			return nil, true, err
+		}
+		if len(messages) == 0 {
+			return nil, true, fmt.Errorf("批量请求为空")
+		}
+		return messages, true, nil
+	}
+	if !json.Valid(body) {
+		return nil, false, fmt.Errorf("请求体不是有效的JSON")
+	}
+	return []json.RawMessage{body}, false, nil
+}
+
+// messageHeader 用于判断消息类型的字段
+type messageHeader struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+}
+
+// containsMethod 判断消息中是否有指定方法的请求
+func containsMethod(messages []json.RawMessage, method string) bool {
+	for _, message := range messages {
+		var header messageHeader
+		if json.Unmarshal(message, &header) == nil && header.Method == method {
+			return true
+		}
+	}
+	return false
+}
+
+// containsRequest 判断消息中是否有需要响应的请求
+func containsRequest(messages []json.RawMessage) bool {
+	for _, message := range messages {
+		var header messageHeader
+		if json.Unmarshal(message, &header) == nil && header.Method != "" && len(header.ID) > 0 && string(header.ID) != "null" {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsEventStream 判断客户端是否接受SSE流
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// parseError 构造JSON-RPC解析错误
+func parseError(err error) mcp.JSONRPCError {
+	response := mcp.JSONRPCError{JSONRPC: mcp.JSONRPC_VERSION}
+	response.Error.Code = mcp.PARSE_ERROR
+	response.Error.Message = fmt.Sprintf("Parse error: %v", err)
+	return response
+}
+
+// writeJSON 写入JSON响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeEvent 写入一条SSE消息
+func writeEvent(w io.Writer, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+}
@@ -0,0 +1,316 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// initializeRequest 创建会话的 initialize 请求
+const initializeRequest = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`
+
+// callRequest 调用 echo 工具的请求
+func callRequest(id int, text string) string {
+	data, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  "tools/call",
+		"params":  map[string]interface{}{"name": "echo", "arguments": map[string]interface{}{"text": text}},
+	})
+	return string(data)
+}
+
+// newTestServer 创建带有 echo 工具的 streamable HTTP 服务器，echo 在返回结果前先推送一条日志通知
+func newTestServer(t *testing.T) (*StreamableHTTPServer, *httptest.Server) {
+	t.Helper()
+
+	svr := server.NewMCPServer("test", "1.0", server.WithToolCapabilities(false))
+	svr.AddTool(mcp.NewTool("echo", mcp.WithString("text")), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		text, _ := request.Params.Arguments["text"].(string)
+		server.ServerFromContext(ctx).SendNotificationToClient(ctx, "notifications/message", map[string]interface{}{"data": "echo " + text})
+		return mcp.NewToolResultText(text), nil
+	})
+	streamable := NewStreamableHTTPServer(svr, "")
+	ts := httptest.NewServer(streamable)
+	t.Cleanup(ts.Close)
+	return streamable, ts
+}
+
+// post 发送 POST 请求，sessionID 为空时不带会话请求头
+func post(t *testing.T, ts *httptest.Server, sessionID, accept, body string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+DefaultEndpoint, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if sessionID != "" {
+		req.Header.Set(SessionHeader, sessionID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("发送请求失败: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// initialize 创建会话并返回会话ID
+func initialize(t *testing.T, ts *httptest.Server) string {
+	t.Helper()
+
+	resp := post(t, ts, "", "", initializeRequest)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("initialize 应返回 200，实际为 %d", resp.StatusCode)
+	}
+	id := resp.Header.Get(SessionHeader)
+	if id == "" {
+		t.Fatal("initialize 的响应中缺少会话ID")
+	}
+	return id
+}
+
+// decodeResponse 解析单条JSON-RPC响应中的工具结果文本
+func decodeResponse(t *testing.T, data []byte) (int, string) {
+	t.Helper()
+
+	var response struct {
+		ID     int `json:"id"`
+		Result struct {
+			Content []struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		t.Fatalf("解析响应失败: %v: %s", err, data)
+	}
+	if len(response.Result.Content) == 0 {
+		t.Fatalf("响应中没有工具结果: %s", data)
+	}
+	return response.ID, response.Result.Content[0].Text
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	streamable, ts := newTestServer(t)
+
+	var closed []string
+	streamable.OnSessionClosed(func(sessionID string) { closed = append(closed, sessionID) })
+
+	id := initialize(t, ts)
+	if streamable.SessionCount() != 1 {
+		t.Errorf("initialize 后应有 1 个会话，实际为 %d", streamable.SessionCount())
+	}
+
+	resp := post(t, ts, id, "application/json", callRequest(2, "hello"))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("工具调用应返回 200，实际为 %d", resp.StatusCode)
+	}
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		t.Fatalf("读取响应失败: %v", err)
+	}
+	if responseID, text := decodeResponse(t, raw); responseID != 2 || text != "hello" {
+		t.Errorf("应返回请求 2 的结果 hello，实际请求 %d 的结果 %q", responseID, text)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+DefaultEndpoint, nil)
+	req.Header.Set(SessionHeader, id)
+	deleted, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("发送请求失败: %v", err)
+	}
+	deleted.Body.Close()
+	if deleted.StatusCode != http.StatusNoContent {
+		t.Errorf("DELETE 应返回 204，实际为 %d", deleted.StatusCode)
+	}
+	if streamable.SessionCount() != 0 || len(closed) != 1 || closed[0] != id {
+		t.Errorf("结束会话后应没有会话并回调一次，实际会话数 %d、回调 %v", streamable.SessionCount(), closed)
+	}
+
+	if resp := post(t, ts, id, "", callRequest(3, "again")); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("已结束的会话应返回 404，实际为 %d", resp.StatusCode)
+	}
+}
+
+func TestSessionRequired(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	tests := []struct {
+		name      string
+		sessionID string
+		status    int
+	}{
+		{name: "缺少会话ID", sessionID: "", status: http.StatusBadRequest},
+		{name: "未知的会话ID", sessionID: "0123456789abcdef", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := post(t, ts, tt.sessionID, "", callRequest(1, "x")); resp.StatusCode != tt.status {
+				t.Errorf("应返回 %d，实际为 %d", tt.status, resp.StatusCode)
+			}
+		})
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+DefaultEndpoint, nil)
+	req.Header.Set(SessionHeader, "0123456789abcdef")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("发送请求失败: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("结束未知的会话应返回 404，实际为 %d", resp.StatusCode)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + DefaultEndpoint)
+	if err != nil {
+		t.Fatalf("发送请求失败: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET 应返回 405，实际为 %d", resp.StatusCode)
+	}
+	if allow := resp.Header.Get("Allow"); allow != "POST, DELETE" {
+		t.Errorf("Allow 响应头应为 POST, DELETE，实际为 %q", allow)
+	}
+}
+
+func TestBatchAndNotifications(t *testing.T) {
+	_, ts := newTestServer(t)
+	id := initialize(t, ts)
+
+	// 只有通知时不返回内容
+	if resp := post(t, ts, id, "", `{"jsonrpc":"2.0","method":"notifications/initialized"}`); resp.StatusCode != http.StatusAccepted {
+		t.Errorf("只有通知时应返回 202，实际为 %d", resp.StatusCode)
+	}
+
+	// 批量请求中的通知没有响应，请求的响应按顺序放在数组中
+	resp := post(t, ts, id, "application/json", "["+callRequest(1, "a")+`,{"jsonrpc":"2.0","method":"notifications/initialized"},`+callRequest(2, "b")+"]")
+	var responses []json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&responses); err != nil {
+		t.Fatalf("批量请求应返回响应数组: %v", err)
+	}
+	if len(responses) != 2 {
+		t.Fatalf("应返回 2 条响应，实际为 %d 条", len(responses))
+	}
+	for i, want := range []string{"a", "b"} {
+		if responseID, text := decodeResponse(t, responses[i]); responseID != i+1 || text != want {
+			t.Errorf("第 %d 条响应应为请求 %d 的结果 %q，实际为请求 %d 的结果 %q", i+1, i+1, want, responseID, text)
+		}
+	}
+
+	// 无效的请求体返回JSON-RPC解析错误
+	resp = post(t, ts, id, "", "[]")
+	var parseErr mcp.JSONRPCError
+	if err := json.NewDecoder(resp.Body).Decode(&parseErr); err != nil || resp.StatusCode != http.StatusBadRequest || parseErr.Error.Code != mcp.PARSE_ERROR {
+		t.Errorf("空的批量请求应返回 400 和解析错误，实际 %d %+v %v", resp.StatusCode, parseErr, err)
+	}
+}
+
+func TestEventStreamResponse(t *testing.T) {
+	_, ts := newTestServer(t)
+	id := initialize(t, ts)
+
+	resp := post(t, ts, id, "application/json, text/event-stream", callRequest(2, "hello"))
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("接受SSE时应以事件流返回，实际 Content-Type 为 %q", contentType)
+	}
+
+	var events []string
+	data := new(strings.Builder)
+	buf := make([]byte, 4096)
+	for {
+		n, err := resp.Body.Read(buf)
+		data.Write(buf[:n])
+		if err != nil {
+			break
+		}
+	}
+	for _, block := range strings.Split(strings.TrimSpace(data.String()), "\n\n") {
+		events = append(events, strings.TrimPrefix(strings.SplitN(block, "\n", 2)[1], "data: "))
+	}
+	if len(events) != 2 {
+		t.Fatalf("应先推送 1 条通知再返回结果，实际收到 %d 条消息: %q", len(events), data.String())
+	}
+	if !strings.Contains(events[0], "notifications/message") || !strings.Contains(events[0], "echo hello") {
+		t.Errorf("第一条消息应为调用过程中的通知，实际为 %s", events[0])
+	}
+	if responseID, text := decodeResponse(t, []byte(events[1])); responseID != 2 || text != "hello" {
+		t.Errorf("最后一条消息应为请求 2 的结果 hello，实际请求 %d 的结果 %q", responseID, text)
+	}
+}
+
+func TestIdleSessionsReaped(t *testing.T) {
+	streamable, ts := newTestServer(t)
+
+	var (
+		mu     sync.Mutex
+		closed []string
+	)
+	streamable.OnSessionClosed(func(sessionID string) {
+		mu.Lock()
+		defer mu.Unlock()
+		closed = append(closed, sessionID)
+	})
+
+	idle := initialize(t, ts)
+	active := initialize(t, ts)
+	streamable.idleTimeout = time.Hour
+	value, _ := streamable.sessions.Load(idle)
+	value.(*httpSession).lastSeen.Store(time.Now().Add(-2 * time.Hour).UnixNano())
+
+	// 任何请求都会触发清理，不需要新的 initialize
+	streamable.lastReap.Store(0)
+	if resp := post(t, ts, active, "", callRequest(1, "x")); resp.StatusCode != http.StatusOK {
+		t.Fatalf("活跃的会话应能继续调用，实际返回 %d", resp.StatusCode)
+	}
+	if resp := post(t, ts, idle, "", callRequest(1, "x")); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("过期的会话应返回 404，实际为 %d", resp.StatusCode)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if streamable.SessionCount() != 1 || len(closed) != 1 || closed[0] != idle {
+		t.Errorf("应只清理过期的会话，实际会话数 %d、回调 %v", streamable.SessionCount(), closed)
+	}
+}
+
+func TestSessionLimit(t *testing.T) {
+	streamable, ts := newTestServer(t)
+	streamable.maxSessions = 1
+
+	first := initialize(t, ts)
+	if resp := post(t, ts, "", "", initializeRequest); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("会话数达到上限时应返回 503，实际为 %d", resp.StatusCode)
+	}
+	if streamable.SessionCount() != 1 {
+		t.Errorf("被拒绝的 initialize 不应占用会话数，实际为 %d", streamable.SessionCount())
+	}
+
+	// 达到上限时先清理过期的会话
+	value, _ := streamable.sessions.Load(first)
+	value.(*httpSession).lastSeen.Store(time.Now().Add(-2 * SessionIdleTimeout).UnixNano())
+	if second := initialize(t, ts); second == first {
+		t.Error("应创建新的会话")
+	}
+	if streamable.SessionCount() != 1 {
+		t.Errorf("过期的会话应被清理，实际会话数为 %d", streamable.SessionCount())
+	}
+}
@@ -0,0 +1,45 @@
+// Package transport 提供MCP服务器支持的传输方式
+package transport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// 支持的传输方式
+const (
+	// SSE 旧版 HTTP+SSE 传输，客户端通过 /sse 建立连接、通过 /message 发送请求
+	SSE = "sse"
+	// HTTP streamable HTTP 传输，客户端通过 /mcp 发送请求
+	HTTP = "http"
+	// Stdio 通过标准输入输出通信，适合由IDE等客户端直接启动服务器
+	Stdio = "stdio"
+)
+
+// Validate 检查传输方式是否受支持
+func Validate(transport string) error {
+	switch transport {
+	case SSE, HTTP, Stdio:
+		return nil
+	default:
+		return fmt.Errorf("不支持的传输方式 %q，可选值: sse、http、stdio", transport)
+	}
+}
+
+// ServeStdio 通过标准输入输出提供服务，直到输入结束或 ctx 被取消
+//
+// out 为实际写入协议消息的输出，调用方应在此之前把 os.Stdout 重定向到标准错误，
+// 避免日志输出混入协议消息。contextFunc 用于向每次调用的上下文写入调用方身份等信息。
+func ServeStdio(ctx context.Context, svr *server.MCPServer, in io.Reader, out io.Writer, contextFunc server.StdioContextFunc) error {
+	stdio := server.NewStdioServer(svr)
+	stdio.SetErrorLogger(log.New(os.Stderr, "", log.LstdFlags))
+	if contextFunc != nil {
+		stdio.SetContextFunc(contextFunc)
+	}
+	return stdio.Listen(ctx, in, out)
+}